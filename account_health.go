@@ -0,0 +1,113 @@
+package schedulesdirect
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OnlineStatus is the SystemStatus status reported when Schedules Direct is operating normally.
+const OnlineStatus = "Online"
+
+// AccountHealth is a parsed view of a StatusResponse.
+type AccountHealth struct {
+	// Expires is when the account subscription expires, zero if unknown.
+	Expires time.Time
+	// DaysRemaining is the number of whole days until the account expires, 0 once expired or if unknown.
+	DaysRemaining int
+	// Expired is true if the account has expired, false if the expiry is unknown.
+	Expired bool
+
+	// LineupsUsed is the number of lineups on the account.
+	LineupsUsed int
+	// MaxLineups is the maximum number of lineups allowed on the account.
+	MaxLineups int
+
+	// Online is true if Schedules Direct reports that its systems are online.
+	Online bool
+	// SystemStatus is the most recent system status, if any was reported.
+	SystemStatus *Status
+
+	LastDataUpdate time.Time
+	Messages       []SystemMessage
+	Notifications  []string
+}
+
+// LineupsRemaining returns the number of lineups that can still be added to the account.
+func (h *AccountHealth) LineupsRemaining() int {
+	remaining := h.MaxLineups - h.LineupsUsed
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// ExpiresWithin returns true if the account expires within the given duration of now.
+// It returns false if the expiry is unknown.
+func (h *AccountHealth) ExpiresWithin(now time.Time, d time.Duration) bool {
+	return !h.Expires.IsZero() && h.Expires.Before(now.Add(d))
+}
+
+// Health parses the StatusResponse into an AccountHealth relative to now.
+// An empty or unparseable account expiry is treated as unknown.
+func (s *StatusResponse) Health(now time.Time) *AccountHealth {
+	health := &AccountHealth{
+		LineupsUsed:    len(s.Lineups),
+		LastDataUpdate: s.LastDataUpdate,
+		Messages:       make([]SystemMessage, 0),
+		Notifications:  s.Notifications,
+	}
+
+	if s.Account != nil {
+		if expires, expiresErr := parseAccountExpiry(s.Account.Expires); expiresErr == nil {
+			health.Expires = expires
+			health.Expired = !now.Before(expires)
+			if !health.Expired {
+				health.DaysRemaining = int(expires.Sub(now).Hours() / 24)
+			}
+		}
+		health.MaxLineups = s.Account.MaxLineups
+		health.Messages = append(health.Messages, s.Account.Messages...)
+	}
+
+	if len(s.SystemStatus) > 0 {
+		health.SystemStatus = &s.SystemStatus[0]
+		health.Online = strings.EqualFold(s.SystemStatus[0].Status, OnlineStatus)
+	}
+
+	return health
+}
+
+// parseAccountExpiry parses the expiry date of an account.
+func parseAccountExpiry(expires string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if parsed, err := time.Parse(layout, expires); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse account expiry date %q", expires)
+}
+
+// GetAccountHealth returns the parsed status of this account.
+func (c *Client) GetAccountHealth() (*AccountHealth, error) {
+	status, err := c.GetStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	return status.Health(time.Now()), nil
+}
+
+// AcknowledgeMessages deletes the given system messages from the account.
+// It stops at, and returns, the first error.
+func (c *Client) AcknowledgeMessages(messages []SystemMessage) error {
+	for _, message := range messages {
+		if message.MessageID == "" {
+			continue
+		}
+		if err := c.DeleteSystemMessage(message.MessageID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package schedulesdirect
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAccountHealthOK(t *testing.T) {
+	mux, client := setup()
+
+	expires := time.Now().Add(10*24*time.Hour + time.Hour).UTC().Format(time.RFC3339)
+
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/status"),
+		func(w http.ResponseWriter, r *http.Request) {
+			ensureMethod(t, r, "GET")
+			ensureHeader(t, r, "token", "d97c908ed44c25fdca302612c70584c8d5acd47a")
+
+			fmt.Fprintf(w, `{"account":{"expires":"%s","messages":[{"msgID":"123456","date":"2016-08-17T18:21:26Z","message":"Renew soon."}],"maxLineups":4},"lineups":[{"lineup":"USA-OTA-10001"}],"lastDataUpdate":"2014-07-28T14:48:59Z","notifications":[],"systemStatus":[{"date":"2012-12-17T16:24:47Z","status":"Online","details":"All servers running normally."}],"serverID":"serverID1","code":0}`, expires)
+		},
+	)
+
+	health, err := client.GetAccountHealth()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 10, health.DaysRemaining)
+	assert.False(t, health.Expired)
+	assert.True(t, health.ExpiresWithin(time.Now(), 30*24*time.Hour))
+	assert.Equal(t, 3, health.LineupsRemaining())
+	assert.True(t, health.Online)
+	assert.Equal(t, "All servers running normally.", health.SystemStatus.Details)
+	if assert.Len(t, health.Messages, 1) {
+		assert.Equal(t, "123456", health.Messages[0].MessageID)
+		assert.Equal(t, "Renew soon.", health.Messages[0].Message)
+	}
+}
+
+func TestStatusResponseHealthExpiredOffline(t *testing.T) {
+	status := &StatusResponse{
+		Account:      &AccountInfo{Expires: "2014-09-26T19:07:28Z", MaxLineups: 4},
+		SystemStatus: []Status{{Status: "Offline"}},
+	}
+
+	health := status.Health(time.Date(2014, 10, 1, 0, 0, 0, 0, time.UTC))
+
+	assert.True(t, health.Expired)
+	assert.Equal(t, 0, health.DaysRemaining)
+	assert.False(t, health.Online)
+
+	status.Account.Expires = "soon"
+	health = status.Health(time.Now())
+	assert.True(t, health.Expires.IsZero())
+	assert.False(t, health.Expired)
+}
+
+func TestGetAccountHealthUnknownExpiry(t *testing.T) {
+	mux, client := setup()
+
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/status"),
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"account":{"expires":"","messages":[{"msgID":"123456","date":"2016-08-17T18:21:26Z","message":"Renew soon."}],"maxLineups":4},"lineups":[{"lineup":"USA-OTA-10001"}],"lastDataUpdate":"2014-07-28T14:48:59Z","notifications":[],"systemStatus":[{"date":"2012-12-17T16:24:47Z","status":"Online","details":"All servers running normally."}],"serverID":"serverID1","code":0}`)
+		},
+	)
+
+	health, err := client.GetAccountHealth()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, health.Expires.IsZero())
+	assert.False(t, health.Expired)
+	assert.Equal(t, 0, health.DaysRemaining)
+	assert.False(t, health.ExpiresWithin(time.Now(), 30*24*time.Hour))
+	assert.Equal(t, 3, health.LineupsRemaining())
+	assert.True(t, health.Online)
+	assert.Len(t, health.Messages, 1)
+}
+
+func TestAcknowledgeMessagesOK(t *testing.T) {
+	mux, client := setup()
+
+	deleted := make([]string, 0)
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/messages/"),
+		func(w http.ResponseWriter, r *http.Request) {
+			ensureMethod(t, r, "DELETE")
+			ensureHeader(t, r, "token", "d97c908ed44c25fdca302612c70584c8d5acd47a")
+			deleted = append(deleted, r.URL.Path)
+
+			fmt.Fprint(w, getBaseResponse(ErrOK))
+		},
+	)
+
+	err := client.AcknowledgeMessages([]SystemMessage{{MessageID: "123456"}, {Message: "no ID"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{fmt.Sprint("/", APIVersion, "/messages/123456")}, deleted)
+}
+
+func TestSystemMessageUnmarshalString(t *testing.T) {
+	messages := make([]SystemMessage, 0)
+	if err := json.Unmarshal([]byte(`["plain message",{"msgID":"1","message":"object message"}]`), &messages); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []SystemMessage{{Message: "plain message"}, {MessageID: "1", Message: "object message"}}, messages)
+}
@@ -0,0 +1,237 @@
+package schedulesdirect
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// An Airing is a single showing of a program on a station.
+type Airing struct {
+	StationID string
+	Program   Program
+	// Info is the full program information, if it has been fetched.
+	Info *ProgramInfo
+}
+
+// Start returns the time the airing begins, or the zero time if it is unknown.
+func (a Airing) Start() time.Time {
+	if a.Program.AirDateTime == nil {
+		return time.Time{}
+	}
+	return *a.Program.AirDateTime
+}
+
+// End returns the time the airing is scheduled to finish.
+func (a Airing) End() time.Time {
+	return a.Start().Add(time.Duration(a.Program.Duration) * time.Second)
+}
+
+// Title returns the title of the airing, or an empty string if the program information is missing.
+func (a Airing) Title() string {
+	if a.Info == nil || len(a.Info.Titles) == 0 {
+		return ""
+	}
+	return a.Info.Titles[0].Title120
+}
+
+// ShowID returns the SH program ID of the series the airing belongs to.
+// For programs which are not episodes the program ID itself is returned.
+func (a Airing) ShowID() string {
+	if len(a.Program.ProgramID) >= 10 {
+		if showID := a.Program.ShowID(); showID != "" {
+			return showID
+		}
+	}
+	return a.Program.ProgramID
+}
+
+// IndexProgramInfo returns the given programs keyed by program ID.
+func IndexProgramInfo(programs []ProgramInfo) map[string]*ProgramInfo {
+	index := make(map[string]*ProgramInfo, len(programs))
+	for idx := range programs {
+		if programs[idx].ProgramID != "" {
+			index[programs[idx].ProgramID] = &programs[idx]
+		}
+	}
+	return index
+}
+
+// AiringsFromSchedules flattens schedules into a slice of airings sorted by start time.
+// Program information is attached from programInfo, which may be nil.
+// Programs without an air date are skipped.
+func AiringsFromSchedules(schedules []Schedule, programInfo map[string]*ProgramInfo) []Airing {
+	airings := make([]Airing, 0)
+	for _, schedule := range schedules {
+		for _, program := range schedule.Programs {
+			if program.AirDateTime == nil {
+				continue
+			}
+			airings = append(airings, Airing{
+				StationID: schedule.StationID,
+				Program:   program,
+				Info:      programInfo[program.ProgramID],
+			})
+		}
+	}
+	sortAirings(airings)
+	return airings
+}
+
+// sortAirings sorts airings by start time, then station ID, then program ID.
+func sortAirings(airings []Airing) {
+	sort.SliceStable(airings, func(i, j int) bool {
+		return airingLess(airings[i], airings[j])
+	})
+}
+
+// airingLess reports whether a sorts before b.
+func airingLess(a, b Airing) bool {
+	if !a.Start().Equal(b.Start()) {
+		return a.Start().Before(b.Start())
+	}
+	if a.StationID != b.StationID {
+		return a.StationID < b.StationID
+	}
+	return a.Program.ProgramID < b.Program.ProgramID
+}
+
+// An AiringKey uniquely and stably identifies an airing by station, start time and program.
+// It is comparable and can be used as a map key.
+type AiringKey struct {
+	StationID string
+	Start     time.Time
+	ProgramID string
+}
+
+// airingKeyTimeFormat is the time format used by AiringKey.String.
+const airingKeyTimeFormat = "20060102T150405Z"
+
+// Key returns the AiringKey for the airing.
+func (a Airing) Key() AiringKey {
+	return AiringKey{
+		StationID: a.StationID,
+		Start:     a.Start().UTC().Truncate(time.Second),
+		ProgramID: a.Program.ProgramID,
+	}
+}
+
+// String returns the key as "STATIONID.START.PROGRAMID", e.g. "20454.20150303T000000Z.SH005371070000".
+func (k AiringKey) String() string {
+	return fmt.Sprintf("%s.%s.%s", k.StationID, k.Start.Format(airingKeyTimeFormat), k.ProgramID)
+}
+
+// ParseAiringKey parses the output of AiringKey.String.
+func ParseAiringKey(key string) (AiringKey, error) {
+	parts := strings.Split(key, ".")
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return AiringKey{}, fmt.Errorf("invalid airing key %q", key)
+	}
+
+	start, startErr := time.Parse(airingKeyTimeFormat, parts[1])
+	if startErr != nil {
+		return AiringKey{}, fmt.Errorf("invalid airing key %q: %s", key, startErr)
+	}
+
+	return AiringKey{StationID: parts[0], Start: start, ProgramID: parts[2]}, nil
+}
+
+// IsRepeat returns true if the airing is known to have been shown before.
+// Airings flagged as new are never repeats, otherwise the repeat flag is honored and
+// the original air date is compared with the airing date, allowing a day for time zones.
+func (a Airing) IsRepeat() bool {
+	if a.Program.New {
+		return false
+	}
+	if a.Program.Repeat {
+		return true
+	}
+	if a.Info != nil && a.Info.OriginalAirDate != nil && a.Info.OriginalAirDate.Time != nil {
+		return a.Info.OriginalAirDate.Time.Before(a.Start().UTC().Truncate(24 * time.Hour).Add(-24 * time.Hour))
+	}
+	return false
+}
+
+// A GroupedAiring is an airing within an AiringGroup.
+type GroupedAiring struct {
+	Airing
+	Key AiringKey
+	// FirstShowing is true for the earliest airings of the program in the group.
+	// Simulcasts starting at the same time are all first showings.
+	FirstShowing bool
+	// Simulcast is true if the program starts at the same time on another station, e.g. a HD/SD pair.
+	Simulcast bool
+	// Repeat is true if the airing has been shown before, either according to
+	// its own flags or because it is not the first showing in the group.
+	Repeat bool
+}
+
+// An AiringGroup clusters every airing of a single program.
+type AiringGroup struct {
+	ProgramID string
+	// Airings are sorted by start time.
+	Airings []GroupedAiring
+}
+
+// FirstShowings returns the first showings of the program in the group.
+func (g AiringGroup) FirstShowings() []GroupedAiring {
+	first := make([]GroupedAiring, 0)
+	for _, airing := range g.Airings {
+		if airing.FirstShowing {
+			first = append(first, airing)
+		}
+	}
+	return first
+}
+
+// IsPremiere returns true if the first showing in the group has not been shown before.
+func (g AiringGroup) IsPremiere() bool {
+	return len(g.Airings) > 0 && !g.Airings[0].IsRepeat()
+}
+
+// GroupAirings clusters the airings by program ID, removing exact duplicates.
+// Groups are returned in order of their first showing.
+func GroupAirings(airings []Airing) []AiringGroup {
+	sorted := make([]Airing, len(airings))
+	copy(sorted, airings)
+	sortAirings(sorted)
+
+	groups := make([]AiringGroup, 0)
+	byProgram := make(map[string]int)
+	seen := make(map[AiringKey]bool)
+
+	for _, airing := range sorted {
+		key := airing.Key()
+		if airing.Program.AirDateTime == nil || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		idx, ok := byProgram[airing.Program.ProgramID]
+		if !ok {
+			idx = len(groups)
+			byProgram[airing.Program.ProgramID] = idx
+			groups = append(groups, AiringGroup{ProgramID: airing.Program.ProgramID})
+		}
+
+		groups[idx].Airings = append(groups[idx].Airings, GroupedAiring{Airing: airing, Key: key})
+	}
+
+	for _, group := range groups {
+		firstStart := group.Airings[0].Key.Start
+		starts := make(map[time.Time]int)
+		for _, airing := range group.Airings {
+			starts[airing.Key.Start]++
+		}
+
+		for idx := range group.Airings {
+			airing := &group.Airings[idx]
+			airing.FirstShowing = airing.Key.Start.Equal(firstStart)
+			airing.Simulcast = starts[airing.Key.Start] > 1
+			airing.Repeat = !airing.FirstShowing || airing.IsRepeat()
+		}
+	}
+
+	return groups
+}
@@ -0,0 +1,734 @@
+package schedulesdirect
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// An AiringFilter is a parsed filter expression which airings can be matched against.
+//
+// An expression combines conditions with and, or, not and parentheses, for example
+//
+//	sports and new and hd and lineup = USA-OTA-10001 and start >= 18:00 and start < 23:00
+//	movie and rating < R and stars >= 3
+//
+// A condition is either a flag, such as new, or a comparison of a field with a value
+// using one of =, !=, <, <=, >, >=, ~ (contains, ignoring case) or in (value, ...).
+// Values are bare words or double quoted strings and text is compared ignoring case.
+// Fields holding several values, such as genre, match if any of their values match.
+// Comparisons of a field which has no value, such as the stars of a show or the episode title of a movie,
+// never match, not even with !=. Use not to match those, e.g. not genre = news.
+//
+// Flags: new, premiere, repeat, live, tape, delayed, premiereOrFinale, hd, uhd, hdr, cc, described, signed,
+// educational, catchup, continued, subjectToBlackout, timeApproximate, joinedInProgress,
+// leftInProgress, programBreak, cableInTheClassroom, sports, movie.
+//
+// Text fields: programID, stationID, title, episodeTitle, entityType, showType,
+// liveTapeDelay, premiereOrFinale, genre, video, audio, advisory.
+//
+// Other fields: rating (ordered by minimum age within a ratings board, by RatingLevel across boards,
+// e.g. rating < R or rating <= teen), stars (0 to 5), year, duration (e.g. 1h30m),
+// start and end (clock time, e.g. 18:00), day (e.g. sat) and lineup (requires Lineups).
+type AiringFilter struct {
+	// Location is the time zone start, end and day are compared in, UTC if nil.
+	Location *time.Location
+	// Lineups are the station IDs of each lineup, used by the lineup field.
+	Lineups map[string][]string
+
+	source string
+	root   filterNode
+}
+
+// ParseAiringFilter parses a filter expression.
+func ParseAiringFilter(expr string) (*AiringFilter, error) {
+	filter := &AiringFilter{}
+	if err := filter.UnmarshalText([]byte(expr)); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// MustParseAiringFilter is like ParseAiringFilter but panics if the expression is invalid.
+func MustParseAiringFilter(expr string) *AiringFilter {
+	filter, err := ParseAiringFilter(expr)
+	if err != nil {
+		panic(err)
+	}
+	return filter
+}
+
+// String returns the filter expression.
+func (f *AiringFilter) String() string {
+	return f.source
+}
+
+// MarshalText implements encoding.TextMarshaler so filters can be stored in config files.
+func (f *AiringFilter) MarshalText() ([]byte, error) {
+	return []byte(f.source), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler so filters can be read from config files.
+// A blank expression matches every airing.
+func (f *AiringFilter) UnmarshalText(text []byte) error {
+	if strings.TrimSpace(string(text)) == "" {
+		f.source, f.root = string(text), nil
+		return nil
+	}
+
+	tokens, err := lexFilter(string(text))
+	if err != nil {
+		return err
+	}
+
+	p := &filterParser{source: string(text), tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return err
+	}
+	if tok := p.peek(); tok.kind != filterEOF {
+		return p.errorf(tok, "unexpected %q", tok.text)
+	}
+
+	f.source = string(text)
+	f.root = root
+	return nil
+}
+
+// AddLineupStations makes the stations of the lineup available to the lineup field.
+func (f *AiringFilter) AddLineupStations(channels *ChannelResponse) {
+	if channels == nil || channels.Metadata == nil {
+		return
+	}
+	if f.Lineups == nil {
+		f.Lineups = make(map[string][]string)
+	}
+	stationIDs := make([]string, 0, len(channels.Stations))
+	for _, station := range channels.Stations {
+		stationIDs = append(stationIDs, station.StationID)
+	}
+	f.Lineups[channels.Metadata.Lineup] = stationIDs
+}
+
+// Match returns true if the airing matches the filter. An empty filter matches every airing.
+func (f *AiringFilter) Match(airing Airing) bool {
+	if f.root == nil {
+		return true
+	}
+	return f.root.match(f, airing)
+}
+
+// Filter returns the airings matching the filter.
+func (f *AiringFilter) Filter(airings []Airing) []Airing {
+	matched := make([]Airing, 0)
+	for _, airing := range airings {
+		if f.Match(airing) {
+			matched = append(matched, airing)
+		}
+	}
+	return matched
+}
+
+func (f *AiringFilter) location() *time.Location {
+	if f.Location != nil {
+		return f.Location
+	}
+	return time.UTC
+}
+
+type filterNode interface {
+	match(f *AiringFilter, airing Airing) bool
+}
+
+type andNode struct{ left, right filterNode }
+
+func (n andNode) match(f *AiringFilter, airing Airing) bool {
+	return n.left.match(f, airing) && n.right.match(f, airing)
+}
+
+type orNode struct{ left, right filterNode }
+
+func (n orNode) match(f *AiringFilter, airing Airing) bool {
+	return n.left.match(f, airing) || n.right.match(f, airing)
+}
+
+type notNode struct{ node filterNode }
+
+func (n notNode) match(f *AiringFilter, airing Airing) bool {
+	return !n.node.match(f, airing)
+}
+
+type flagNode struct {
+	flag func(Airing) bool
+	want bool
+}
+
+func (n flagNode) match(_ *AiringFilter, airing Airing) bool {
+	return n.flag(airing) == n.want
+}
+
+type compareNode struct {
+	field  filterField
+	op     string
+	values []filterValue
+}
+
+// filterValue is a comparison value, parsed according to the kind of the field it is compared with.
+type filterValue struct {
+	text   string
+	number float64
+}
+
+func (n compareNode) match(f *AiringFilter, airing Airing) bool {
+	switch n.field.kind {
+	case textFilterField:
+		return n.matchText(n.field.text(airing))
+	case numberFilterField:
+		value, ok := n.field.number(airing)
+		return ok && n.matchNumber(value)
+	case clockFilterField:
+		t := airing.Start()
+		if n.field.name == "end" {
+			t = airing.End()
+		}
+		t = t.In(f.location())
+		return n.matchNumber(float64(t.Hour()*60 + t.Minute()))
+	case weekdayFilterField:
+		return n.matchNumber(float64(airing.Start().In(f.location()).Weekday()))
+	case lineupFilterField:
+		for _, value := range n.values {
+			if containsString(f.Lineups[value.text], airing.StationID) {
+				return n.op != "!="
+			}
+		}
+		return n.op == "!="
+	case ratingFilterField:
+		return n.matchRating(airingRatings(airing))
+	}
+	return false
+}
+
+func (n compareNode) matchText(texts []string) bool {
+	matched, known := false, false
+	for _, text := range texts {
+		if text == "" {
+			continue
+		}
+		known = true
+		for _, value := range n.values {
+			if n.op == "~" {
+				matched = matched || strings.Contains(strings.ToLower(text), strings.ToLower(value.text))
+			} else {
+				matched = matched || strings.EqualFold(text, value.text)
+			}
+		}
+	}
+	if n.op == "!=" {
+		return known && !matched
+	}
+	return matched
+}
+
+func (n compareNode) matchNumber(number float64) bool {
+	for _, value := range n.values {
+		if compareFilterNumbers(number, n.op, value.number) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n compareNode) matchRating(ratings []ContentRating) bool {
+	matched := false
+	for _, rating := range ratings {
+		for _, value := range n.values {
+			switch n.op {
+			case "=", "!=", "in":
+				matched = matched || normalizeRatingCode(rating.Code) == normalizeRatingCode(value.text)
+			default:
+				matched = matched || compareRatings(NormalizeContentRating(rating), n.op, value.text)
+			}
+		}
+	}
+	if n.op == "!=" {
+		return len(ratings) > 0 && !matched
+	}
+	return matched
+}
+
+// compareRatings compares rating with code on the rating's own board if the board has the code,
+// otherwise it compares their levels. code may also be the name of a RatingLevel.
+func compareRatings(rating NormalizedRating, op, code string) bool {
+	if !rating.Known {
+		return false
+	}
+
+	if board, ok := lookupRatingBoard(rating.Board); ok {
+		if age, ok := board.ages[normalizeRatingCode(code)]; ok {
+			return compareFilterNumbers(float64(rating.MinimumAge), op, float64(age))
+		}
+	}
+
+	var level RatingLevel
+	if err := level.UnmarshalText([]byte(code)); err != nil {
+		var ok bool
+		if level, ok = ratingCodeLevel(code); !ok {
+			return false
+		}
+	}
+	return compareFilterNumbers(float64(rating.Level), op, float64(level))
+}
+
+func compareFilterNumbers(a float64, op string, b float64) bool {
+	switch op {
+	case "=", "in":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+type filterFieldKind int
+
+const (
+	flagFilterField filterFieldKind = iota
+	textFilterField
+	numberFilterField
+	durationFilterField
+	clockFilterField
+	weekdayFilterField
+	lineupFilterField
+	ratingFilterField
+)
+
+type filterField struct {
+	name   string
+	kind   filterFieldKind
+	flag   func(Airing) bool
+	text   func(Airing) []string
+	number func(Airing) (float64, bool)
+}
+
+// filterFieldsByName are the fields usable in filter expressions by lower case name.
+var filterFieldsByName = filterFields() // nolint: gochecknoglobals
+
+// filterFields returns the fields usable in filter expressions by lower case name.
+func filterFields() map[string]filterField {
+	fields := make(map[string]filterField)
+
+	flags := map[string]func(Airing) bool{
+		"new":                 func(a Airing) bool { return a.Program.New },
+		"premiere":            func(a Airing) bool { return a.Program.Premiere },
+		"repeat":              func(a Airing) bool { return a.Program.Repeat },
+		"live":                func(a Airing) bool { return a.Program.IsLive() },
+		"tape":                func(a Airing) bool { return strings.EqualFold(string(a.Program.LiveTapeDelay), string(Tape)) },
+		"delayed":             func(a Airing) bool { return strings.EqualFold(string(a.Program.LiveTapeDelay), string(Delayed)) },
+		"hd":                  func(a Airing) bool { return a.Program.IsHD() },
+		"uhd":                 func(a Airing) bool { return a.Program.Is4K() },
+		"hdr":                 func(a Airing) bool { return a.Program.IsHDR() },
+		"cc":                  func(a Airing) bool { return a.Program.HasCaptions() },
+		"described":           func(a Airing) bool { return a.Program.HasAudioDescription() },
+		"signed":              func(a Airing) bool { return a.Program.Signed },
+		"educational":         func(a Airing) bool { return a.Program.Education },
+		"catchup":             func(a Airing) bool { return a.Program.Catchup },
+		"continued":           func(a Airing) bool { return a.Program.Continued },
+		"subjectToBlackout":   func(a Airing) bool { return a.Program.SubjectToBlackout },
+		"timeApproximate":     func(a Airing) bool { return a.Program.TimeApproximate },
+		"joinedInProgress":    func(a Airing) bool { return a.Program.JoinedInProgress },
+		"leftInProgress":      func(a Airing) bool { return a.Program.LeftInProgress },
+		"programBreak":        func(a Airing) bool { return a.Program.ProgramBreak },
+		"cableInTheClassroom": func(a Airing) bool { return a.Program.CableInTheClassroom },
+		"sports":              IsSportsAiring,
+		"movie": func(a Airing) bool {
+			return strings.HasPrefix(a.Program.ProgramID, "MV") || (a.Info != nil && a.Info.EntityType == MovieEntityType)
+		},
+	}
+	for name, flag := range flags {
+		fields[name] = filterField{name: name, kind: flagFilterField, flag: flag}
+	}
+
+	info := func(a Airing) *ProgramInfo {
+		if a.Info == nil {
+			return &ProgramInfo{}
+		}
+		return a.Info
+	}
+	texts := map[string]func(Airing) []string{
+		"programID":     func(a Airing) []string { return []string{a.Program.ProgramID} },
+		"stationID":     func(a Airing) []string { return []string{a.StationID} },
+		"title":         func(a Airing) []string { return []string{a.Title()} },
+		"episodeTitle":  func(a Airing) []string { return []string{info(a).EpisodeTitle150} },
+		"entityType":    func(a Airing) []string { return []string{string(info(a).EntityType)} },
+		"showType":      func(a Airing) []string { return []string{string(info(a).ShowType)} },
+		"liveTapeDelay": func(a Airing) []string { return []string{string(a.Program.LiveTapeDelay)} },
+		"genre":         func(a Airing) []string { return info(a).Genres },
+		"video": func(a Airing) []string {
+			video := make([]string, 0, len(a.Program.VideoProperties))
+			for _, property := range a.Program.VideoProperties {
+				video = append(video, string(property))
+			}
+			return video
+		},
+		"audio": func(a Airing) []string {
+			audio := make([]string, 0, len(a.Program.AudioProperties))
+			for _, property := range a.Program.AudioProperties {
+				audio = append(audio, string(property))
+			}
+			return audio
+		},
+		"advisory": func(a Airing) []string { return info(a).ContentAdvisory },
+	}
+	for name, text := range texts {
+		fields[name] = filterField{name: name, kind: textFilterField, text: text}
+	}
+
+	// premiereOrFinale is both a flag and a text field.
+	fields["premiereOrFinale"] = filterField{
+		name: "premiereOrFinale",
+		kind: textFilterField,
+		flag: func(a Airing) bool { return a.Program.IsPremiereOrFinale != nil },
+		text: func(a Airing) []string {
+			if a.Program.IsPremiereOrFinale == nil {
+				return nil
+			}
+			return []string{string(*a.Program.IsPremiereOrFinale)}
+		},
+	}
+
+	fields["stars"] = filterField{name: "stars", kind: numberFilterField, number: func(a Airing) (float64, bool) {
+		if a.Info == nil {
+			return 0, false
+		}
+		rating, ok := StarRatingNormalizer{}.Normalize(a.Info.Movie)
+		return rating.Stars, ok
+	}}
+	fields["year"] = filterField{name: "year", kind: numberFilterField, number: func(a Airing) (float64, bool) {
+		if a.Info == nil || a.Info.Movie == nil || a.Info.Movie.Year == nil || a.Info.Movie.Year.Time == nil {
+			return 0, false
+		}
+		return float64(a.Info.Movie.Year.Year()), true
+	}}
+	fields["duration"] = filterField{name: "duration", kind: durationFilterField, number: func(a Airing) (float64, bool) {
+		return float64(a.Program.Duration), true
+	}}
+	fields["start"] = filterField{name: "start", kind: clockFilterField}
+	fields["end"] = filterField{name: "end", kind: clockFilterField}
+	fields["day"] = filterField{name: "day", kind: weekdayFilterField}
+	fields["lineup"] = filterField{name: "lineup", kind: lineupFilterField}
+	fields["rating"] = filterField{name: "rating", kind: ratingFilterField}
+
+	lower := make(map[string]filterField, len(fields))
+	for name, field := range fields {
+		lower[strings.ToLower(name)] = field
+	}
+	return lower
+}
+
+func lookupFilterField(name string) (filterField, bool) {
+	field, ok := filterFieldsByName[strings.ToLower(name)]
+	return field, ok
+}
+
+type filterTokenKind int
+
+const (
+	filterEOF filterTokenKind = iota
+	filterWord
+	filterString
+	filterOperator
+	filterLeftParen
+	filterRightParen
+	filterComma
+)
+
+type filterToken struct {
+	kind   filterTokenKind
+	text   string
+	offset int
+}
+
+func lexFilter(expr string) ([]filterToken, error) {
+	tokens := make([]filterToken, 0)
+	runes := []rune(expr)
+
+	isWordRune := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(":._-+/", r)
+	}
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, filterToken{filterLeftParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{filterRightParen, ")", i})
+			i++
+		case r == ',':
+			tokens = append(tokens, filterToken{filterComma, ",", i})
+			i++
+		case r == '"':
+			end := i + 1
+			for ; end < len(runes) && runes[end] != '"'; end++ {
+				if runes[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("invalid filter %q at offset %d: unterminated string", expr, i)
+			}
+			text, err := strconv.Unquote(string(runes[i : end+1]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid filter %q at offset %d: %s", expr, i, err)
+			}
+			tokens = append(tokens, filterToken{filterString, text, i})
+			i = end + 1
+		case strings.ContainsRune("=!<>~", r):
+			op := string(r)
+			if i+1 < len(runes) && runes[i+1] == '=' && r != '=' && r != '~' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("invalid filter %q at offset %d: expected !=", expr, i)
+			}
+			tokens = append(tokens, filterToken{filterOperator, op, i})
+			i += len(op)
+		case isWordRune(r):
+			end := i
+			for end < len(runes) && isWordRune(runes[end]) {
+				end++
+			}
+			tokens = append(tokens, filterToken{filterWord, string(runes[i:end]), i})
+			i = end
+		default:
+			return nil, fmt.Errorf("invalid filter %q at offset %d: unexpected %q", expr, i, r)
+		}
+	}
+
+	return append(tokens, filterToken{filterEOF, "end of filter", len(runes)}), nil
+}
+
+type filterParser struct {
+	source string
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() filterToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != filterEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *filterParser) isKeyword(tok filterToken, keyword string) bool {
+	return tok.kind == filterWord && strings.EqualFold(tok.text, keyword)
+}
+
+func (p *filterParser) errorf(tok filterToken, format string, args ...interface{}) error {
+	return fmt.Errorf("invalid filter %q at offset %d: %s", p.source, tok.offset, fmt.Sprintf(format, args...))
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword(p.peek(), "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword(p.peek(), "and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseNot() (filterNode, error) {
+	if p.isKeyword(p.peek(), "not") {
+		p.next()
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (filterNode, error) {
+	tok := p.next()
+
+	if tok.kind == filterLeftParen {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != filterRightParen {
+			return nil, p.errorf(closing, "expected ) but found %q", closing.text)
+		}
+		return node, nil
+	}
+
+	if tok.kind != filterWord {
+		return nil, p.errorf(tok, "expected a field but found %q", tok.text)
+	}
+
+	field, ok := lookupFilterField(tok.text)
+	if !ok {
+		return nil, p.errorf(tok, "unknown field %q", tok.text)
+	}
+
+	op := p.peek()
+	isComparison := op.kind == filterOperator || p.isKeyword(op, "in")
+	if !isComparison {
+		if field.flag == nil {
+			return nil, p.errorf(op, "field %q needs a comparison", field.name)
+		}
+		return flagNode{flag: field.flag, want: true}, nil
+	}
+	p.next()
+
+	opText := strings.ToLower(op.text)
+	if field.kind == flagFilterField {
+		return p.parseFlagComparison(field, op)
+	}
+	if err := p.checkOperator(field, op); err != nil {
+		return nil, err
+	}
+
+	valueTokens := make([]filterToken, 0)
+	if opText == "in" {
+		if open := p.next(); open.kind != filterLeftParen {
+			return nil, p.errorf(open, "expected ( after in but found %q", open.text)
+		}
+		for {
+			valueTokens = append(valueTokens, p.next())
+			sep := p.next()
+			if sep.kind == filterRightParen {
+				break
+			}
+			if sep.kind != filterComma {
+				return nil, p.errorf(sep, "expected , or ) but found %q", sep.text)
+			}
+		}
+	} else {
+		valueTokens = append(valueTokens, p.next())
+	}
+
+	node := compareNode{field: field, op: opText, values: make([]filterValue, 0, len(valueTokens))}
+	for _, valueToken := range valueTokens {
+		value, err := p.parseValue(field, valueToken)
+		if err != nil {
+			return nil, err
+		}
+		node.values = append(node.values, value)
+	}
+
+	// Durations are compared in seconds, like Program.Duration.
+	if field.kind == durationFilterField {
+		node.field.kind = numberFilterField
+	}
+
+	return node, nil
+}
+
+func (p *filterParser) parseFlagComparison(field filterField, op filterToken) (filterNode, error) {
+	if op.text != "=" && op.text != "!=" {
+		return nil, p.errorf(op, "flag %q can only be compared with = or !=", field.name)
+	}
+	valueToken := p.next()
+	want, err := strconv.ParseBool(strings.ToLower(valueToken.text))
+	if err != nil || (valueToken.kind != filterWord && valueToken.kind != filterString) {
+		return nil, p.errorf(valueToken, "flag %q can only be compared with true or false", field.name)
+	}
+	if op.text == "!=" {
+		want = !want
+	}
+	return flagNode{flag: field.flag, want: want}, nil
+}
+
+func (p *filterParser) checkOperator(field filterField, op filterToken) error {
+	switch strings.ToLower(op.text) {
+	case "=", "!=", "in":
+		return nil
+	case "~":
+		if field.kind == textFilterField {
+			return nil
+		}
+	case "<", "<=", ">", ">=":
+		if field.kind != textFilterField && field.kind != lineupFilterField && field.kind != weekdayFilterField {
+			return nil
+		}
+	}
+	return p.errorf(op, "field %q can not be compared with %s", field.name, op.text)
+}
+
+func (p *filterParser) parseValue(field filterField, tok filterToken) (filterValue, error) {
+	if tok.kind != filterWord && tok.kind != filterString {
+		return filterValue{}, p.errorf(tok, "expected a value but found %q", tok.text)
+	}
+
+	value := filterValue{text: tok.text}
+	var err error
+
+	switch field.kind {
+	case numberFilterField:
+		value.number, err = strconv.ParseFloat(tok.text, 64)
+	case durationFilterField:
+		var duration time.Duration
+		duration, err = time.ParseDuration(tok.text)
+		value.number = duration.Seconds()
+	case clockFilterField:
+		var clock time.Time
+		clock, err = time.Parse("15:04", tok.text)
+		value.number = float64(clock.Hour()*60 + clock.Minute())
+	case weekdayFilterField:
+		err = fmt.Errorf("unknown day")
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if len(tok.text) >= 3 && strings.HasPrefix(strings.ToLower(day.String()), strings.ToLower(tok.text)) {
+				value.number, err = float64(day), nil
+			}
+		}
+	}
+
+	if err != nil {
+		return filterValue{}, p.errorf(tok, "invalid value %q for field %q", tok.text, field.name)
+	}
+	return value, nil
+}
@@ -0,0 +1,141 @@
+package schedulesdirect
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testFilterAirings() []Airing {
+	football := Airing{StationID: "10001", Program: testProgram("SP000000000001", "2015-03-03T19:00:00Z", 10800)}
+	football.Program.New = true
+	football.Program.LiveTapeDelay = Live
+	football.Program.VideoProperties = VideoProperties{HDTVVideo}
+	football.Info = &ProgramInfo{EntityType: SportsEntityType, Genres: []string{"Football"}, EventDetails: &EventDetails{}}
+
+	movie := Airing{StationID: "10002", Program: testProgram("MV000000010000", "2015-03-07T21:00:00Z", 7200)}
+	movie.Program.Ratings = []ContentRating{{Body: "USA Parental Rating", Code: "TV14"}}
+	movie.Info = &ProgramInfo{
+		EntityType:    MovieEntityType,
+		Genres:        []string{"Comedy", "Science fiction"},
+		ContentRating: []ContentRating{{Body: "Motion Picture Association of America", Code: "PG-13"}},
+		Movie:         &Movie{QualityRating: []MovieQualityRating{{RatingsBody: "Gracenote", Rating: "3.5", MaxRating: "4"}}},
+	}
+
+	rerun := Airing{StationID: "10003", Program: testProgram("EP000000060003", "2015-03-03T23:30:00Z", 1800)}
+	rerun.Program.AudioProperties = AudioProperties{ClosedCaptionedAudio, StereoAudio}
+	rerun.Info = &ProgramInfo{EntityType: EpisodeEntityType, Titles: []Title{{Title120: "'Allo 'Allo!"}}, Genres: []string{"Sitcom"}}
+
+	return []Airing{football, movie, rerun}
+}
+
+func filterProgramIDs(t *testing.T, expr string, configure ...func(*AiringFilter)) []string {
+	t.Helper()
+
+	filter, err := ParseAiringFilter(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fn := range configure {
+		fn(filter)
+	}
+
+	ids := make([]string, 0)
+	for _, airing := range filter.Filter(testFilterAirings()) {
+		ids = append(ids, airing.Program.ProgramID)
+	}
+	return ids
+}
+
+func TestAiringFilterMatch(t *testing.T) {
+	lineup := func(f *AiringFilter) {
+		f.AddLineupStations(&ChannelResponse{
+			Stations: []Station{{StationID: "10001"}, {StationID: "10003"}},
+			Metadata: &ChannelResponseMeta{Lineup: "USA-OTA-10001"},
+		})
+	}
+
+	assert.Equal(t, []string{"SP000000000001"}, filterProgramIDs(t, "sports and new and HD and lineup = USA-OTA-10001 and start >= 18:00 and start < 23:00", lineup))
+	assert.Equal(t, []string{"MV000000010000"}, filterProgramIDs(t, "movie and rating < R and stars >= 3"))
+	assert.Empty(t, filterProgramIDs(t, "movie and rating < PG and stars >= 3"))
+	assert.Equal(t, []string{"MV000000010000"}, filterProgramIDs(t, `rating = "tv-14"`))
+	assert.Equal(t, []string{"MV000000010000"}, filterProgramIDs(t, "rating <= teen and rating > 12A"))
+
+	assert.Equal(t, []string{"SP000000000001", "MV000000010000", "EP000000060003"}, filterProgramIDs(t, ""))
+	assert.Equal(t, []string{"MV000000010000", "EP000000060003"}, filterProgramIDs(t, "not (live or sports)"))
+	assert.Equal(t, []string{"SP000000000001", "EP000000060003"}, filterProgramIDs(t, "genre in (football, sitcom)"))
+	assert.Equal(t, []string{"MV000000010000"}, filterProgramIDs(t, `genre ~ "fiction" and entityType = Movie`))
+	assert.Equal(t, []string{"EP000000060003"}, filterProgramIDs(t, "cc and audio = STEREO and title ~ allo"))
+	assert.Equal(t, []string{"SP000000000001", "EP000000060003"}, filterProgramIDs(t, "duration <= 3h and stationID != 10002"))
+
+	// Comparisons of missing values never match, not even with !=.
+	assert.Equal(t, []string{"EP000000060003"}, filterProgramIDs(t, "title != Brazil"))
+	assert.Equal(t, []string{"MV000000010000"}, filterProgramIDs(t, "rating != G"))
+	assert.Equal(t, []string{"SP000000000001", "MV000000010000", "EP000000060003"}, filterProgramIDs(t, "not title = Brazil"))
+	assert.Equal(t, []string{"MV000000010000"}, filterProgramIDs(t, "day in (sat, sun)"))
+	assert.Equal(t, []string{"MV000000010000", "EP000000060003"}, filterProgramIDs(t, "new = false"))
+	assert.Equal(t, []string{"EP000000060003"}, filterProgramIDs(t, "lineup = USA-OTA-10001 and not sports or lineup = unknown", lineup))
+
+	// 23:30 UTC is 18:30 in New York.
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"EP000000060003"}, filterProgramIDs(t, "start >= 18:00 and start < 19:00", func(f *AiringFilter) { f.Location = newYork }))
+}
+
+func TestLookupFilterField(t *testing.T) {
+	field, ok := lookupFilterField("EpisodeTitle")
+	assert.True(t, ok)
+	assert.Equal(t, "episodeTitle", field.name)
+	_, ok = lookupFilterField("nonsense")
+	assert.False(t, ok)
+}
+
+func TestAiringFilterParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"unknown",
+		"new and",
+		"(new",
+		"new)",
+		"title",
+		"title < x",
+		"stars ~ 3",
+		"stars >= three",
+		"start >= 25:00",
+		"day = someday",
+		"new < true",
+		"new = maybe",
+		"genre in (comedy",
+		`title = "unterminated`,
+		"title ! x",
+		"title = x & y",
+	} {
+		_, err := ParseAiringFilter(expr)
+		assert.Error(t, err, expr)
+	}
+
+	assert.Panics(t, func() { MustParseAiringFilter("title") })
+}
+
+func TestAiringFilterConfig(t *testing.T) {
+	var config struct {
+		Filter *AiringFilter `json:"filter"`
+	}
+
+	if err := json.Unmarshal([]byte(`{"filter":"movie and stars >= 3"}`), &config); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "movie and stars >= 3", config.Filter.String())
+	assert.Len(t, config.Filter.Filter(testFilterAirings()), 1)
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.JSONEq(t, `{"filter":"movie and stars >= 3"}`, string(data))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"filter":"movie and"}`), &config))
+}
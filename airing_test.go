@@ -0,0 +1,87 @@
+package schedulesdirect
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAiringKey(t *testing.T) {
+	airing := Airing{StationID: "20454", Program: testProgram("SH005371070000", "2015-03-02T19:00:00-05:00", 1800)}
+
+	key := airing.Key()
+	assert.Equal(t, "20454.20150303T000000Z.SH005371070000", key.String())
+
+	parsed, err := ParseAiringKey(key.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, key, parsed)
+
+	keys := map[AiringKey]bool{key: true}
+	sameAiring := Airing{StationID: "20454", Program: testProgram("SH005371070000", "2015-03-03T00:00:00Z", 1800)}
+	assert.True(t, keys[sameAiring.Key()])
+
+	for _, invalid := range []string{"", "20454.SH005371070000", "20454.yesterday.SH005371070000", ".20150303T000000Z.SH005371070000"} {
+		_, err := ParseAiringKey(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestAiringIsRepeat(t *testing.T) {
+	originalAirDate := time.Date(1985, 11, 4, 0, 0, 0, 0, time.UTC)
+	info := &ProgramInfo{OriginalAirDate: &Date{Time: &originalAirDate}}
+
+	rerun := Airing{Program: testProgram("EP000000060003", "2015-03-03T20:00:00Z", 1800), Info: info}
+	assert.True(t, rerun.IsRepeat())
+
+	rerun.Program.New = true
+	assert.False(t, rerun.IsRepeat())
+
+	sameDay := Airing{Program: testProgram("EP000000060003", "1985-11-05T02:00:00Z", 1800), Info: info}
+	assert.False(t, sameDay.IsRepeat())
+
+	encore := Airing{Program: testProgram("SP000000000001", "2015-03-03T20:00:00Z", 1800)}
+	encore.Program.Repeat = true
+	assert.True(t, encore.IsRepeat())
+}
+
+func TestGroupAirings(t *testing.T) {
+	newEpisode := func(stationID, airDateTime string) Airing {
+		airing := Airing{StationID: stationID, Program: testProgram("EP000000060003", airDateTime, 1800)}
+		airing.Program.New = true
+		return airing
+	}
+
+	airings := []Airing{
+		newEpisode("10002", "2015-03-04T01:00:00Z"), // West feed
+		newEpisode("10001", "2015-03-03T22:00:00Z"), // East HD
+		newEpisode("10011", "2015-03-03T22:00:00Z"), // East SD
+		newEpisode("10001", "2015-03-03T22:00:00Z"), // Duplicate
+		{StationID: "10001", Program: testProgram("EP000000060002", "2015-03-03T21:30:00Z", 1800)},
+	}
+
+	groups := GroupAirings(airings)
+
+	if assert.Len(t, groups, 2) {
+		assert.Equal(t, "EP000000060002", groups[0].ProgramID)
+
+		group := groups[1]
+		assert.True(t, group.IsPremiere())
+		if assert.Len(t, group.Airings, 3) {
+			assert.Len(t, group.FirstShowings(), 2)
+
+			assert.True(t, group.Airings[0].FirstShowing)
+			assert.True(t, group.Airings[0].Simulcast)
+			assert.False(t, group.Airings[0].Repeat)
+			assert.Equal(t, "10011", group.Airings[1].StationID)
+			assert.True(t, group.Airings[1].FirstShowing)
+
+			assert.Equal(t, "10002", group.Airings[2].StationID)
+			assert.False(t, group.Airings[2].FirstShowing)
+			assert.False(t, group.Airings[2].Simulcast)
+			assert.True(t, group.Airings[2].Repeat)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 )
@@ -151,6 +152,45 @@ func (ar *ArtworkResponse) UnmarshalJSON(b []byte) error {
 	return json.Unmarshal(ar.wrapper.Data, &ar.Error)
 }
 
+// programArtwork returns the artwork of the program found in the index, looking it up by each of its artwork
+// lookup IDs, their 10 character root IDs and the root ID of the program itself, which holds the artwork of
+// episodes without HasEpisodeArtwork. The episode image of the program comes first.
+func programArtwork(info *ProgramInfo, index map[string][]Artwork) []Artwork {
+	artwork := make([]Artwork, 0)
+	if info.EpisodeImage != nil {
+		artwork = append(artwork, *info.EpisodeImage)
+	}
+	if len(info.ProgramID) < 10 {
+		return artwork
+	}
+
+	ids := []string{info.ProgramID[:10]}
+	for _, id := range info.ArtworkLookupIDs() {
+		ids = append(ids, id, id[:10])
+	}
+	for _, id := range dedupeStrings(ids) {
+		artwork = append(artwork, index[id]...)
+	}
+	return artwork
+}
+
+// selectArtwork returns the primary, then largest, artwork matching the filter, or nil if none match.
+func selectArtwork(artwork []Artwork, match func(Artwork) bool) *Artwork {
+	var best *Artwork
+	for idx := range artwork {
+		candidate := &artwork[idx]
+		if candidate.URI == "" || !match(*candidate) {
+			continue
+		}
+		if best == nil ||
+			(candidate.Primary.bool && !best.Primary.bool) ||
+			(candidate.Primary.bool == best.Primary.bool && candidate.Width*candidate.Height > best.Width*best.Height) {
+			best = candidate
+		}
+	}
+	return best
+}
+
 // GetArtworkForProgramIDs returns artwork for the given programIDs.
 //
 // If more than 500 Program IDs are provided, the client will automatically
@@ -160,6 +200,7 @@ func (c *Client) GetArtworkForProgramIDs(programIDs []string) ([]ArtworkResponse
 	// chunking the requests for them.
 	// Obviously you can disable this behavior by passing less than 500 IDs.
 	if len(programIDs) > 500 {
+		c.log(slog.LevelDebug, "chunking schedules direct request", slog.String("endpoint", "/metadata/programs"), slog.Int("ids", len(programIDs)), slog.Int("chunkSize", 500))
 		allResponses := make([]ArtworkResponse, 0)
 		for _, chunk := range chunkStringSlice(programIDs, 500) {
 			resp, err := c.GetArtworkForProgramIDs(chunk)
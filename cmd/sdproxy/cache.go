@@ -0,0 +1,234 @@
+package main
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	key    string
+	md5    string
+	data   json.RawMessage
+	stored time.Time
+}
+
+// objectCache holds raw JSON objects by key along with the MD5 Schedules Direct reported for them.
+// Once it holds maxEntries objects, the least recently used object is evicted for each new one.
+type objectCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// recent orders the entries from most to least recently used.
+	recent *list.List
+	// ttl is how long an entry is used when its current MD5 is not known.
+	ttl time.Duration
+	// maxEntries is the most objects kept, unlimited if not positive.
+	maxEntries int
+	now        func() time.Time
+}
+
+func newObjectCache(ttl time.Duration, maxEntries int) *objectCache {
+	return &objectCache{entries: make(map[string]*list.Element), recent: list.New(), ttl: ttl, maxEntries: maxEntries, now: time.Now}
+}
+
+// get returns the object stored for key if it has the given MD5.
+// If md5 is empty the object is returned if it was stored less than ttl ago.
+func (c *objectCache) get(key, md5 string) (json.RawMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.recent.MoveToFront(element)
+
+	entry := element.Value.(*cacheEntry)
+	if md5 != "" {
+		return entry.data, entry.md5 == md5
+	}
+	return entry.data, c.now().Sub(entry.stored) < c.ttl
+}
+
+func (c *objectCache) put(key, md5 string, data json.RawMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry{key: key, md5: md5, data: data, stored: c.now()}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.recent.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.recent.PushFront(entry)
+
+	for c.maxEntries > 0 && c.recent.Len() > c.maxEntries {
+		oldest := c.recent.Back()
+		c.recent.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// md5 returns the MD5 stored for key, or an empty string if key is not cached.
+func (c *objectCache) md5(key string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return ""
+	}
+	c.recent.MoveToFront(element)
+	return element.Value.(*cacheEntry).md5
+}
+
+func (c *objectCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// md5Index remembers the latest MD5 of each program seen in a schedule.
+// Like an objectCache it forgets the least recently used programs once it holds maxEntries.
+type md5Index struct {
+	programs *objectCache
+}
+
+func newMD5Index(maxEntries int) md5Index {
+	return md5Index{programs: newObjectCache(0, maxEntries)}
+}
+
+func (i md5Index) get(programID string) string {
+	return i.programs.md5(programID)
+}
+
+func (i md5Index) set(programID, md5 string) {
+	i.programs.put(programID, md5, nil)
+}
+
+// imageCache stores images on disk. Images never change for a given URI.
+// Once the images take more than maxBytes, the least recently used images are removed.
+type imageCache struct {
+	dir string
+	// maxBytes is the most bytes of images kept on disk, unlimited if not positive.
+	maxBytes int64
+
+	mu sync.Mutex
+	// size is the number of bytes of images on disk, negative until the directory was scanned.
+	size int64
+}
+
+func newImageCache(dir string, maxBytes int64) *imageCache {
+	return &imageCache{dir: dir, maxBytes: maxBytes, size: -1}
+}
+
+// path returns the file an image is stored in, named after a hash of its URI.
+func (c *imageCache) path(uri string) string {
+	sum := sha256.Sum256([]byte(uri))
+	ext := strings.ToLower(path.Ext(strings.SplitN(uri, "?", 2)[0]))
+	if len(ext) > 5 || strings.ContainsAny(ext, `/\`) {
+		ext = ""
+	}
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+ext)
+}
+
+// get returns the image and marks it as recently used.
+func (c *imageCache) get(uri string) ([]byte, bool) {
+	file := c.path(uri)
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(file, now, now)
+	return data, true
+}
+
+// put writes the image to a temporary file first so readers never see a partial image.
+func (c *imageCache) put(uri string, data []byte) error {
+	if err := os.MkdirAll(c.dir, 0750); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(c.dir, ".image-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path(uri)); err != nil {
+		return err
+	}
+
+	if c.maxBytes <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.size >= 0 {
+		c.size += int64(len(data))
+	}
+	if c.size < 0 || c.size > c.maxBytes {
+		return c.prune()
+	}
+	return nil
+}
+
+// prune measures the images on disk and, if they take more than maxBytes, removes the least
+// recently used images until they take at most 90% of it, so that not every put has to prune.
+func (c *imageCache) prune() error {
+	files, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	images := make([]os.FileInfo, 0, len(files))
+	size := int64(0)
+	for _, file := range files {
+		// Skip the temporary files of images being written.
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		images = append(images, file)
+		size += file.Size()
+	}
+
+	if size > c.maxBytes {
+		sort.Slice(images, func(i, j int) bool {
+			return images[i].ModTime().Before(images[j].ModTime())
+		})
+		target := c.maxBytes - c.maxBytes/10
+		for _, image := range images {
+			if size <= target {
+				break
+			}
+			if err := os.Remove(filepath.Join(c.dir, image.Name())); err != nil && !os.IsNotExist(err) {
+				c.size = size
+				return err
+			}
+			size -= image.Size()
+		}
+	}
+
+	c.size = size
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObjectCache(t *testing.T) {
+	now := time.Date(2015, 3, 3, 0, 0, 0, 0, time.UTC)
+	cache := newObjectCache(time.Hour, 0)
+	cache.now = func() time.Time { return now }
+
+	_, ok := cache.get("EP000000060003", "")
+	assert.False(t, ok)
+
+	cache.put("EP000000060003", "abc", json.RawMessage(`{"programID":"EP000000060003"}`))
+
+	data, ok := cache.get("EP000000060003", "abc")
+	assert.True(t, ok)
+	assert.JSONEq(t, `{"programID":"EP000000060003"}`, string(data))
+
+	_, ok = cache.get("EP000000060003", "def")
+	assert.False(t, ok)
+
+	_, ok = cache.get("EP000000060003", "")
+	assert.True(t, ok)
+
+	now = now.Add(2 * time.Hour)
+	_, ok = cache.get("EP000000060003", "")
+	assert.False(t, ok)
+	_, ok = cache.get("EP000000060003", "abc")
+	assert.True(t, ok)
+}
+
+func TestObjectCacheEviction(t *testing.T) {
+	cache := newObjectCache(time.Hour, 2)
+
+	cache.put("EP000000060001", "a", json.RawMessage(`{}`))
+	cache.put("EP000000060002", "b", json.RawMessage(`{}`))
+	_, ok := cache.get("EP000000060001", "a")
+	assert.True(t, ok)
+
+	cache.put("EP000000060003", "c", json.RawMessage(`{}`))
+	assert.Equal(t, 2, cache.len())
+
+	_, ok = cache.get("EP000000060002", "b")
+	assert.False(t, ok, "least recently used entry is evicted")
+	_, ok = cache.get("EP000000060001", "a")
+	assert.True(t, ok)
+
+	cache.put("EP000000060001", "d", json.RawMessage(`{}`))
+	assert.Equal(t, 2, cache.len())
+	_, ok = cache.get("EP000000060003", "c")
+	assert.True(t, ok)
+}
+
+func TestMD5Index(t *testing.T) {
+	index := newMD5Index(2)
+	assert.Empty(t, index.get("EP000000060001"))
+
+	index.set("EP000000060001", "a")
+	index.set("EP000000060002", "b")
+	assert.Equal(t, "a", index.get("EP000000060001"))
+	index.set("EP000000060003", "c")
+
+	assert.Empty(t, index.get("EP000000060002"), "least recently used program is forgotten")
+	assert.Equal(t, "a", index.get("EP000000060001"))
+	assert.Equal(t, "c", index.get("EP000000060003"))
+}
+
+func TestImageCache(t *testing.T) {
+	cache := newImageCache(filepath.Join(t.TempDir(), "images"), 0)
+
+	_, ok := cache.get("assets/p12345_b_h6_aa.jpg")
+	assert.False(t, ok)
+
+	if err := cache.put("assets/p12345_b_h6_aa.jpg", []byte("image")); err != nil {
+		t.Fatal(err)
+	}
+
+	data, ok := cache.get("assets/p12345_b_h6_aa.jpg")
+	assert.True(t, ok)
+	assert.Equal(t, "image", string(data))
+
+	assert.True(t, strings.HasSuffix(cache.path("assets/p12345_b_h6_aa.jpg"), ".jpg"))
+	assert.Equal(t, cache.dir, filepath.Dir(cache.path("../../etc/passwd")))
+	assert.False(t, strings.HasSuffix(cache.path("https://s3.amazonaws.com/image?x=/a.b"), ".b"))
+}
+
+func TestImageCachePrune(t *testing.T) {
+	cache := newImageCache(t.TempDir(), 25)
+	image := []byte("0123456789")
+
+	for _, uri := range []string{"assets/a.jpg", "assets/b.jpg"} {
+		if err := cache.put(uri, image); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Make a.jpg the most recently used image.
+	past := time.Now().Add(-time.Hour)
+	_ = os.Chtimes(cache.path("assets/a.jpg"), past, past)
+	_ = os.Chtimes(cache.path("assets/b.jpg"), past.Add(-time.Minute), past.Add(-time.Minute))
+	_, ok := cache.get("assets/a.jpg")
+	assert.True(t, ok)
+
+	if err := cache.put("assets/c.jpg", image); err != nil {
+		t.Fatal(err)
+	}
+
+	_, ok = cache.get("assets/b.jpg")
+	assert.False(t, ok, "least recently used image is removed")
+	for _, uri := range []string{"assets/a.jpg", "assets/c.jpg"} {
+		_, ok = cache.get(uri)
+		assert.True(t, ok, uri)
+	}
+	assert.Equal(t, int64(20), cache.size)
+}
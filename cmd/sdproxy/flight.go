@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// upstreamResponse is a response which can be replayed to any number of LAN clients.
+type upstreamResponse struct {
+	status      int
+	contentType string
+	body        []byte
+}
+
+type flightCall struct {
+	wg       sync.WaitGroup
+	response *upstreamResponse
+	err      error
+	// dups is the number of callers waiting on the call.
+	dups int
+}
+
+// flightGroup coalesces concurrent calls with the same key into a single call.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// do runs fn unless a call with the same key is already running, in which case it waits for that call instead.
+// shared is true if the result came from another caller's call.
+func (g *flightGroup) do(key string, fn func() (*upstreamResponse, error)) (response *upstreamResponse, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if call, ok := g.calls[key]; ok {
+		call.dups++
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.response, true, call.err
+	}
+
+	call := &flightCall{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	// Release the waiters and forget the call even if fn panics, they then get call.err.
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		call.wg.Done()
+	}()
+
+	call.err = fmt.Errorf("upstream call for %q did not complete", key)
+	call.response, call.err = fn()
+
+	return call.response, false, call.err
+}
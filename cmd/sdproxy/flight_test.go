@@ -0,0 +1,120 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlightGroup(t *testing.T) {
+	var group flightGroup
+	var calls int32
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	fn := func() (*upstreamResponse, error) {
+		atomic.AddInt32(&calls, 1)
+		close(started)
+		<-release
+		return &upstreamResponse{status: 200, body: []byte("ok")}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]*upstreamResponse, 3)
+	shared := make([]bool, 3)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[0], shared[0], _ = group.do("key", fn)
+	}()
+	<-started
+
+	for i := 1; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], shared[i], _ = group.do("key", func() (*upstreamResponse, error) {
+				t.Error("call should have been coalesced")
+				return nil, nil
+			})
+		}(i)
+	}
+
+	// Release the running call once both callers are waiting on it.
+	for {
+		group.mu.Lock()
+		waiting := group.calls["key"].dups
+		group.mu.Unlock()
+		if waiting == 2 {
+			break
+		}
+		runtime.Gosched()
+	}
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.False(t, shared[0])
+	for i := 1; i < 3; i++ {
+		assert.True(t, shared[i])
+		assert.Equal(t, "ok", string(results[i].body))
+	}
+
+	response, wasShared, err := group.do("key", func() (*upstreamResponse, error) {
+		return &upstreamResponse{body: []byte("again")}, nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, wasShared)
+	assert.Equal(t, "again", string(response.body))
+}
+
+func TestFlightGroupPanic(t *testing.T) {
+	var group flightGroup
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	waited := make(chan error)
+
+	go func() {
+		defer func() { _ = recover() }()
+		_, _, _ = group.do("key", func() (*upstreamResponse, error) {
+			close(started)
+			<-release
+			panic("upstream")
+		})
+	}()
+	<-started
+
+	go func() {
+		_, _, err := group.do("key", func() (*upstreamResponse, error) {
+			t.Error("call should have been coalesced")
+			return nil, nil
+		})
+		waited <- err
+	}()
+
+	for {
+		group.mu.Lock()
+		waiting := group.calls["key"].dups
+		group.mu.Unlock()
+		if waiting == 1 {
+			break
+		}
+		runtime.Gosched()
+	}
+	close(release)
+
+	assert.Error(t, <-waited)
+
+	response, wasShared, err := group.do("key", func() (*upstreamResponse, error) {
+		return &upstreamResponse{body: []byte("again")}, nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, wasShared)
+	assert.Equal(t, "again", string(response.body))
+}
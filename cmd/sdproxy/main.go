@@ -0,0 +1,87 @@
+// Command sdproxy is a caching Schedules Direct proxy for the local network.
+//
+// It exposes the same 20141201 REST API as Schedules Direct and forwards requests
+// using a single upstream token. Programs, program metadata and schedules are cached
+// in memory by MD5 and images are cached on disk, so several tuners and media servers
+// on a LAN can share one account without repeating downloads.
+//
+// Point clients at the proxy by setting their base URL, e.g. http://sdproxy.lan:8080/,
+// and log in with the same username and password the proxy uses.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	schedulesdirect "github.com/tellytv/go.schedulesdirect"
+)
+
+func main() {
+	defaultCacheDir := "sdproxy-cache"
+	if dir, err := os.UserCacheDir(); err == nil {
+		defaultCacheDir = filepath.Join(dir, "sdproxy")
+	}
+
+	listen := flag.String("listen", ":8080", "address to serve the proxy on")
+	username := flag.String("username", os.Getenv("SD_USERNAME"), "Schedules Direct username, defaults to $SD_USERNAME")
+	password := flag.String("password", os.Getenv("SD_PASSWORD"), "Schedules Direct password, defaults to $SD_PASSWORD")
+	cacheDir := flag.String("cache-dir", defaultCacheDir, "directory images are cached in")
+	ttl := flag.Duration("ttl", 24*time.Hour, "how long programs are cached when their MD5 is unknown")
+	scheduleTTL := flag.Duration("schedule-ttl", time.Hour, "how long schedules are cached before their MD5 is checked again")
+	cacheEntries := flag.Int("cache-entries", 100000, "most programs, metadata, schedules and program MD5s each cached in memory, 0 for unlimited")
+	imageCacheMB := flag.Int64("image-cache-mb", 2048, "most megabytes of images cached on disk, 0 for unlimited")
+	verbose := flag.Bool("verbose", false, "log every request")
+	flag.Parse()
+
+	level := slog.LevelInfo
+	if *verbose {
+		level = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	config := proxyConfig{
+		Username:        *username,
+		Password:        *password,
+		ImageDir:        *cacheDir,
+		MaxImageBytes:   *imageCacheMB << 20,
+		TTL:             *ttl,
+		ScheduleTTL:     *scheduleTTL,
+		MaxCacheEntries: *cacheEntries,
+		Logger:          logger,
+	}
+	if err := run(*listen, config); err != nil {
+		logger.Error("sdproxy stopped", slog.Any("error", err))
+		os.Exit(1)
+	}
+}
+
+func run(listen string, config proxyConfig) error {
+	if config.Username == "" || config.Password == "" {
+		return fmt.Errorf("a Schedules Direct username and password are required")
+	}
+
+	client, clientErr := schedulesdirect.NewClient(config.Username, config.Password)
+	if clientErr != nil {
+		return clientErr
+	}
+	client.Logger = config.Logger
+
+	handler, proxyErr := newProxy(client, config)
+	if proxyErr != nil {
+		return proxyErr
+	}
+
+	server := &http.Server{
+		Addr:              listen,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	config.Logger.Info("serving schedules direct proxy", slog.String("listen", listen), slog.String("cacheDir", config.ImageDir))
+	return server.ListenAndServe()
+}
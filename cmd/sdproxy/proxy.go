@@ -0,0 +1,643 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1" // #nosec
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	schedulesdirect "github.com/tellytv/go.schedulesdirect"
+)
+
+const (
+	serverID = "sdproxy"
+
+	// maxRequestBody limits the size of request bodies accepted from LAN clients.
+	maxRequestBody = 10 << 20
+
+	// programChunkSize and metadataChunkSize are the most IDs Schedules Direct accepts per request.
+	programChunkSize  = 5000
+	metadataChunkSize = 500
+)
+
+// proxyConfig configures a proxy.
+type proxyConfig struct {
+	// Username and Password are the Schedules Direct account, used to refresh the upstream token
+	// and accepted from LAN clients asking the proxy for a token.
+	Username string
+	Password string
+	// ImageDir is where images are cached.
+	ImageDir string
+	// MaxImageBytes is the most bytes of images kept in ImageDir, unlimited if not positive.
+	MaxImageBytes int64
+	// TTL is how long programs and metadata are served from cache when their current MD5 is not known.
+	TTL time.Duration
+	// ScheduleTTL is how long schedules are served from cache before their MD5 is checked upstream again.
+	ScheduleTTL time.Duration
+	// MaxCacheEntries is the most programs, metadata, schedules and program MD5s each kept in memory,
+	// unlimited if not positive.
+	MaxCacheEntries int
+	Logger          *slog.Logger
+}
+
+// proxy serves the Schedules Direct API to LAN clients from a single upstream token.
+// Programs and program metadata are cached by program ID and MD5, schedules by station, date and MD5
+// and images on disk. Identical concurrent upstream requests are coalesced.
+type proxy struct {
+	clientMu sync.Mutex
+	client   *schedulesdirect.Client
+
+	username     string
+	password     string
+	passwordHash string
+	// token is handed to LAN clients, it is never the upstream token.
+	token  string
+	logger *slog.Logger
+
+	programs    *objectCache
+	metadata    *objectCache
+	schedules   *objectCache
+	programMD5s md5Index
+	images      *imageCache
+	flights     flightGroup
+}
+
+func newProxy(client *schedulesdirect.Client, config proxyConfig) (*proxy, error) {
+	hash := sha1.Sum([]byte(config.Password)) // #nosec
+
+	token := make([]byte, 20)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("error generating proxy token: %s", err)
+	}
+
+	return &proxy{
+		client:       client,
+		username:     config.Username,
+		password:     config.Password,
+		passwordHash: hex.EncodeToString(hash[:]),
+		token:        hex.EncodeToString(token),
+		logger:       config.Logger,
+		programs:     newObjectCache(config.TTL, config.MaxCacheEntries),
+		metadata:     newObjectCache(config.TTL, config.MaxCacheEntries),
+		schedules:    newObjectCache(config.ScheduleTTL, config.MaxCacheEntries),
+		programMD5s:  newMD5Index(config.MaxCacheEntries),
+		images:       newImageCache(config.ImageDir, config.MaxImageBytes),
+	}, nil
+}
+
+func (p *proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	prefix := fmt.Sprint("/", schedulesdirect.APIVersion, "/")
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.NotFound(w, r)
+		return
+	}
+	endpoint := "/" + strings.TrimPrefix(r.URL.Path, prefix)
+
+	// Read one byte past the limit to tell a body of exactly maxRequestBody bytes from a larger one.
+	body, readErr := ioutil.ReadAll(io.LimitReader(r.Body, maxRequestBody+1))
+	if readErr != nil {
+		p.writeError(w, readErr)
+		return
+	}
+	if len(body) > maxRequestBody {
+		p.log(slog.LevelWarn, "rejected request body", slog.String("endpoint", endpoint), slog.String("remote", r.RemoteAddr))
+		p.writeJSON(w, http.StatusRequestEntityTooLarge, p.baseResponse(schedulesdirect.ErrInvalidJSON, fmt.Sprintf("Request body is larger than %d bytes.", maxRequestBody)))
+		return
+	}
+
+	p.log(slog.LevelDebug, "handling request", slog.String("method", r.Method), slog.String("endpoint", endpoint), slog.String("remote", r.RemoteAddr))
+
+	if endpoint == "/token" && r.Method == http.MethodPost {
+		p.handleToken(w, body)
+		return
+	}
+
+	if !p.authorize(w, r) {
+		return
+	}
+
+	switch {
+	case endpoint == "/programs" && r.Method == http.MethodPost:
+		p.handleCachedObjects(w, body, p.programs, endpoint, programChunkSize)
+	case strings.TrimSuffix(endpoint, "/") == "/metadata/programs" && r.Method == http.MethodPost:
+		p.handleCachedObjects(w, body, p.metadata, "/metadata/programs", metadataChunkSize)
+	case endpoint == "/schedules" && r.Method == http.MethodPost:
+		p.handleSchedules(w, body)
+	case strings.HasPrefix(endpoint, "/image/") && r.Method == http.MethodGet:
+		p.handleImage(w, strings.TrimPrefix(endpoint, "/image/"))
+	default:
+		p.handlePassthrough(w, r, endpoint, body)
+	}
+}
+
+// handleToken hands the proxy token to LAN clients using the proxy's account.
+func (p *proxy) handleToken(w http.ResponseWriter, body []byte) {
+	credentials := struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}{}
+	if err := json.Unmarshal(body, &credentials); err != nil {
+		p.writeError(w, p.baseResponse(schedulesdirect.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	validUser := subtle.ConstantTimeCompare([]byte(credentials.Username), []byte(p.username)) == 1
+	validPassword := subtle.ConstantTimeCompare([]byte(strings.ToLower(credentials.Password)), []byte(p.passwordHash)) == 1
+	if !validUser || !validPassword {
+		p.log(slog.LevelWarn, "rejected token request", slog.String("username", credentials.Username))
+		p.writeError(w, p.baseResponse(schedulesdirect.ErrInvalidUser, "Invalid user."))
+		return
+	}
+
+	p.writeJSON(w, http.StatusOK, schedulesdirect.TokenResponse{
+		BaseResponse: p.baseResponse(schedulesdirect.ErrOK, "OK"),
+		Token:        p.token,
+	})
+}
+
+func (p *proxy) authorize(w http.ResponseWriter, r *http.Request) bool {
+	token := r.Header.Get("token")
+	if token == "" {
+		p.writeError(w, p.baseResponse(schedulesdirect.ErrTokenMissing, "Token required but not provided in request header."))
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(p.token)) != 1 {
+		p.writeError(w, p.baseResponse(schedulesdirect.ErrInvalidUser, "Invalid token."))
+		return false
+	}
+	return true
+}
+
+// handleCachedObjects serves a list of program IDs from cache, fetching only the missing programs upstream.
+func (p *proxy) handleCachedObjects(w http.ResponseWriter, body []byte, cache *objectCache, endpoint string, chunkSize int) {
+	ids := make([]string, 0)
+	if err := json.Unmarshal(body, &ids); err != nil {
+		p.writeError(w, p.baseResponse(schedulesdirect.ErrInvalidJSON, err.Error()))
+		return
+	}
+	ids = uniqueStrings(ids)
+
+	results := make(map[string]json.RawMessage)
+	misses := make([]string, 0)
+	for _, id := range ids {
+		if data, ok := cache.get(id, p.programMD5s.get(id)); ok {
+			results[id] = data
+		} else {
+			misses = append(misses, id)
+		}
+	}
+	p.log(slog.LevelDebug, "cache lookup", slog.String("endpoint", endpoint), slog.Int("hits", len(ids)-len(misses)), slog.Int("misses", len(misses)))
+
+	for start := 0; start < len(misses); start += chunkSize {
+		end := start + chunkSize
+		if end > len(misses) {
+			end = len(misses)
+		}
+		chunk := misses[start:end]
+
+		js, jsErr := json.Marshal(chunk)
+		if jsErr != nil {
+			p.writeError(w, jsErr)
+			return
+		}
+
+		response, _, err := p.flights.do(endpoint+"\x00"+string(js), func() (*upstreamResponse, error) {
+			return p.send(http.MethodPost, endpoint, js)
+		})
+		if err != nil {
+			p.writeError(w, err)
+			return
+		}
+
+		elements := make([]json.RawMessage, 0)
+		if err := json.Unmarshal(response.body, &elements); err != nil {
+			p.writeError(w, err)
+			return
+		}
+
+		for _, element := range elements {
+			head := struct {
+				ProgramID string          `json:"programID"`
+				MD5       string          `json:"md5"`
+				Code      int             `json:"code"`
+				Data      json.RawMessage `json:"data"`
+			}{}
+			if err := json.Unmarshal(element, &head); err != nil || head.ProgramID == "" {
+				continue
+			}
+			results[head.ProgramID] = element
+
+			// Errors such as PROGRAMID_QUEUED are passed on but never cached.
+			if head.Code != 0 || (head.Data != nil && !bytes.HasPrefix(bytes.TrimSpace(head.Data), []byte("["))) {
+				continue
+			}
+			md5 := head.MD5
+			if md5 == "" {
+				md5 = p.programMD5s.get(head.ProgramID)
+			}
+			cache.put(head.ProgramID, md5, element)
+		}
+	}
+
+	ordered := make([]json.RawMessage, 0, len(ids))
+	for _, id := range ids {
+		if data, ok := results[id]; ok {
+			ordered = append(ordered, data)
+		}
+	}
+	p.writeJSON(w, http.StatusOK, ordered)
+}
+
+// handleSchedules serves schedules from cache as long as their MD5 upstream has not changed.
+// The MD5s are only checked upstream for schedules stored more than ScheduleTTL ago.
+// Requests without dates are passed through.
+func (p *proxy) handleSchedules(w http.ResponseWriter, body []byte) {
+	requests := make([]schedulesdirect.StationScheduleRequest, 0)
+	if err := json.Unmarshal(body, &requests); err != nil {
+		p.writeError(w, p.baseResponse(schedulesdirect.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	for _, request := range requests {
+		if len(request.Dates) == 0 {
+			response, err := p.coalescedSend(http.MethodPost, "/schedules", body)
+			if err != nil {
+				p.writeError(w, err)
+				return
+			}
+			p.recordProgramMD5s(response.body)
+			p.writeUpstream(w, response)
+			return
+		}
+	}
+
+	results := make(map[string]json.RawMessage)
+	order := make([]string, 0)
+	requested := make(map[string]bool)
+	stale := make([]schedulesdirect.StationScheduleRequest, 0)
+
+	// Schedules stored less than ScheduleTTL ago are served without asking upstream for their MD5.
+	for _, request := range requests {
+		requested[request.StationID] = true
+		staleDates := make([]string, 0)
+		for _, date := range request.Dates {
+			key := request.StationID + "/" + date
+			order = append(order, key)
+			if data, ok := p.schedules.get(key, ""); ok {
+				results[key] = data
+				continue
+			}
+			staleDates = append(staleDates, date)
+		}
+		if len(staleDates) > 0 {
+			stale = append(stale, schedulesdirect.StationScheduleRequest{StationID: request.StationID, Dates: staleDates})
+		}
+	}
+
+	missing := make([]schedulesdirect.StationScheduleRequest, 0)
+	if len(stale) > 0 {
+		client, clientErr := p.upstream()
+		if clientErr != nil {
+			p.writeError(w, clientErr)
+			return
+		}
+		lastModified, lastModifiedErr := client.GetLastModified(stale)
+		if lastModifiedErr != nil {
+			p.writeError(w, lastModifiedErr)
+			return
+		}
+
+		for _, request := range stale {
+			missingDates := make([]string, 0)
+			for _, date := range request.Dates {
+				key := request.StationID + "/" + date
+				if md5 := lastModified[request.StationID][date].MD5; md5 != "" {
+					if data, ok := p.schedules.get(key, md5); ok {
+						// Store the schedule again so it is fresh for another ScheduleTTL.
+						p.schedules.put(key, md5, data)
+						results[key] = data
+						continue
+					}
+				}
+				missingDates = append(missingDates, date)
+			}
+			if len(missingDates) > 0 {
+				missing = append(missing, schedulesdirect.StationScheduleRequest{StationID: request.StationID, Dates: missingDates})
+			}
+		}
+	}
+	p.log(slog.LevelDebug, "cache lookup", slog.String("endpoint", "/schedules"), slog.Int("hits", len(results)), slog.Int("misses", len(order)-len(results)))
+
+	extra := make([]json.RawMessage, 0)
+	if len(missing) > 0 {
+		js, jsErr := json.Marshal(missing)
+		if jsErr != nil {
+			p.writeError(w, jsErr)
+			return
+		}
+		response, err := p.coalescedSend(http.MethodPost, "/schedules", js)
+		if err != nil {
+			p.writeError(w, err)
+			return
+		}
+
+		elements := make([]json.RawMessage, 0)
+		if err := json.Unmarshal(response.body, &elements); err != nil {
+			p.writeError(w, err)
+			return
+		}
+
+		for _, element := range elements {
+			head := struct {
+				StationID     string `json:"stationID"`
+				Code          int    `json:"code"`
+				RequestedDate string `json:"requestedDate"`
+				Metadata      struct {
+					MD5       string `json:"md5"`
+					StartDate string `json:"startDate"`
+				} `json:"metadata"`
+			}{}
+			if err := json.Unmarshal(element, &head); err != nil {
+				continue
+			}
+
+			date := head.Metadata.StartDate
+			if date == "" {
+				date = head.RequestedDate
+			}
+			key := head.StationID + "/" + date
+			if !requested[head.StationID] || date == "" {
+				extra = append(extra, element)
+				continue
+			}
+
+			results[key] = element
+			if head.Code == 0 && head.Metadata.MD5 != "" {
+				p.schedules.put(key, head.Metadata.MD5, element)
+			}
+		}
+	}
+
+	ordered := make([]json.RawMessage, 0, len(order))
+	for _, key := range order {
+		if data, ok := results[key]; ok {
+			ordered = append(ordered, data)
+		}
+	}
+	ordered = append(ordered, extra...)
+
+	for _, element := range ordered {
+		p.recordProgramMD5s(element)
+	}
+
+	p.writeJSON(w, http.StatusOK, ordered)
+}
+
+// recordProgramMD5s remembers the program MD5s found in a schedule or a list of schedules.
+func (p *proxy) recordProgramMD5s(data []byte) {
+	type schedule struct {
+		Programs []struct {
+			ProgramID string `json:"programID"`
+			MD5       string `json:"md5"`
+		} `json:"programs"`
+	}
+
+	schedules := make([]schedule, 0)
+	if err := json.Unmarshal(data, &schedules); err != nil {
+		single := schedule{}
+		if err := json.Unmarshal(data, &single); err != nil {
+			return
+		}
+		schedules = append(schedules, single)
+	}
+
+	for _, s := range schedules {
+		for _, program := range s.Programs {
+			if program.ProgramID != "" && program.MD5 != "" {
+				p.programMD5s.set(program.ProgramID, program.MD5)
+			}
+		}
+	}
+}
+
+// handleImage serves images from disk, downloading them once.
+func (p *proxy) handleImage(w http.ResponseWriter, uri string) {
+	if !validImageURI(uri) {
+		p.log(slog.LevelWarn, "rejected image uri", slog.String("uri", uri))
+		p.writeError(w, p.baseResponse(schedulesdirect.ErrImageNotFound, "Invalid image URI."))
+		return
+	}
+
+	data, ok := p.images.get(uri)
+	if !ok {
+		response, _, err := p.flights.do("/image/"+uri, func() (*upstreamResponse, error) {
+			client, clientErr := p.upstream()
+			if clientErr != nil {
+				return nil, clientErr
+			}
+			image, imageErr := client.GetImage(uri)
+			if imageErr != nil {
+				return nil, imageErr
+			}
+			if putErr := p.images.put(uri, image); putErr != nil {
+				p.log(slog.LevelWarn, "error caching image", slog.String("uri", uri), slog.Any("error", putErr))
+			}
+			return &upstreamResponse{status: http.StatusOK, body: image}, nil
+		})
+		if err != nil {
+			p.writeError(w, err)
+			return
+		}
+		data = response.body
+	}
+
+	w.Header().Set("Content-Type", http.DetectContentType(data))
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	if _, err := w.Write(data); err != nil {
+		p.log(slog.LevelDebug, "error writing response", slog.Any("error", err))
+	}
+}
+
+// validImageURI returns true if uri is a path relative to the image endpoint or an image on s3.amazonaws.com.
+// Anything else would make the proxy send its upstream token to another host or API endpoint.
+func validImageURI(uri string) bool {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.User != nil || strings.Contains(uri, `\`) {
+		return false
+	}
+
+	switch {
+	case parsed.Scheme == "" && parsed.Host == "":
+		if parsed.Path == "" || strings.HasPrefix(parsed.Path, "/") {
+			return false
+		}
+	case parsed.Scheme == "https" && parsed.Host == "s3.amazonaws.com":
+	default:
+		return false
+	}
+
+	for _, segment := range strings.Split(parsed.Path, "/") {
+		if segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// handlePassthrough sends the request upstream as is. Only GET requests are coalesced.
+func (p *proxy) handlePassthrough(w http.ResponseWriter, r *http.Request, endpoint string, body []byte) {
+	if r.URL.RawQuery != "" {
+		endpoint += "?" + r.URL.RawQuery
+	}
+
+	var response *upstreamResponse
+	var err error
+	if r.Method == http.MethodGet {
+		response, err = p.coalescedSend(r.Method, endpoint, body)
+	} else {
+		response, err = p.send(r.Method, endpoint, body)
+	}
+	if err != nil {
+		p.writeError(w, err)
+		return
+	}
+	p.writeUpstream(w, response)
+}
+
+func (p *proxy) coalescedSend(method, endpoint string, body []byte) (*upstreamResponse, error) {
+	response, shared, err := p.flights.do(method+" "+endpoint+"\x00"+string(body), func() (*upstreamResponse, error) {
+		return p.send(method, endpoint, body)
+	})
+	if shared {
+		p.log(slog.LevelDebug, "coalesced request", slog.String("method", method), slog.String("endpoint", endpoint))
+	}
+	return response, err
+}
+
+// upstream returns a copy of the upstream client with a valid token, refreshing the token if it expired.
+func (p *proxy) upstream() (*schedulesdirect.Client, error) {
+	p.clientMu.Lock()
+	defer p.clientMu.Unlock()
+
+	if time.Now().After(p.client.TokenExpiresAt) {
+		p.log(slog.LevelInfo, "refreshing upstream token")
+		token, err := p.client.GetToken(p.username, p.password)
+		if err != nil {
+			return nil, err
+		}
+		p.client.Token = token
+	}
+
+	client := *p.client
+	return &client, nil
+}
+
+// send sends a request to Schedules Direct with the upstream token.
+func (p *proxy) send(method, endpoint string, body []byte) (*upstreamResponse, error) {
+	client, clientErr := p.upstream()
+	if clientErr != nil {
+		return nil, clientErr
+	}
+
+	var reader io.Reader
+	if len(body) > 0 {
+		reader = bytes.NewReader(body)
+	}
+
+	req, httpErr := http.NewRequest(method, fmt.Sprint(client.BaseURL, schedulesdirect.APIVersion, endpoint), reader)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+	req.Header.Set("Accept-Encoding", "deflate,gzip")
+
+	response, data, err := client.SendRequest(req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := response.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	return &upstreamResponse{status: response.StatusCode, contentType: contentType, body: data}, nil
+}
+
+func (p *proxy) baseResponse(code schedulesdirect.ErrorCode, message string) *schedulesdirect.BaseResponse {
+	return &schedulesdirect.BaseResponse{
+		Response: code.InternalCode(),
+		Code:     code,
+		ServerID: serverID,
+		Message:  message,
+		DateTime: time.Now().UTC(),
+	}
+}
+
+// writeError writes Schedules Direct errors as they were received and any other error as SERVICE_OFFLINE.
+func (p *proxy) writeError(w http.ResponseWriter, err error) {
+	var baseResp *schedulesdirect.BaseResponse
+	if !errors.As(err, &baseResp) {
+		p.log(slog.LevelError, "upstream request failed", slog.Any("error", err))
+		p.writeJSON(w, http.StatusBadGateway, p.baseResponse(schedulesdirect.ErrServiceOffline, err.Error()))
+		return
+	}
+
+	status := http.StatusBadRequest
+	switch baseResp.Code {
+	case schedulesdirect.ErrTokenMissing, schedulesdirect.ErrInvalidUser, schedulesdirect.ErrInvalidHash,
+		schedulesdirect.ErrTokenExpired, schedulesdirect.ErrAccountExpired, schedulesdirect.ErrAccountLockout,
+		schedulesdirect.ErrAccountDisabled:
+		status = http.StatusForbidden
+	case schedulesdirect.ErrImageNotFound:
+		status = http.StatusNotFound
+	}
+	p.writeJSON(w, status, baseResp)
+}
+
+func (p *proxy) writeUpstream(w http.ResponseWriter, response *upstreamResponse) {
+	w.Header().Set("Content-Type", response.contentType)
+	w.WriteHeader(response.status)
+	if _, err := w.Write(response.body); err != nil {
+		p.log(slog.LevelDebug, "error writing response", slog.Any("error", err))
+	}
+}
+
+func (p *proxy) writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	p.writeUpstream(w, &upstreamResponse{status: status, contentType: "application/json", body: data})
+}
+
+func (p *proxy) log(level slog.Level, msg string, args ...any) {
+	if p.logger != nil {
+		p.logger.Log(context.Background(), level, msg, args...)
+	}
+}
+
+// uniqueStrings returns the strings without duplicates, keeping their order.
+func uniqueStrings(sl []string) []string {
+	seen := make(map[string]bool)
+	unique := make([]string, 0, len(sl))
+	for _, s := range sl {
+		if !seen[s] {
+			seen[s] = true
+			unique = append(unique, s)
+		}
+	}
+	return unique
+}
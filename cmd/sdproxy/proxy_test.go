@@ -0,0 +1,294 @@
+package main
+
+import (
+	"crypto/sha1" // #nosec
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	schedulesdirect "github.com/tellytv/go.schedulesdirect"
+)
+
+const upstreamToken = "d97c908ed44c25fdca302612c70584c8d5acd47a"
+
+// upstreamCounter counts the requests the fake Schedules Direct server received per endpoint.
+type upstreamCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+	ids    [][]string
+}
+
+func (c *upstreamCounter) add(endpoint string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[endpoint]++
+}
+
+func (c *upstreamCounter) count(endpoint string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[endpoint]
+}
+
+// setupProxy starts a fake Schedules Direct server, a proxy in front of it and
+// returns a library client talking to the proxy.
+func setupProxy(t *testing.T) (*http.ServeMux, *upstreamCounter, *proxy, *schedulesdirect.Client) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	upstream := httptest.NewServer(mux)
+	t.Cleanup(upstream.Close)
+
+	counter := &upstreamCounter{counts: make(map[string]int)}
+
+	mux.HandleFunc("/20141201/programs", func(w http.ResponseWriter, r *http.Request) {
+		counter.add("/programs")
+		assert.Equal(t, upstreamToken, r.Header.Get("token"))
+
+		ids := make([]string, 0)
+		if err := json.NewDecoder(r.Body).Decode(&ids); err != nil {
+			t.Fatal(err)
+		}
+		counter.mu.Lock()
+		counter.ids = append(counter.ids, ids)
+		counter.mu.Unlock()
+
+		programs := make([]map[string]interface{}, 0)
+		for _, id := range ids {
+			programs = append(programs, map[string]interface{}{
+				"programID": id,
+				"md5":       "md5-" + id,
+				"titles":    []map[string]string{{"title120": "Title " + id}},
+			})
+		}
+		_ = json.NewEncoder(w).Encode(programs)
+	})
+
+	mux.HandleFunc("/20141201/schedules/md5", func(w http.ResponseWriter, r *http.Request) {
+		counter.add("/schedules/md5")
+		fmt.Fprint(w, `{"I10021":{"2015-03-03":{"code":0,"message":"OK","lastModified":"2015-03-02T15:54:20Z","md5":"sched-md5"}}}`)
+	})
+
+	mux.HandleFunc("/20141201/schedules", func(w http.ResponseWriter, r *http.Request) {
+		counter.add("/schedules")
+		fmt.Fprint(w, `[{"stationID":"I10021","programs":[{"programID":"EP000000060003","airDateTime":"2015-03-03T00:00:00Z","duration":1800,"md5":"new-md5"}],"metadata":{"modified":"2015-03-02T15:54:20Z","md5":"sched-md5","startDate":"2015-03-03"}}]`)
+	})
+
+	mux.HandleFunc("/20141201/image/", func(w http.ResponseWriter, r *http.Request) {
+		counter.add("/image")
+		_, _ = w.Write([]byte("\x89PNG\r\n\x1a\n fake image"))
+	})
+
+	mux.HandleFunc("/20141201/status", func(w http.ResponseWriter, r *http.Request) {
+		counter.add("/status")
+		fmt.Fprint(w, `{"account":{"expires":"2020-09-07T15:26:24Z","maxLineups":4},"code":0}`)
+	})
+
+	upstreamClient := &schedulesdirect.Client{
+		BaseURL:        fmt.Sprint(upstream.URL, "/"),
+		HTTP:           http.DefaultClient,
+		Token:          upstreamToken,
+		TokenExpiresAt: time.Now().Add(24 * time.Hour),
+	}
+
+	p, err := newProxy(upstreamClient, proxyConfig{
+		Username:    "user",
+		Password:    "secret",
+		ImageDir:    t.TempDir(),
+		TTL:         time.Hour,
+		ScheduleTTL: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(p)
+	t.Cleanup(server.Close)
+
+	client := &schedulesdirect.Client{
+		BaseURL:        fmt.Sprint(server.URL, "/"),
+		HTTP:           http.DefaultClient,
+		Token:          p.token,
+		TokenExpiresAt: time.Now().Add(24 * time.Hour),
+	}
+
+	return mux, counter, p, client
+}
+
+func TestProxyToken(t *testing.T) {
+	_, _, p, client := setupProxy(t)
+
+	_, err := client.GetToken("user", "wrong")
+	if baseResp, ok := err.(*schedulesdirect.BaseResponse); assert.True(t, ok, err) {
+		assert.Equal(t, schedulesdirect.ErrInvalidUser, baseResp.Code)
+	}
+
+	token, err := client.GetToken("user", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, p.token, token)
+	assert.NotEqual(t, upstreamToken, token)
+
+	hash := sha1.Sum([]byte("secret")) // #nosec
+	assert.Equal(t, hex.EncodeToString(hash[:]), p.passwordHash)
+
+	for token, code := range map[string]schedulesdirect.ErrorCode{"": schedulesdirect.ErrTokenMissing, upstreamToken: schedulesdirect.ErrInvalidUser} {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/20141201/status", nil)
+		req.Header.Set("token", token)
+		p.ServeHTTP(recorder, req)
+
+		baseResp := &schedulesdirect.BaseResponse{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), baseResp); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		assert.Equal(t, code, baseResp.Code)
+	}
+}
+
+func TestProxyPrograms(t *testing.T) {
+	_, counter, p, client := setupProxy(t)
+
+	programs, err := client.GetProgramInfo([]string{"EP000000060003", "SH000000010000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, programs, 2) {
+		assert.Equal(t, "EP000000060003", programs[0].ProgramID)
+		assert.Equal(t, "Title SH000000010000", programs[1].Titles[0].Title120)
+	}
+
+	// Only the new program is fetched upstream, the results keep the requested order.
+	programs, err = client.GetProgramInfo([]string{"SH000000020000", "EP000000060003"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, programs, 2) {
+		assert.Equal(t, "SH000000020000", programs[0].ProgramID)
+		assert.Equal(t, "EP000000060003", programs[1].ProgramID)
+	}
+	assert.Equal(t, 2, counter.count("/programs"))
+	assert.Equal(t, []string{"SH000000020000"}, counter.ids[1])
+	assert.Equal(t, 3, p.programs.len())
+
+	// A schedule reporting a new MD5 makes the cached program stale.
+	p.programMD5s.set("EP000000060003", "new-md5")
+	_, err = client.GetProgramInfo([]string{"EP000000060003", "SH000000010000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3, counter.count("/programs"))
+	assert.Equal(t, []string{"EP000000060003"}, counter.ids[2])
+}
+
+func TestProxySchedules(t *testing.T) {
+	_, counter, p, client := setupProxy(t)
+
+	requests := []schedulesdirect.StationScheduleRequest{{StationID: "I10021", Dates: []string{"2015-03-03"}}}
+	for i := 0; i < 2; i++ {
+		schedules, err := client.GetSchedules(requests)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if assert.Len(t, schedules, 1) {
+			assert.Equal(t, "I10021", schedules[0].StationID)
+			assert.Equal(t, "sched-md5", schedules[0].Metadata.MD5)
+		}
+	}
+
+	// The second request is served from cache without checking the MD5 upstream.
+	assert.Equal(t, 1, counter.count("/schedules/md5"))
+	assert.Equal(t, 1, counter.count("/schedules"))
+	assert.Equal(t, "new-md5", p.programMD5s.get("EP000000060003"))
+
+	// Once the schedule is older than ScheduleTTL its MD5 is checked, but it is not downloaded again.
+	p.schedules.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	for i := 0; i < 2; i++ {
+		schedules, err := client.GetSchedules(requests)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Len(t, schedules, 1)
+	}
+	assert.Equal(t, 2, counter.count("/schedules/md5"))
+	assert.Equal(t, 1, counter.count("/schedules"))
+}
+
+func TestProxyImages(t *testing.T) {
+	_, counter, p, client := setupProxy(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := client.GetImage("assets/p12345_b_h6_aa.jpg")
+			if assert.NoError(t, err) {
+				assert.Contains(t, string(data), "fake image")
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, ok := p.images.get("assets/p12345_b_h6_aa.jpg")
+	assert.True(t, ok)
+	assert.Contains(t, string(data), "fake image")
+
+	// Concurrent requests may overlap the first download, but later requests never reach upstream.
+	downloads := counter.count("/image")
+	_, err := client.GetImage("assets/p12345_b_h6_aa.jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, downloads, counter.count("/image"))
+
+	for _, uri := range []string{"https://s3.amazonaws.com.evil/a.jpg", "https://s3.amazonaws.com@evil/a.jpg", "http://s3.amazonaws.com/a.jpg", "//evil/a.jpg", "../lineups", "assets/../../lineups"} {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/20141201/image/x", nil)
+		request.URL.Path = "/20141201/image/" + uri
+		request.Header.Set("token", p.token)
+		p.ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusNotFound, recorder.Code, uri)
+	}
+	assert.Equal(t, downloads, counter.count("/image"))
+
+	assert.True(t, validImageURI("https://s3.amazonaws.com/schedulesdirect/assets/a.jpg"))
+}
+
+func TestProxyPassthrough(t *testing.T) {
+	mux, counter, p, client := setupProxy(t)
+
+	status, err := client.GetStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 4, status.Account.MaxLineups)
+	assert.Equal(t, 1, counter.count("/status"))
+
+	mux.HandleFunc("/20141201/lineups", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"response":"NO_LINEUPS","code":4102,"serverID":"20141201.web.1","message":"No Lineups have been added to this account.","datetime":"2016-08-23T13:55:25Z"}`)
+	})
+
+	_, err = client.GetLineups()
+	if baseResp, ok := err.(*schedulesdirect.BaseResponse); assert.True(t, ok, err) {
+		assert.Equal(t, schedulesdirect.ErrNoLineups, baseResp.Code)
+	}
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/other", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/20141201/programs", strings.NewReader(`["`+strings.Repeat("A", maxRequestBody)+`"]`))
+	request.Header.Set("token", p.token)
+	p.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	assert.Equal(t, 0, counter.count("/programs"))
+}
@@ -0,0 +1,408 @@
+package schedulesdirect
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// RatingLevel is a ratings board independent ordinal of how restrictive a content rating is.
+type RatingLevel int
+
+const (
+	// UnknownRatingLevel means the rating is not known to the normalizer.
+	UnknownRatingLevel RatingLevel = iota
+	// GeneralRatingLevel is suitable for all ages, e.g. G, TV-G, U, FSK 0.
+	GeneralRatingLevel
+	// ChildrenRatingLevel is suitable for children, e.g. TV-Y7, BBFC PG, FSK 6.
+	ChildrenRatingLevel
+	// GuidanceRatingLevel needs parental guidance for pre-teens, e.g. PG, TV-PG, BBFC 12.
+	GuidanceRatingLevel
+	// TeenRatingLevel is suitable for teens, e.g. PG-13, TV-14, BBFC 15.
+	TeenRatingLevel
+	// MatureRatingLevel is suitable for older teens, e.g. R, TV-MA, FSK 16.
+	MatureRatingLevel
+	// AdultRatingLevel is for adults only, e.g. NC-17, BBFC 18, FSK 18.
+	AdultRatingLevel
+)
+
+// String returns the name of the level.
+func (l RatingLevel) String() string {
+	switch l {
+	case GeneralRatingLevel:
+		return "general"
+	case ChildrenRatingLevel:
+		return "children"
+	case GuidanceRatingLevel:
+		return "guidance"
+	case TeenRatingLevel:
+		return "teen"
+	case MatureRatingLevel:
+		return "mature"
+	case AdultRatingLevel:
+		return "adult"
+	}
+	return "unknown"
+}
+
+// MarshalText encodes the level as its name.
+func (l RatingLevel) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText decodes a level from its name.
+func (l *RatingLevel) UnmarshalText(text []byte) error {
+	for level := UnknownRatingLevel; level <= AdultRatingLevel; level++ {
+		if strings.EqualFold(level.String(), string(text)) {
+			*l = level
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown rating level %q", text)
+}
+
+// ratingLevelForAge returns the level of a rating with the given minimum age.
+func ratingLevelForAge(age int) RatingLevel {
+	switch {
+	case age <= 0:
+		return GeneralRatingLevel
+	case age < 10:
+		return ChildrenRatingLevel
+	case age < 13:
+		return GuidanceRatingLevel
+	case age < 16:
+		return TeenRatingLevel
+	case age < 18:
+		return MatureRatingLevel
+	}
+	return AdultRatingLevel
+}
+
+// A NormalizedRating is a ContentRating mapped onto an age threshold and a RatingLevel.
+type NormalizedRating struct {
+	ContentRating
+	// Board is the canonical name of the ratings board, the original body if the board is unknown.
+	Board string
+	// MinimumAge is the youngest age the rating is suitable for, only meaningful if Known.
+	MinimumAge int
+	Level      RatingLevel
+	// Known is true if the board and code are known to the normalizer.
+	Known bool
+}
+
+type ratingBoard struct {
+	name    string
+	aliases []string
+	ages    map[string]int
+}
+
+// contentRatingBoards returns the ratings boards known to the normalizer, with the minimum age of each code.
+// Codes are stored normalized by normalizeRatingCode.
+func contentRatingBoards() []ratingBoard {
+	return []ratingBoard{
+		{
+			name:    "USA Parental Rating",
+			aliases: []string{"TV Parental Guidelines"},
+			ages:    map[string]int{"TVY": 0, "TVG": 0, "TVY7": 7, "TVPG": 10, "TV14": 14, "TVMA": 17},
+		},
+		{
+			name:    "Motion Picture Association of America",
+			aliases: []string{"MPAA", "Motion Picture Association"},
+			ages:    map[string]int{"G": 0, "PG": 10, "PG13": 13, "R": 17, "NC17": 18},
+		},
+		{
+			name:    "British Board of Film Classification",
+			aliases: []string{"BBFC"},
+			ages:    map[string]int{"U": 0, "UC": 0, "PG": 8, "12": 12, "12A": 12, "15": 15, "18": 18, "R18": 18},
+		},
+		{
+			name:    "Freiwillige Selbstkontrolle der Filmwirtschaft",
+			aliases: []string{"FSK"},
+			ages: map[string]int{
+				"0": 0, "6": 6, "12": 12, "16": 16, "18": 18,
+				"FSK0": 0, "FSK6": 6, "FSK12": 12, "FSK16": 16, "FSK18": 18,
+			},
+		},
+		{
+			name:    "Canadian Parental Rating",
+			aliases: []string{"Canadian Home Video Rating System"},
+			ages:    map[string]int{"C": 0, "G": 0, "C8": 8, "PG": 10, "14+": 14, "14A": 14, "18+": 18, "18A": 18, "R": 18},
+		},
+		{
+			name:    "Australian Classification Board",
+			aliases: []string{"ACB"},
+			ages:    map[string]int{"G": 0, "PG": 8, "M": 15, "MA15+": 15, "R18+": 18, "X18+": 18},
+		},
+	}
+}
+
+func lookupRatingBoard(body string) (ratingBoard, bool) {
+	for _, board := range contentRatingBoards() {
+		if strings.EqualFold(board.name, body) || containsStringFold(board.aliases, body) {
+			return board, true
+		}
+	}
+	return ratingBoard{}, false
+}
+
+// NormalizeContentRating maps the rating onto an age threshold and a RatingLevel.
+func NormalizeContentRating(rating ContentRating) NormalizedRating {
+	normalized := NormalizedRating{ContentRating: rating, Board: rating.Body}
+
+	board, ok := lookupRatingBoard(rating.Body)
+	if !ok {
+		return normalized
+	}
+	normalized.Board = board.name
+
+	age, ok := board.ages[normalizeRatingCode(rating.Code)]
+	if !ok {
+		return normalized
+	}
+	normalized.MinimumAge = age
+	normalized.Level = ratingLevelForAge(age)
+	normalized.Known = true
+	return normalized
+}
+
+// AiringRatings returns the normalized ratings of the airing followed by those of its program.
+func AiringRatings(airing Airing) []NormalizedRating {
+	ratings := airingRatings(airing)
+	normalized := make([]NormalizedRating, 0, len(ratings))
+	for _, rating := range ratings {
+		normalized = append(normalized, NormalizeContentRating(rating))
+	}
+	return normalized
+}
+
+// airingRatings returns the ratings of the airing followed by the ratings of its program.
+func airingRatings(airing Airing) []ContentRating {
+	ratings := append([]ContentRating{}, airing.Program.Ratings...)
+	if airing.Info != nil {
+		ratings = append(ratings, airing.Info.ContentRating...)
+	}
+	return ratings
+}
+
+// ratingCodeLevel returns the level of code on any known board, preferring lower levels when the code is ambiguous.
+func ratingCodeLevel(code string) (RatingLevel, bool) {
+	level, found := UnknownRatingLevel, false
+	for _, board := range contentRatingBoards() {
+		if age, ok := board.ages[normalizeRatingCode(code)]; ok && (!found || ratingLevelForAge(age) < level) {
+			level, found = ratingLevelForAge(age), true
+		}
+	}
+	return level, found
+}
+
+// normalizeRatingCode upper cases code and drops separators so TV-14 equals TV14.
+func normalizeRatingCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' || r == '_' {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, code)
+}
+
+// A ParentalPolicy decides which airings are blocked in a country.
+type ParentalPolicy struct {
+	// Country is the ISO 3166-1 alpha-3 code the policy applies to, as used by ContentRating.Country.
+	// A policy without a country applies to every country without a policy of its own.
+	Country string `json:"country,omitempty" xml:"country,attr,omitempty"`
+	// MaxRatings are the most restrictive ratings allowed per board, e.g. TV-14 or PG-13.
+	MaxRatings []ContentRating `json:"maxRatings,omitempty" xml:"max-rating"`
+	// MaxLevel is the most restrictive level allowed for boards without a MaxRatings entry, UnknownRatingLevel to allow all.
+	MaxLevel RatingLevel `json:"maxLevel,omitempty" xml:"max-level,attr,omitempty"`
+	// BlockUnrated blocks airings without any known rating.
+	BlockUnrated bool `json:"blockUnrated,omitempty" xml:"block-unrated,attr,omitempty"`
+	// BlockedAdvisories blocks airings with a content advisory containing any of these, ignoring case.
+	BlockedAdvisories []string `json:"blockedAdvisories,omitempty" xml:"blocked-advisory"`
+}
+
+// A ParentalDecision is the outcome of applying a ParentalPolicy to an airing.
+type ParentalDecision struct {
+	Airing  Airing
+	Blocked bool
+	// Reason explains why the airing is blocked.
+	Reason string
+}
+
+// Decide applies the policy to the airing.
+func (p ParentalPolicy) Decide(airing Airing) ParentalDecision {
+	decision := ParentalDecision{Airing: airing}
+
+	ratings := p.countryRatings(AiringRatings(airing))
+
+	known := false
+	for _, rating := range ratings {
+		if !rating.Known {
+			continue
+		}
+		known = true
+
+		if maxRating, ok := p.maxRating(rating.Board); ok {
+			max := NormalizeContentRating(maxRating)
+			if max.Known && rating.MinimumAge > max.MinimumAge {
+				decision.Blocked = true
+				decision.Reason = fmt.Sprintf("rated %s by %s, above %s", rating.Code, rating.Board, maxRating.Code)
+				return decision
+			}
+			continue
+		}
+
+		if p.MaxLevel != UnknownRatingLevel && rating.Level > p.MaxLevel {
+			decision.Blocked = true
+			decision.Reason = fmt.Sprintf("rated %s by %s, above the %s level", rating.Code, rating.Board, p.MaxLevel)
+			return decision
+		}
+	}
+
+	if !known && p.BlockUnrated {
+		decision.Blocked = true
+		decision.Reason = "not rated"
+		return decision
+	}
+
+	if airing.Info != nil {
+		for _, advisory := range airing.Info.ContentAdvisory {
+			for _, blocked := range p.BlockedAdvisories {
+				if strings.Contains(strings.ToLower(advisory), strings.ToLower(blocked)) {
+					decision.Blocked = true
+					decision.Reason = fmt.Sprintf("content advisory %q", advisory)
+					return decision
+				}
+			}
+		}
+	}
+
+	return decision
+}
+
+// Filter returns the airings the policy allows, and the decisions for the blocked ones.
+func (p ParentalPolicy) Filter(airings []Airing) ([]Airing, []ParentalDecision) {
+	allowed := make([]Airing, 0)
+	blocked := make([]ParentalDecision, 0)
+	for _, airing := range airings {
+		if decision := p.Decide(airing); decision.Blocked {
+			blocked = append(blocked, decision)
+		} else {
+			allowed = append(allowed, airing)
+		}
+	}
+	return allowed, blocked
+}
+
+// countryRatings returns the ratings for the policy's country, or all ratings if there are none for it.
+func (p ParentalPolicy) countryRatings(ratings []NormalizedRating) []NormalizedRating {
+	if p.Country == "" {
+		return ratings
+	}
+	matching := make([]NormalizedRating, 0)
+	for _, rating := range ratings {
+		if strings.EqualFold(rating.Country, p.Country) {
+			matching = append(matching, rating)
+		}
+	}
+	if len(matching) == 0 {
+		return ratings
+	}
+	return matching
+}
+
+func (p ParentalPolicy) maxRating(board string) (ContentRating, bool) {
+	for _, maxRating := range p.MaxRatings {
+		if NormalizeContentRating(maxRating).Board == board {
+			return maxRating, true
+		}
+	}
+	return ContentRating{}, false
+}
+
+// ParentalPolicies are the parental control policies of several countries.
+type ParentalPolicies []ParentalPolicy
+
+// ForCountry returns the policy for the country, falling back to the policy without a country.
+func (p ParentalPolicies) ForCountry(country string) (ParentalPolicy, bool) {
+	fallback, found := ParentalPolicy{}, false
+	for _, policy := range p {
+		if strings.EqualFold(policy.Country, country) && policy.Country != "" {
+			return policy, true
+		}
+		if policy.Country == "" {
+			fallback, found = policy, true
+		}
+	}
+	return fallback, found
+}
+
+// An XMLTVRating is a rating element of an XMLTV programme.
+type XMLTVRating struct {
+	XMLName xml.Name `xml:"rating"`
+	System  string   `xml:"system,attr,omitempty"`
+	Value   string   `xml:"value"`
+}
+
+// XMLTVRatings returns the ratings of the airing as XMLTV rating elements, without duplicates.
+func XMLTVRatings(airing Airing) []XMLTVRating {
+	ratings := make([]XMLTVRating, 0)
+	seen := make(map[string]bool)
+	for _, rating := range AiringRatings(airing) {
+		key := rating.Board + "\x00" + rating.Code
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		ratings = append(ratings, XMLTVRating{System: rating.Board, Value: rating.Code})
+	}
+	return ratings
+}
+
+type parentalControlsXML struct {
+	XMLName  xml.Name             `xml:"parental-controls"`
+	Policies []ParentalPolicy     `xml:"policy"`
+	Blocked  []parentalBlockedXML `xml:"blocked"`
+}
+
+type parentalBlockedXML struct {
+	Channel   string `xml:"channel,attr"`
+	Start     string `xml:"start,attr"`
+	ProgramID string `xml:"program-id,attr"`
+	Country   string `xml:"country,attr,omitempty"`
+	Reason    string `xml:",chardata"`
+}
+
+// WriteParentalControlsXML writes the policies and the airings each of them blocks as an XML document
+// to accompany an XMLTV file. Channels are station IDs and start times use the XMLTV time format.
+func WriteParentalControlsXML(w io.Writer, policies ParentalPolicies, airings []Airing) error {
+	doc := parentalControlsXML{
+		Policies: policies,
+		Blocked:  make([]parentalBlockedXML, 0),
+	}
+
+	for _, policy := range policies {
+		_, blocked := policy.Filter(airings)
+		for _, decision := range blocked {
+			doc.Blocked = append(doc.Blocked, parentalBlockedXML{
+				Channel:   decision.Airing.StationID,
+				Start:     decision.Airing.Start().Format("20060102150405 -0700"),
+				ProgramID: decision.Airing.Program.ProgramID,
+				Country:   policy.Country,
+				Reason:    decision.Reason,
+			})
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
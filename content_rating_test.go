@@ -0,0 +1,138 @@
+package schedulesdirect
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeContentRating(t *testing.T) {
+	tv14 := NormalizeContentRating(ContentRating{Body: "USA Parental Rating", Code: "TV14", Country: "USA"})
+	assert.True(t, tv14.Known)
+	assert.Equal(t, 14, tv14.MinimumAge)
+	assert.Equal(t, TeenRatingLevel, tv14.Level)
+
+	pg13 := NormalizeContentRating(ContentRating{Body: "MPAA", Code: "PG-13"})
+	assert.Equal(t, "Motion Picture Association of America", pg13.Board)
+	assert.Equal(t, TeenRatingLevel, pg13.Level)
+
+	fsk := NormalizeContentRating(ContentRating{Body: "Freiwillige Selbstkontrolle der Filmwirtschaft", Code: "FSK 16"})
+	assert.Equal(t, 16, fsk.MinimumAge)
+	assert.Equal(t, MatureRatingLevel, fsk.Level)
+
+	assert.Equal(t, GeneralRatingLevel, NormalizeContentRating(ContentRating{Body: "BBFC", Code: "U"}).Level)
+	assert.Equal(t, AdultRatingLevel, NormalizeContentRating(ContentRating{Body: "BBFC", Code: "R18"}).Level)
+
+	unknown := NormalizeContentRating(ContentRating{Body: "Some Board", Code: "X"})
+	assert.False(t, unknown.Known)
+	assert.Equal(t, UnknownRatingLevel, unknown.Level)
+	assert.False(t, NormalizeContentRating(ContentRating{Body: "USA Parental Rating", Code: "NR"}).Known)
+}
+
+func testRatedAiring(programID string, advisories []string, ratings ...ContentRating) Airing {
+	return Airing{
+		StationID: "10001",
+		Program:   testProgram(programID, "2015-03-03T20:00:00Z", 1800),
+		Info:      &ProgramInfo{ProgramID: programID, ContentRating: ratings, ContentAdvisory: advisories},
+	}
+}
+
+func TestParentalPolicy(t *testing.T) {
+	policy := ParentalPolicy{
+		Country: "USA",
+		MaxRatings: []ContentRating{
+			{Body: "USA Parental Rating", Code: "TV-14"},
+			{Body: "Motion Picture Association of America", Code: "PG-13"},
+		},
+		MaxLevel:          TeenRatingLevel,
+		BlockedAdvisories: []string{"graphic violence"},
+	}
+
+	airings := []Airing{
+		testRatedAiring("EP000000000001", nil, ContentRating{Body: "USA Parental Rating", Code: "TV14", Country: "USA"}),
+		testRatedAiring("EP000000000002", nil, ContentRating{Body: "USA Parental Rating", Code: "TVMA", Country: "USA"}),
+		testRatedAiring("MV000000000003", nil,
+			ContentRating{Body: "Motion Picture Association of America", Code: "R", Country: "USA"},
+			ContentRating{Body: "British Board of Film Classification", Code: "12", Country: "GBR"},
+		),
+		testRatedAiring("MV000000000004", nil, ContentRating{Body: "Freiwillige Selbstkontrolle der Filmwirtschaft", Code: "FSK 18", Country: "DEU"}),
+		testRatedAiring("EP000000000005", []string{"Graphic Violence"}),
+		testRatedAiring("EP000000000006", nil),
+	}
+
+	allowed, blocked := policy.Filter(airings)
+
+	if assert.Len(t, allowed, 2) {
+		assert.Equal(t, "EP000000000001", allowed[0].Program.ProgramID)
+		assert.Equal(t, "EP000000000006", allowed[1].Program.ProgramID)
+	}
+	if assert.Len(t, blocked, 4) {
+		assert.Equal(t, "rated TVMA by USA Parental Rating, above TV-14", blocked[0].Reason)
+		assert.Equal(t, "rated R by Motion Picture Association of America, above PG-13", blocked[1].Reason)
+		assert.Equal(t, "rated FSK 18 by Freiwillige Selbstkontrolle der Filmwirtschaft, above the teen level", blocked[2].Reason)
+		assert.Equal(t, `content advisory "Graphic Violence"`, blocked[3].Reason)
+	}
+
+	british := ParentalPolicy{Country: "GBR", MaxLevel: GuidanceRatingLevel, BlockUnrated: true}
+	assert.False(t, british.Decide(airings[2]).Blocked)
+	assert.True(t, british.Decide(airings[5]).Blocked)
+}
+
+func TestParentalPolicies(t *testing.T) {
+	policies := ParentalPolicies{
+		{MaxLevel: MatureRatingLevel},
+		{Country: "USA", MaxRatings: []ContentRating{{Body: "USA Parental Rating", Code: "TVPG"}}},
+	}
+
+	policy, ok := policies.ForCountry("usa")
+	assert.True(t, ok)
+	assert.Equal(t, "USA", policy.Country)
+
+	policy, ok = policies.ForCountry("CAN")
+	assert.True(t, ok)
+	assert.Equal(t, MatureRatingLevel, policy.MaxLevel)
+
+	_, ok = ParentalPolicies{}.ForCountry("USA")
+	assert.False(t, ok)
+
+	var decoded ParentalPolicies
+	if err := json.Unmarshal([]byte(`[{"country":"USA","maxLevel":"teen","blockUnrated":true}]`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ParentalPolicies{{Country: "USA", MaxLevel: TeenRatingLevel, BlockUnrated: true}}, decoded)
+	assert.Error(t, json.Unmarshal([]byte(`[{"maxLevel":"toddler"}]`), &decoded))
+}
+
+func TestParentalControlsXML(t *testing.T) {
+	policies := ParentalPolicies{{
+		Country:    "USA",
+		MaxRatings: []ContentRating{{Body: "USA Parental Rating", Code: "TV14"}},
+		MaxLevel:   TeenRatingLevel,
+	}}
+	airings := []Airing{
+		testRatedAiring("EP000000000001", nil, ContentRating{Body: "USA Parental Rating", Code: "TV14"}),
+		testRatedAiring("EP000000000002", nil, ContentRating{Body: "USA Parental Rating", Code: "TVMA"}),
+	}
+
+	var buf bytes.Buffer
+	if err := WriteParentalControlsXML(&buf, policies, airings); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<parental-controls>
+  <policy country="USA" max-level="teen">
+    <max-rating body="USA Parental Rating" code="TV14"></max-rating>
+  </policy>
+  <blocked channel="10001" start="20150303200000 +0000" program-id="EP000000000002" country="USA">rated TVMA by USA Parental Rating, above TV14</blocked>
+</parental-controls>
+`
+	assert.Equal(t, expected, buf.String())
+
+	assert.Equal(t, []XMLTVRating{{System: "USA Parental Rating", Value: "TVMA"}}, XMLTVRatings(Airing{
+		Program: Program{Ratings: []ContentRating{{Body: "USA Parental Rating", Code: "TVMA"}}},
+		Info:    &ProgramInfo{ContentRating: []ContentRating{{Body: "USA Parental Rating", Code: "TVMA"}}},
+	}))
+}
@@ -0,0 +1,456 @@
+package schedulesdirect
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DVBDeliverySystem is the delivery system of a DVB or ATSC channel, named as in dvbv5 channel files.
+type DVBDeliverySystem string
+
+const (
+	// DVBSDeliverySystem is DVB-S satellite.
+	DVBSDeliverySystem DVBDeliverySystem = "DVBS"
+	// DVBS2DeliverySystem is DVB-S2 satellite.
+	DVBS2DeliverySystem DVBDeliverySystem = "DVBS2"
+	// DVBTDeliverySystem is DVB-T terrestrial.
+	DVBTDeliverySystem DVBDeliverySystem = "DVBT"
+	// DVBT2DeliverySystem is DVB-T2 terrestrial.
+	DVBT2DeliverySystem DVBDeliverySystem = "DVBT2"
+	// DVBCDeliverySystem is DVB-C cable.
+	DVBCDeliverySystem DVBDeliverySystem = "DVBC/ANNEX_A"
+	// ATSCDeliverySystem is ATSC terrestrial.
+	ATSCDeliverySystem DVBDeliverySystem = "ATSC"
+)
+
+// IsSatellite returns true for DVB-S and DVB-S2.
+func (d DVBDeliverySystem) IsSatellite() bool {
+	return d == DVBSDeliverySystem || d == DVBS2DeliverySystem
+}
+
+// IsTerrestrial returns true for DVB-T, DVB-T2 and ATSC.
+func (d DVBDeliverySystem) IsTerrestrial() bool {
+	return d == DVBTDeliverySystem || d == DVBT2DeliverySystem || d == ATSCDeliverySystem
+}
+
+// A DVBChannel is a ChannelMap entry of a DVB lineup with its tuning parameters parsed and validated.
+type DVBChannel struct {
+	StationID string
+	Number    string
+	Name      string
+	Provider  string
+
+	DeliverySystem DVBDeliverySystem
+	FrequencyHertz int64
+	// Polarization is H, V, L or R. It is only set for satellite channels.
+	Polarization string
+	// SymbolRate is in symbols per second.
+	SymbolRate int
+	// FEC is the inner forward error correction, e.g. "2/3", or AUTO if unknown.
+	FEC string
+	// Modulation is named as in dvbv5 channel files, e.g. "PSK/8" or "QAM/256".
+	Modulation string
+
+	NetworkID   int
+	TransportID int
+	ServiceID   int
+}
+
+// A DVBChannelError is a ChannelMap entry which cannot be tuned from its parameters.
+type DVBChannelError struct {
+	ChannelMap ChannelMap
+	// Missing are the tuning parameters which are missing.
+	Missing []string
+	// Invalid are the tuning parameters which could not be parsed.
+	Invalid []string
+}
+
+func (e DVBChannelError) Error() string {
+	problems := make([]string, 0, 2)
+	if len(e.Missing) > 0 {
+		problems = append(problems, "missing "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Invalid) > 0 {
+		problems = append(problems, "invalid "+strings.Join(e.Invalid, ", "))
+	}
+	return fmt.Sprintf("channel %s (station %s) cannot be tuned: %s", e.ChannelMap.Number(), e.ChannelMap.StationID, strings.Join(problems, "; "))
+}
+
+// ParseDVBChannel parses and validates the tuning parameters of a verbose ChannelMap entry.
+// station is used to name the channel and may be empty.
+func ParseDVBChannel(entry ChannelMap, station Station) (DVBChannel, error) {
+	channel := DVBChannel{
+		StationID:      entry.StationID,
+		Number:         entry.Number(),
+		Name:           dvbChannelName(entry, station),
+		Provider:       station.Affiliate,
+		FrequencyHertz: int64(entry.FrequencyHertz),
+		SymbolRate:     entry.SymbolRate,
+		NetworkID:      entry.NetworkID,
+		TransportID:    entry.TransportID,
+		ServiceID:      entry.ServiceID,
+	}
+	validation := DVBChannelError{ChannelMap: entry, Missing: make([]string, 0), Invalid: make([]string, 0)}
+
+	// Some lineups only name the delivery system in the modulation system.
+	deliverySystem, ok := parseDVBDeliverySystem(entry.DeliverySystem)
+	if !ok {
+		deliverySystem, ok = parseDVBDeliverySystem(entry.ModulationSystem)
+	}
+	switch {
+	case ok:
+		channel.DeliverySystem = deliverySystem
+	case entry.DeliverySystem == "":
+		validation.Missing = append(validation.Missing, "deliverySystem")
+	default:
+		validation.Invalid = append(validation.Invalid, "deliverySystem")
+	}
+
+	if channel.FrequencyHertz <= 0 {
+		validation.Missing = append(validation.Missing, "frequencyHz")
+	}
+	if channel.ServiceID <= 0 {
+		validation.Missing = append(validation.Missing, "serviceID")
+	}
+
+	// Symbol rates are sometimes given in kilosymbols per second.
+	if channel.SymbolRate > 0 && channel.SymbolRate < 1000000 {
+		channel.SymbolRate *= 1000
+	}
+
+	channel.FEC = "AUTO"
+	if entry.FED != "" {
+		if fec, fecOK := parseDVBFEC(entry.FED); fecOK {
+			channel.FEC = fec
+		} else {
+			validation.Invalid = append(validation.Invalid, "fec")
+		}
+	}
+
+	modulation, modulationOK := parseDVBModulation(entry.ModulationSystem)
+	_, modulationIsDeliverySystem := parseDVBDeliverySystem(entry.ModulationSystem)
+	if entry.ModulationSystem != "" && !modulationOK && !modulationIsDeliverySystem {
+		validation.Invalid = append(validation.Invalid, "modulationSystem")
+	}
+	channel.Modulation = modulation
+
+	switch {
+	case channel.DeliverySystem.IsSatellite():
+		if polarization, polarizationOK := parseDVBPolarization(entry.Polarization); polarizationOK {
+			channel.Polarization = polarization
+		} else if entry.Polarization == "" {
+			validation.Missing = append(validation.Missing, "polarization")
+		} else {
+			validation.Invalid = append(validation.Invalid, "polarization")
+		}
+		if channel.SymbolRate <= 0 {
+			validation.Missing = append(validation.Missing, "symbolrate")
+		}
+		if channel.Modulation == "" {
+			if channel.DeliverySystem == DVBS2DeliverySystem {
+				validation.Missing = append(validation.Missing, "modulationSystem")
+			}
+			channel.Modulation = "QPSK"
+		}
+	case channel.DeliverySystem == DVBCDeliverySystem:
+		if channel.SymbolRate <= 0 {
+			validation.Missing = append(validation.Missing, "symbolrate")
+		}
+		if channel.Modulation == "" {
+			validation.Missing = append(validation.Missing, "modulationSystem")
+		}
+	case channel.DeliverySystem == ATSCDeliverySystem:
+		if channel.Modulation == "" {
+			channel.Modulation = "VSB/8"
+		}
+	}
+
+	if len(validation.Missing) > 0 || len(validation.Invalid) > 0 {
+		return channel, validation
+	}
+	return channel, nil
+}
+
+// DVBChannels parses the tuning parameters of every entry of the verbose channel maps.
+// Entries which cannot be tuned are returned as errors describing what is missing.
+func DVBChannels(lineups ...*ChannelResponse) ([]DVBChannel, []DVBChannelError) {
+	channels := make([]DVBChannel, 0)
+	invalid := make([]DVBChannelError, 0)
+
+	for _, lineup := range lineups {
+		if lineup == nil {
+			continue
+		}
+		stations := make(map[string]Station, len(lineup.Stations))
+		for _, station := range lineup.Stations {
+			stations[station.StationID] = station
+		}
+
+		for _, entry := range lineup.Map {
+			channel, err := ParseDVBChannel(entry, stations[entry.StationID])
+			if err != nil {
+				if channelErr, ok := err.(DVBChannelError); ok {
+					invalid = append(invalid, channelErr)
+				}
+				continue
+			}
+			channels = append(channels, channel)
+		}
+	}
+
+	return channels, invalid
+}
+
+// WriteDVBv5Channels writes the channels as a dvbv5 channel file, as read by dvbv5-zap and dvbv5-scan.
+// Satellite frequencies are written in kHz, all other frequencies in Hz.
+func WriteDVBv5Channels(w io.Writer, channels []DVBChannel) error {
+	buf := bufio.NewWriter(w)
+
+	for idx, channel := range channels {
+		if idx > 0 {
+			if _, err := fmt.Fprintln(buf); err != nil {
+				return err
+			}
+		}
+
+		frequency := channel.FrequencyHertz
+		if channel.DeliverySystem.IsSatellite() {
+			frequency /= 1000
+		}
+
+		properties := [][2]string{
+			{"SERVICE_ID", fmt.Sprint(channel.ServiceID)},
+			{"VCHANNEL", channel.Number},
+			{"DELIVERY_SYSTEM", string(channel.DeliverySystem)},
+			{"FREQUENCY", fmt.Sprint(frequency)},
+			{"INVERSION", "AUTO"},
+		}
+		if channel.Polarization != "" {
+			properties = append(properties, [2]string{"POLARIZATION", dvbv5Polarizations()[channel.Polarization]})
+		}
+		if channel.SymbolRate > 0 {
+			properties = append(properties, [2]string{"SYMBOL_RATE", fmt.Sprint(channel.SymbolRate)})
+		}
+		if channel.DeliverySystem != ATSCDeliverySystem {
+			properties = append(properties, [2]string{"INNER_FEC", channel.FEC})
+		}
+		if channel.Modulation != "" {
+			properties = append(properties, [2]string{"MODULATION", channel.Modulation})
+		}
+
+		if _, err := fmt.Fprintf(buf, "[%s]\n", dvbv5Name(channel.Name)); err != nil {
+			return err
+		}
+		for _, property := range properties {
+			if property[1] == "" {
+				continue
+			}
+			if _, err := fmt.Fprintf(buf, "\t%s = %s\n", property[0], property[1]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return buf.Flush()
+}
+
+// WriteVDRChannels writes the channels in the channels.conf format of VDR 2.x.
+//
+// The orbital position of a satellite is not part of Schedules Direct data, so satelliteSource
+// must name the VDR source of satellite channels, e.g. "S19.2E". PIDs are written as 0 and
+// are filled in by VDR from the service's PMT once tuned.
+func WriteVDRChannels(w io.Writer, channels []DVBChannel, satelliteSource string) error {
+	buf := bufio.NewWriter(w)
+
+	for _, channel := range channels {
+		source, frequency, symbolRate := "", channel.FrequencyHertz/1000, channel.SymbolRate/1000
+		switch {
+		case channel.DeliverySystem.IsSatellite():
+			if satelliteSource == "" {
+				return fmt.Errorf("channel %s (station %s) is a satellite channel but no VDR satellite source was given", channel.Number, channel.StationID)
+			}
+			source, frequency = satelliteSource, channel.FrequencyHertz/1000000
+		case channel.DeliverySystem == DVBCDeliverySystem:
+			source = "C"
+		case channel.DeliverySystem == ATSCDeliverySystem:
+			source, symbolRate = "A", 0
+		default:
+			source, symbolRate = "T", 0
+		}
+
+		name := vdrName(channel.Name)
+		if channel.Provider != "" {
+			name += ";" + vdrName(channel.Provider)
+		}
+
+		if _, err := fmt.Fprintf(buf, "%s:%d:%s:%s:%d:0:0:0:0:%d:%d:%d:0\n",
+			name, frequency, vdrParameters(channel), source, symbolRate, channel.ServiceID, channel.NetworkID, channel.TransportID); err != nil {
+			return err
+		}
+	}
+
+	return buf.Flush()
+}
+
+// vdrParameters returns the parameter string of a VDR channel, e.g. "HC23M5O35S1".
+func vdrParameters(channel DVBChannel) string {
+	params := ""
+	if channel.Polarization != "" {
+		params += channel.Polarization
+	}
+	if channel.DeliverySystem != ATSCDeliverySystem {
+		params += "C" + vdrFECs()[channel.FEC]
+	}
+	if code, ok := vdrModulations()[channel.Modulation]; ok {
+		params += "M" + code
+	}
+	if channel.DeliverySystem.IsSatellite() {
+		params += "O35"
+	}
+	switch channel.DeliverySystem {
+	case DVBSDeliverySystem, DVBTDeliverySystem:
+		params += "S0"
+	case DVBS2DeliverySystem, DVBT2DeliverySystem:
+		params += "S1"
+	}
+	return params
+}
+
+// dvbChannelName returns the name of a channel, preferring the station's name over its call signs.
+func dvbChannelName(entry ChannelMap, station Station) string {
+	for _, name := range []string{station.Name, station.CallSign, entry.ProviderCallSign} {
+		if name != "" {
+			return name
+		}
+	}
+	if number := entry.Number(); number != "" {
+		return number
+	}
+	return entry.StationID
+}
+
+// dvbv5Name makes a name safe for a dvbv5 channel section header.
+func dvbv5Name(name string) string {
+	return strings.NewReplacer("[", "(", "]", ")", "\n", " ").Replace(name)
+}
+
+// vdrName escapes a name for channels.conf, where ':' separates fields and ';' separates the provider.
+func vdrName(name string) string {
+	return strings.NewReplacer(":", "|", ";", ",", "\n", " ").Replace(name)
+}
+
+// normalizeDVBParameter upper cases a tuning parameter and removes separators, so "DVB-S2" becomes "DVBS2".
+func normalizeDVBParameter(value string) string {
+	return strings.NewReplacer("-", "", "_", "", " ", "", "/", "").Replace(strings.ToUpper(strings.TrimSpace(value)))
+}
+
+func parseDVBDeliverySystem(value string) (DVBDeliverySystem, bool) {
+	switch normalizeDVBParameter(value) {
+	case "DVBS":
+		return DVBSDeliverySystem, true
+	case "DVBS2":
+		return DVBS2DeliverySystem, true
+	case "DVBT":
+		return DVBTDeliverySystem, true
+	case "DVBT2":
+		return DVBT2DeliverySystem, true
+	case "DVBC", "DVBCANNEXA":
+		return DVBCDeliverySystem, true
+	case "ATSC":
+		return ATSCDeliverySystem, true
+	}
+	return "", false
+}
+
+func parseDVBModulation(value string) (string, bool) {
+	modulations := map[string]string{
+		"QPSK":    "QPSK",
+		"8PSK":    "PSK/8",
+		"PSK8":    "PSK/8",
+		"16APSK":  "APSK/16",
+		"APSK16":  "APSK/16",
+		"32APSK":  "APSK/32",
+		"APSK32":  "APSK/32",
+		"8VSB":    "VSB/8",
+		"VSB8":    "VSB/8",
+		"16VSB":   "VSB/16",
+		"VSB16":   "VSB/16",
+		"QAMAUTO": "QAM/AUTO",
+	}
+	for _, size := range []string{"16", "32", "64", "128", "256"} {
+		modulations["QAM"+size] = "QAM/" + size
+		modulations[size+"QAM"] = "QAM/" + size
+	}
+
+	modulation, ok := modulations[normalizeDVBParameter(value)]
+	return modulation, ok
+}
+
+func parseDVBFEC(value string) (string, bool) {
+	normalized := strings.ToUpper(strings.TrimSpace(value))
+	switch normalized {
+	case "AUTO", "NONE":
+		return normalized, true
+	}
+	if _, ok := vdrFECs()[normalized]; ok {
+		return normalized, true
+	}
+	return "", false
+}
+
+func parseDVBPolarization(value string) (string, bool) {
+	switch strings.ToUpper(strings.TrimSpace(value)) {
+	case "H", "HORIZONTAL":
+		return "H", true
+	case "V", "VERTICAL":
+		return "V", true
+	case "L", "LEFT", "CIRCULAR LEFT":
+		return "L", true
+	case "R", "RIGHT", "CIRCULAR RIGHT":
+		return "R", true
+	}
+	return "", false
+}
+
+// dvbv5Polarizations maps polarizations to their dvbv5 names.
+func dvbv5Polarizations() map[string]string {
+	return map[string]string{"H": "HORIZONTAL", "V": "VERTICAL", "L": "LEFT", "R": "RIGHT"}
+}
+
+// vdrFECs maps code rates to their VDR parameter values.
+func vdrFECs() map[string]string {
+	return map[string]string{
+		"NONE": "0",
+		"1/2":  "12",
+		"2/3":  "23",
+		"3/4":  "34",
+		"3/5":  "35",
+		"4/5":  "45",
+		"5/6":  "56",
+		"6/7":  "67",
+		"7/8":  "78",
+		"8/9":  "89",
+		"9/10": "910",
+		"AUTO": "999",
+	}
+}
+
+// vdrModulations maps dvbv5 modulations to their VDR parameter values.
+func vdrModulations() map[string]string {
+	return map[string]string{
+		"QPSK":     "2",
+		"PSK/8":    "5",
+		"APSK/16":  "6",
+		"APSK/32":  "7",
+		"VSB/8":    "10",
+		"VSB/16":   "11",
+		"QAM/16":   "16",
+		"QAM/32":   "32",
+		"QAM/64":   "64",
+		"QAM/128":  "128",
+		"QAM/256":  "256",
+		"QAM/AUTO": "999",
+	}
+}
@@ -0,0 +1,126 @@
+package schedulesdirect
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testDVBLineup(t *testing.T) *ChannelResponse {
+	t.Helper()
+
+	lineup := &ChannelResponse{}
+	if err := json.Unmarshal([]byte(`{
+		"map": [
+			{"stationID": "50000", "channel": "101", "deliverySystem": "DVB-S2", "modulationSystem": "8PSK", "frequencyHz": 11494000000,
+				"polarization": "Horizontal", "symbolrate": 22000, "fec": "2/3", "networkID": 1, "transportID": 1011, "serviceID": 10301},
+			{"stationID": "50001", "logicalChannelNumber": "1", "deliverySystem": "DVB-T", "frequencyHz": 506000000,
+				"networkID": 9018, "transportID": 4164, "serviceID": 4287},
+			{"stationID": "50002", "channel": "201", "deliverySystem": "DVB-C", "modulationSystem": "QAM256", "frequencyHz": 330000000,
+				"symbolrate": 6900000, "serviceID": 28106},
+			{"stationID": "50003", "channel": "102", "deliverySystem": "DVB-S", "frequencyHz": 11720000000, "serviceID": 10302},
+			{"stationID": "50004", "channel": "103", "deliverySystem": "DVB-S2", "modulationSystem": "8PSK", "frequencyHz": 11720000000,
+				"polarization": "Sideways", "symbolrate": 27500000, "fec": "11/12", "serviceID": 10303}
+		],
+		"stations": [
+			{"stationID": "50000", "callsign": "BBC1", "name": "BBC One: HD", "affiliate": "BBC"},
+			{"stationID": "50001", "callsign": "BBC1"}
+		],
+		"metadata": {"lineup": "GBR-0001458-DEFAULT"}
+	}`), lineup); err != nil {
+		t.Fatal(err)
+	}
+	return lineup
+}
+
+func TestDVBChannels(t *testing.T) {
+	channels, invalid := DVBChannels(testDVBLineup(t), nil)
+
+	if assert.Len(t, channels, 3) {
+		assert.Equal(t, DVBChannel{
+			StationID:      "50000",
+			Number:         "101",
+			Name:           "BBC One: HD",
+			Provider:       "BBC",
+			DeliverySystem: DVBS2DeliverySystem,
+			FrequencyHertz: 11494000000,
+			Polarization:   "H",
+			SymbolRate:     22000000,
+			FEC:            "2/3",
+			Modulation:     "PSK/8",
+			NetworkID:      1,
+			TransportID:    1011,
+			ServiceID:      10301,
+		}, channels[0])
+		assert.Equal(t, DVBTDeliverySystem, channels[1].DeliverySystem)
+		assert.Equal(t, "AUTO", channels[1].FEC)
+		assert.Equal(t, "QAM/256", channels[2].Modulation)
+		assert.Equal(t, "201", channels[2].Name)
+	}
+
+	if assert.Len(t, invalid, 2) {
+		assert.Equal(t, []string{"polarization", "symbolrate"}, invalid[0].Missing)
+		assert.Equal(t, "channel 102 (station 50003) cannot be tuned: missing polarization, symbolrate", invalid[0].Error())
+		assert.Equal(t, []string{"fec", "polarization"}, invalid[1].Invalid)
+	}
+
+	_, err := ParseDVBChannel(ChannelMap{StationID: "1", DeliverySystem: "DVB-X"}, Station{})
+	if assert.Error(t, err) {
+		channelErr := err.(DVBChannelError)
+		assert.Equal(t, []string{"frequencyHz", "serviceID"}, channelErr.Missing)
+		assert.Equal(t, []string{"deliverySystem"}, channelErr.Invalid)
+	}
+
+	channel, err := ParseDVBChannel(ChannelMap{ModulationSystem: "DVB-S", FrequencyHertz: 12000000000, Polarization: "v", SymbolRate: 27500, ServiceID: 1}, Station{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, DVBSDeliverySystem, channel.DeliverySystem)
+		assert.Equal(t, "QPSK", channel.Modulation)
+	}
+}
+
+func TestWriteDVBv5Channels(t *testing.T) {
+	channels, _ := DVBChannels(testDVBLineup(t))
+
+	buf := &bytes.Buffer{}
+	if err := WriteDVBv5Channels(buf, channels[:2]); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, `[BBC One: HD]
+	SERVICE_ID = 10301
+	VCHANNEL = 101
+	DELIVERY_SYSTEM = DVBS2
+	FREQUENCY = 11494000
+	INVERSION = AUTO
+	POLARIZATION = HORIZONTAL
+	SYMBOL_RATE = 22000000
+	INNER_FEC = 2/3
+	MODULATION = PSK/8
+
+[BBC1]
+	SERVICE_ID = 4287
+	VCHANNEL = 1
+	DELIVERY_SYSTEM = DVBT
+	FREQUENCY = 506000000
+	INVERSION = AUTO
+	INNER_FEC = AUTO
+`, buf.String())
+}
+
+func TestWriteVDRChannels(t *testing.T) {
+	channels, _ := DVBChannels(testDVBLineup(t))
+
+	buf := &bytes.Buffer{}
+	if err := WriteVDRChannels(buf, channels, "S28.2E"); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, `BBC One| HD;BBC:11494:HC23M5O35S1:S28.2E:22000:0:0:0:0:10301:1:1011:0
+BBC1:506000:C999S0:T:0:0:0:0:0:4287:9018:4164:0
+201:330000:C999M256:C:6900:0:0:0:0:28106:0:0:0
+`, buf.String())
+
+	assert.Error(t, WriteVDRChannels(&bytes.Buffer{}, channels, ""))
+}
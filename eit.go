@@ -0,0 +1,630 @@
+package schedulesdirect
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultGPSUTCOffset is the number of leap seconds GPS time is ahead of UTC, used for ATSC start times.
+const DefaultGPSUTCOffset = 18
+
+const (
+	// maxSectionLength is the largest section_length of an EIT or ETT section, 4096 bytes including the header.
+	maxSectionLength = 4093
+	// eitSegment is the time span of a DVB schedule segment and of an ATSC EIT-k table.
+	eitSegment = 3 * time.Hour
+	// maxExtendedEventDescriptors limits how much of a description is carried by a DVB event,
+	// keeping each event well below the size of a section.
+	maxExtendedEventDescriptors = 8
+)
+
+// gpsEpoch is the start of GPS time, 1980-01-06T00:00:00Z, as a Unix timestamp.
+const gpsEpoch = 315964800
+
+// An EITService is a station's service in an MPEG transport stream.
+type EITService struct {
+	StationID   string
+	ServiceID   uint16
+	TransportID uint16
+	NetworkID   uint16
+	// SourceID is the ATSC source_id of the virtual channel. ServiceID is used if zero.
+	SourceID uint16
+}
+
+// EITServices returns the services of the verbose channel maps, skipping entries without a service ID.
+// A station mapped more than once is only returned once.
+func EITServices(lineups ...*ChannelResponse) []EITService {
+	services := make([]EITService, 0)
+	seen := make(map[string]bool)
+	for _, lineup := range lineups {
+		if lineup == nil {
+			continue
+		}
+		for _, entry := range lineup.Map {
+			if entry.ServiceID <= 0 || entry.ServiceID > 0xFFFF || seen[entry.StationID] {
+				continue
+			}
+			seen[entry.StationID] = true
+			services = append(services, EITService{
+				StationID:   entry.StationID,
+				ServiceID:   uint16(entry.ServiceID),
+				TransportID: uint16(entry.TransportID),
+				NetworkID:   uint16(entry.NetworkID),
+			})
+		}
+	}
+	return services
+}
+
+// An EITGenerator generates DVB EIT and ATSC PSIP EIT/ETT sections from schedules.
+// Sections are returned as raw bytes, starting with the table_id and ending with the CRC_32,
+// ready to be packetized into the transport stream.
+type EITGenerator struct {
+	// Programs are used for titles, descriptions, genres and ratings.
+	// Airings of programs which are missing are still listed, without a title.
+	Programs map[string]*ProgramInfo
+	// Language is the ISO 639-2 code of the texts, "eng" if empty.
+	Language string
+	// DescriptionLanguages are passed to ProgramInfo.Description to choose between descriptions.
+	DescriptionLanguages []string
+	// Version is the 5 bit version_number of every table. Increment it whenever the schedules change.
+	Version uint8
+	// OtherTransportStream generates DVB tables for services in other transport streams (table IDs 0x4F and 0x60 to 0x6F).
+	OtherTransportStream bool
+	// ScheduleDays is the number of days of DVB schedule sections, starting at midnight UTC, 8 if zero.
+	ScheduleDays int
+	// GPSUTCOffset is the number of leap seconds between GPS time and UTC, DefaultGPSUTCOffset if zero.
+	GPSUTCOffset int
+	// Genres maps genres and keywords to content and genre descriptors, NewGenreMapper(nil) if nil.
+	Genres *GenreMapper
+}
+
+// PresentFollowing returns the two sections of the DVB EIT present/following table of the service at the given time.
+// Section 0 holds the event running at now and section 1 the event following it. Either may be empty.
+func (g EITGenerator) PresentFollowing(service EITService, schedules []Schedule, now time.Time) ([][]byte, error) {
+	var present, following []byte
+	after := now
+	genres := g.genreMapper()
+
+	airings := g.airings(service, schedules)
+	for _, airing := range airings {
+		if !airing.Start().After(now) && airing.End().After(now) {
+			present = g.dvbEvent(airing, 4, genres)
+			after = airing.End().Add(-time.Nanosecond)
+			break
+		}
+	}
+	for _, airing := range airings {
+		if airing.Start().After(after) {
+			following = g.dvbEvent(airing, 1, genres)
+			break
+		}
+	}
+
+	tableID := byte(0x4E)
+	if g.OtherTransportStream {
+		tableID = 0x4F
+	}
+
+	sections := make([][]byte, 0, 2)
+	for number, event := range [][]byte{present, following} {
+		section, err := g.dvbEITSection(tableID, service, byte(number), 1, 1, tableID, event)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, section)
+	}
+	return sections, nil
+}
+
+// Schedule returns the sections of the DVB EIT schedule tables of the service, covering ScheduleDays from midnight UTC of now.
+//
+// Events are split into 3 hour segments of up to 8 sections each. Segments without events are signalled
+// with an empty section, as required by EN 300 468.
+func (g EITGenerator) Schedule(service EITService, schedules []Schedule, now time.Time) ([][]byte, error) {
+	days := g.ScheduleDays
+	if days == 0 {
+		days = 8
+	}
+	if days < 0 || days > 64 {
+		return nil, fmt.Errorf("cannot generate %d days of EIT schedule, the maximum is 64", days)
+	}
+
+	origin := now.UTC().Truncate(24 * time.Hour)
+	end := origin.AddDate(0, 0, days)
+
+	segments := make(map[int][][]byte)
+	lastSegment := 0
+	genres := g.genreMapper()
+	for _, airing := range g.airings(service, schedules) {
+		if !airing.End().After(origin) || !airing.Start().Before(end) {
+			continue
+		}
+		segment := 0
+		if airing.Start().After(origin) {
+			segment = int(airing.Start().Sub(origin) / eitSegment)
+		}
+		segments[segment] = append(segments[segment], g.dvbEvent(airing, 0, genres))
+		if segment > lastSegment {
+			lastSegment = segment
+		}
+	}
+
+	baseTableID := 0x50
+	if g.OtherTransportStream {
+		baseTableID = 0x60
+	}
+	lastTableID := byte(baseTableID + lastSegment/32)
+
+	sections := make([][]byte, 0)
+	for table := 0; table <= lastSegment/32; table++ {
+		firstSegment := table * 32
+		lastTableSegment := firstSegment + 31
+		if lastTableSegment > lastSegment {
+			lastTableSegment = lastSegment
+		}
+
+		packed := make([][][]byte, 0, lastTableSegment-firstSegment+1)
+		for segment := firstSegment; segment <= lastTableSegment; segment++ {
+			segmentSections, err := packEvents(segments[segment], maxSectionLength-15, 8, 0)
+			if err != nil {
+				return nil, fmt.Errorf("error packing EIT schedule segment %d of service %d: %s", segment, service.ServiceID, err)
+			}
+			packed = append(packed, segmentSections)
+		}
+
+		lastSectionNumber := byte((len(packed)-1)*8 + len(packed[len(packed)-1]) - 1)
+		for idx, segmentSections := range packed {
+			segmentLastSectionNumber := byte(idx*8 + len(segmentSections) - 1)
+			for number, events := range segmentSections {
+				section, err := g.dvbEITSection(byte(baseTableID+table), service, byte(idx*8+number), lastSectionNumber, segmentLastSectionNumber, lastTableID, events)
+				if err != nil {
+					return nil, err
+				}
+				sections = append(sections, section)
+			}
+		}
+	}
+
+	return sections, nil
+}
+
+// ATSCEIT returns the sections of the ATSC EIT-k table of the service and the ETT sections carrying the
+// descriptions of its events. EIT-k covers the k-th 3 hour slot from the slot containing now, slots starting
+// at 00:00, 03:00, ... UTC. Events overlapping the slot are included.
+func (g EITGenerator) ATSCEIT(service EITService, schedules []Schedule, now time.Time, k int) ([][]byte, [][]byte, error) {
+	if k < 0 || k > 127 {
+		return nil, nil, fmt.Errorf("invalid EIT-%d, k must be between 0 and 127", k)
+	}
+
+	start := now.UTC().Truncate(eitSegment).Add(time.Duration(k) * eitSegment)
+	end := start.Add(eitSegment)
+
+	sourceID := service.SourceID
+	if sourceID == 0 {
+		sourceID = service.ServiceID
+	}
+
+	events := make([][]byte, 0)
+	etts := make([][]byte, 0)
+	genres := g.genreMapper()
+	for _, airing := range g.airings(service, schedules) {
+		if !airing.Start().Before(end) || !airing.End().After(start) {
+			continue
+		}
+
+		eventID := uint16(airing.Start().Unix()/60) & 0x3FFF
+		description := ""
+		if airing.Info != nil {
+			description = airing.Info.Description(g.DescriptionLanguages...)
+		}
+
+		events = append(events, g.atscEvent(airing, eventID, description != "", genres))
+		if description != "" {
+			ett, err := g.atscETTSection(sourceID, eventID, description)
+			if err != nil {
+				return nil, nil, err
+			}
+			etts = append(etts, ett)
+		}
+	}
+
+	packed, err := packEvents(events, maxSectionLength-11, 256, 255)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error packing EIT-%d of source %d: %s", k, sourceID, err)
+	}
+
+	sections := make([][]byte, 0, len(packed))
+	for number, sectionEvents := range packed {
+		count := 0
+		for offset := 0; offset < len(sectionEvents); count++ {
+			offset += atscEventLength(sectionEvents[offset:])
+		}
+
+		body := make([]byte, 0, 7+len(sectionEvents))
+		body = binary.BigEndian.AppendUint16(body, sourceID)
+		body = append(body, 0xC0|(g.Version&0x1F)<<1|0x01, byte(number), byte(len(packed)-1), 0x00, byte(count))
+		body = append(body, sectionEvents...)
+
+		section, sectionErr := psipSection(0xCB, body)
+		if sectionErr != nil {
+			return nil, nil, sectionErr
+		}
+		sections = append(sections, section)
+	}
+
+	return sections, etts, nil
+}
+
+func (g EITGenerator) airings(service EITService, schedules []Schedule) []Airing {
+	airings := make([]Airing, 0)
+	for _, airing := range AiringsFromSchedules(schedules, g.Programs) {
+		if airing.StationID == service.StationID {
+			airings = append(airings, airing)
+		}
+	}
+	return airings
+}
+
+func (g EITGenerator) language() string {
+	if len(g.Language) == 3 {
+		return strings.ToLower(g.Language)
+	}
+	return "eng"
+}
+
+func (g EITGenerator) genreMapper() *GenreMapper {
+	if g.Genres != nil {
+		return g.Genres
+	}
+	return NewGenreMapper(nil)
+}
+
+func (g EITGenerator) gpsUTCOffset() int {
+	if g.GPSUTCOffset == 0 {
+		return DefaultGPSUTCOffset
+	}
+	return g.GPSUTCOffset
+}
+
+// dvbEITSection wraps the events in an EIT section. events may be empty.
+func (g EITGenerator) dvbEITSection(tableID byte, service EITService, sectionNumber, lastSectionNumber, segmentLastSectionNumber, lastTableID byte, events []byte) ([]byte, error) {
+	body := make([]byte, 0, 11+len(events))
+	body = binary.BigEndian.AppendUint16(body, service.ServiceID)
+	body = append(body, 0xC0|(g.Version&0x1F)<<1|0x01, sectionNumber, lastSectionNumber)
+	body = binary.BigEndian.AppendUint16(body, service.TransportID)
+	body = binary.BigEndian.AppendUint16(body, service.NetworkID)
+	body = append(body, segmentLastSectionNumber, lastTableID)
+	body = append(body, events...)
+
+	// DVB sets the reserved_future_use bit after the section_syntax_indicator.
+	return psipSection(tableID, body)
+}
+
+// dvbEvent encodes an event of a DVB EIT with its short event, extended event, content and parental rating descriptors.
+func (g EITGenerator) dvbEvent(airing Airing, runningStatus byte, genres *GenreMapper) []byte {
+	title, description := "", ""
+	if airing.Info != nil {
+		title = airing.Info.Title()
+		description = airing.Info.Description(g.DescriptionLanguages...)
+		if airing.Info.EpisodeTitle150 != "" && description == "" {
+			description = airing.Info.EpisodeTitle150
+		}
+	}
+
+	descriptors := make([]byte, 0, 512)
+
+	// Short event descriptor, truncating the description if it does not fit. The full description
+	// follows in extended event descriptors.
+	name := dvbString(title, 125)
+	text := dvbString(description, 250-len(name))
+	descriptors = append(descriptors, 0x4D, byte(5+len(name)+len(text)))
+	descriptors = append(descriptors, g.language()...)
+	descriptors = append(descriptors, byte(len(name)))
+	descriptors = append(descriptors, name...)
+	descriptors = append(descriptors, byte(len(text)))
+	descriptors = append(descriptors, text...)
+
+	if len(text) < len(dvbString(description, 4096)) {
+		chunks := dvbStringChunks(description, 249, maxExtendedEventDescriptors)
+		for idx, chunk := range chunks {
+			descriptors = append(descriptors, 0x4E, byte(6+len(chunk)), byte(idx<<4|(len(chunks)-1)))
+			descriptors = append(descriptors, g.language()...)
+			descriptors = append(descriptors, 0x00, byte(len(chunk)))
+			descriptors = append(descriptors, chunk...)
+		}
+	}
+
+	// At most 4 content nibbles are signalled, the genres of the program taking precedence over its keywords.
+	if content := genres.Program(airing.Info).DVBContent(); len(content) > 0 {
+		if len(content) > 4 {
+			content = content[:4]
+		}
+		descriptors = append(descriptors, 0x54, byte(2*len(content)))
+		for _, nibbles := range content {
+			descriptors = append(descriptors, nibbles, 0x00)
+		}
+	}
+
+	if ratings := dvbParentalRatings(airing); len(ratings) > 0 {
+		descriptors = append(descriptors, 0x55, byte(4*len(ratings)))
+		for _, rating := range ratings {
+			descriptors = append(descriptors, rating...)
+		}
+	}
+
+	event := make([]byte, 0, 12+len(descriptors))
+	event = binary.BigEndian.AppendUint16(event, uint16(airing.Start().Unix()/60))
+	event = append(event, mjdTime(airing.Start())...)
+	event = append(event, bcdDuration(airing.End().Sub(airing.Start()))...)
+	// free_CA_mode is 0, the event is not scrambled.
+	event = binary.BigEndian.AppendUint16(event, uint16(runningStatus&0x07)<<13|uint16(len(descriptors)&0x0FFF))
+	return append(event, descriptors...)
+}
+
+// atscEvent encodes an event of an ATSC EIT with its content advisory and genre descriptors.
+func (g EITGenerator) atscEvent(airing Airing, eventID uint16, hasETT bool, genres *GenreMapper) []byte {
+	title := ""
+	if airing.Info != nil {
+		title = airing.Info.Title()
+	}
+	titleText := atscMultipleString(g.language(), title, 247, 1)
+
+	descriptors := make([]byte, 0, 64)
+	if advisory := atscContentAdvisory(airing); len(advisory) > 0 {
+		descriptors = append(descriptors, advisory...)
+	}
+	if codes := genres.Program(airing.Info).ATSCGenres(); len(codes) > 0 {
+		if len(codes) > 5 {
+			codes = codes[:5]
+		}
+		descriptors = append(descriptors, 0xAB, byte(1+len(codes)), 0xE0|byte(len(codes)))
+		descriptors = append(descriptors, codes...)
+	}
+
+	etmLocation := uint32(0)
+	if hasETT {
+		etmLocation = 1
+	}
+	length := uint32(airing.End().Sub(airing.Start()) / time.Second)
+
+	event := make([]byte, 0, 12+len(titleText)+len(descriptors))
+	event = binary.BigEndian.AppendUint16(event, 0xC000|eventID&0x3FFF)
+	event = binary.BigEndian.AppendUint32(event, uint32(airing.Start().Unix()-gpsEpoch+int64(g.gpsUTCOffset())))
+	event = append(event, byte(0xC0|etmLocation<<4|length>>16&0x0F), byte(length>>8), byte(length))
+	event = append(event, byte(len(titleText)))
+	event = append(event, titleText...)
+	event = binary.BigEndian.AppendUint16(event, 0xF000|uint16(len(descriptors)&0x0FFF))
+	return append(event, descriptors...)
+}
+
+// atscEventLength returns the length of the ATSC event at the start of events.
+func atscEventLength(events []byte) int {
+	titleLength := int(events[9])
+	descriptorsLength := int(binary.BigEndian.Uint16(events[10+titleLength:]) & 0x0FFF)
+	return 12 + titleLength + descriptorsLength
+}
+
+// atscETTSection returns the ETT section carrying the extended text message of an event.
+func (g EITGenerator) atscETTSection(sourceID, eventID uint16, description string) ([]byte, error) {
+	body := make([]byte, 0, 512)
+	body = binary.BigEndian.AppendUint16(body, eventID)
+	body = append(body, 0xC0|(g.Version&0x1F)<<1|0x01, 0x00, 0x00, 0x00)
+	body = binary.BigEndian.AppendUint32(body, uint32(sourceID)<<16|uint32(eventID&0x3FFF)<<2|0x02)
+	body = append(body, atscMultipleString(g.language(), description, 255, 15)...)
+	return psipSection(0xCC, body)
+}
+
+// psipSection adds the long section header and CRC_32 to the body of a section.
+func psipSection(tableID byte, body []byte) ([]byte, error) {
+	length := len(body) + 4
+	if length > maxSectionLength {
+		return nil, fmt.Errorf("section of table 0x%02X is %d bytes long, the maximum is %d", tableID, length, maxSectionLength)
+	}
+
+	section := make([]byte, 0, 3+length)
+	// section_syntax_indicator, private/reserved_future_use and reserved bits are all set.
+	section = append(section, tableID, 0xF0|byte(length>>8&0x0F), byte(length))
+	section = append(section, body...)
+	return binary.BigEndian.AppendUint32(section, mpegCRC32(section)), nil
+}
+
+// packEvents groups encoded events into sections of at most maxBytes of events each, returning at least one,
+// possibly empty, section. maxEvents limits the events per section if it is not zero.
+func packEvents(events [][]byte, maxBytes, maxSections, maxEvents int) ([][]byte, error) {
+	sections := [][]byte{{}}
+	count := 0
+	for _, event := range events {
+		if len(event) > maxBytes {
+			return nil, fmt.Errorf("event of %d bytes does not fit in a section", len(event))
+		}
+		last := len(sections) - 1
+		if len(sections[last])+len(event) > maxBytes || (maxEvents > 0 && count == maxEvents) {
+			if len(sections) == maxSections {
+				return nil, fmt.Errorf("events do not fit in %d sections", maxSections)
+			}
+			sections = append(sections, []byte{})
+			last, count = last+1, 0
+		}
+		sections[last] = append(sections[last], event...)
+		count++
+	}
+	return sections, nil
+}
+
+// mpegCRC32 returns the CRC_32 of MPEG-2 sections, as defined in ISO/IEC 13818-1 Annex A.
+func mpegCRC32(data []byte) uint32 {
+	crc := uint32(0xFFFFFFFF)
+	for _, b := range data {
+		crc ^= uint32(b) << 24
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// mjdTime encodes t as a 16 bit Modified Julian Date followed by the UTC time in 6 BCD digits.
+func mjdTime(t time.Time) []byte {
+	t = t.UTC()
+	mjd := uint16(t.Unix()/86400 + 40587)
+	return []byte{byte(mjd >> 8), byte(mjd), bcd(t.Hour()), bcd(t.Minute()), bcd(t.Second())}
+}
+
+// bcdDuration encodes d as hours, minutes and seconds in 6 BCD digits, capped at 99:59:59.
+func bcdDuration(d time.Duration) []byte {
+	seconds := int(d / time.Second)
+	if seconds > 99*3600+59*60+59 {
+		seconds = 99*3600 + 59*60 + 59
+	}
+	return []byte{bcd(seconds / 3600), bcd(seconds / 60 % 60), bcd(seconds % 60)}
+}
+
+func bcd(value int) byte {
+	return byte(value/10<<4 | value%10)
+}
+
+// dvbString encodes text for DVB, as plain bytes if it is printable ASCII or as UTF-8 after the 0x15
+// character table selector otherwise. The result is truncated to maxBytes on a character boundary.
+func dvbString(text string, maxBytes int) []byte {
+	text = strings.Join(strings.Fields(text), " ")
+	if maxBytes <= 0 || text == "" {
+		return []byte{}
+	}
+	for _, r := range text {
+		if r > 0x7E {
+			if maxBytes < 2 {
+				return []byte{}
+			}
+			return append([]byte{0x15}, truncateUTF8(text, maxBytes-1)...)
+		}
+	}
+	return []byte(truncateUTF8(text, maxBytes))
+}
+
+// dvbStringChunks splits text into at most maxChunks DVB strings of at most maxBytes each.
+func dvbStringChunks(text string, maxBytes, maxChunks int) [][]byte {
+	text = strings.Join(strings.Fields(text), " ")
+	chunks := make([][]byte, 0)
+	for text != "" && len(chunks) < maxChunks {
+		chunk := dvbString(text, maxBytes)
+		consumed := len(chunk)
+		if len(chunk) > 0 && chunk[0] == 0x15 {
+			consumed--
+		}
+		if consumed == 0 {
+			break
+		}
+		chunks = append(chunks, chunk)
+		text = text[consumed:]
+	}
+	return chunks
+}
+
+// truncateUTF8 truncates text to at most maxBytes without splitting a character.
+func truncateUTF8(text string, maxBytes int) string {
+	if len(text) <= maxBytes {
+		return text
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut]
+}
+
+// atscMultipleString encodes text as an ATSC multiple_string_structure of one string in the given language,
+// split into at most maxSegments uncompressed Latin-1 segments of maxSegmentBytes each.
+func atscMultipleString(language, text string, maxSegmentBytes, maxSegments int) []byte {
+	latin1 := make([]byte, 0, len(text))
+	for _, r := range strings.Join(strings.Fields(text), " ") {
+		if r > 0xFF {
+			r = '?'
+		}
+		latin1 = append(latin1, byte(r))
+	}
+
+	segments := make([][]byte, 0)
+	for len(latin1) > 0 && len(segments) < maxSegments {
+		size := maxSegmentBytes
+		if size > len(latin1) {
+			size = len(latin1)
+		}
+		segments = append(segments, latin1[:size])
+		latin1 = latin1[size:]
+	}
+	if len(segments) == 0 {
+		return []byte{0x00}
+	}
+
+	structure := []byte{0x01}
+	structure = append(structure, language...)
+	structure = append(structure, byte(len(segments)))
+	for _, segment := range segments {
+		// compression_type 0 is uncompressed and mode 0 selects the Latin-1 code page.
+		structure = append(structure, 0x00, 0x00, byte(len(segment)))
+		structure = append(structure, segment...)
+	}
+	return structure
+}
+
+// dvbParentalRatings returns the country code and rating bytes of the parental rating descriptor of the airing.
+// DVB ratings are the minimum age minus 3, so only ratings for ages 4 to 18 can be signalled.
+func dvbParentalRatings(airing Airing) [][]byte {
+	ratings := make([][]byte, 0)
+	seen := make(map[string]bool)
+	for _, rating := range AiringRatings(airing) {
+		country := strings.ToUpper(rating.Country)
+		if !rating.Known || len(country) != 3 || seen[country] || rating.MinimumAge < 4 || rating.MinimumAge > 18 {
+			continue
+		}
+		seen[country] = true
+		ratings = append(ratings, append([]byte(country), byte(rating.MinimumAge-3)))
+		if len(ratings) == 8 {
+			break
+		}
+	}
+	return ratings
+}
+
+// atscContentAdvisory returns the content_advisory_descriptor of the airing for rating region 1 (US),
+// signalling TV Parental Guidelines and MPAA ratings, or nil if the airing has neither.
+func atscContentAdvisory(airing Airing) []byte {
+	// Rating values of the dimensions of the region 1 rating region table.
+	dimensions := map[string]map[string][2]byte{
+		"USA Parental Rating": {
+			"TVG": {0, 2}, "TVPG": {0, 3}, "TV14": {0, 4}, "TVMA": {0, 5}, "TVY": {5, 1}, "TVY7": {5, 2},
+		},
+		"Motion Picture Association of America": {
+			"G": {7, 2}, "PG": {7, 3}, "PG13": {7, 4}, "R": {7, 5}, "NC17": {7, 6}, "X": {7, 7}, "NR": {7, 8},
+		},
+	}
+
+	rated := make([][2]byte, 0, 2)
+	seen := make(map[byte]bool)
+	for _, rating := range AiringRatings(airing) {
+		value, ok := dimensions[rating.Board][normalizeRatingCode(rating.Code)]
+		if !ok || seen[value[0]] {
+			continue
+		}
+		seen[value[0]] = true
+		rated = append(rated, value)
+	}
+	if len(rated) == 0 {
+		return nil
+	}
+
+	descriptor := []byte{0x87, byte(4 + 2*len(rated)), 0xC1, 0x01, byte(len(rated))}
+	for _, value := range rated {
+		descriptor = append(descriptor, value[0], 0xF0|value[1])
+	}
+	// rating_description_length, no description text.
+	return append(descriptor, 0x00)
+}
@@ -0,0 +1,207 @@
+package schedulesdirect
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testEITGuide() ([]Schedule, map[string]*ProgramInfo) {
+	rated := testProgram("EP000000060003", "2015-03-03T20:00:00Z", 1800)
+	rated.Ratings = []ContentRating{{Body: "USA Parental Rating", Code: "TV14", Country: "USA"}}
+
+	schedules := []Schedule{
+		{StationID: "10001", Programs: []Program{
+			rated,
+			testProgram("EP000000060002", "2015-03-03T20:30:00Z", 1800),
+			testProgram("MV000000010000", "2015-03-03T23:00:00Z", 7200),
+		}},
+		{StationID: "10002", Programs: []Program{
+			testProgram("EP000000060002", "2015-03-03T20:00:00Z", 1800),
+		}},
+	}
+
+	programs := IndexProgramInfo([]ProgramInfo{
+		{
+			ProgramID: "EP000000060003",
+			Titles:    []Title{{Title120: "'Allo 'Allo!"}},
+			Genres:    []string{"Sitcom", "Comedy"},
+			Descriptions: map[string][]Description{
+				"description1000": {{Language: "en", Description: strings.Repeat("René is running a café. ", 20)}},
+			},
+		},
+		{ProgramID: "EP000000060002", Titles: []Title{{Title120: "'Allo 'Allo!"}}, Genres: []string{"News"}},
+		{ProgramID: "MV000000010000", Titles: []Title{{Title120: "A Movie"}}, Genres: []string{"Movie", "Drama"}},
+	})
+
+	return schedules, programs
+}
+
+// testEITDescriptors returns the descriptors of the first event of a DVB EIT section, keyed by tag.
+func testEITDescriptors(section []byte) map[byte][][]byte {
+	event := section[14:]
+	length := int(binary.BigEndian.Uint16(event[10:]) & 0x0FFF)
+	loop := event[12 : 12+length]
+
+	descriptors := make(map[byte][][]byte)
+	for len(loop) > 0 {
+		descriptors[loop[0]] = append(descriptors[loop[0]], loop[2:2+int(loop[1])])
+		loop = loop[2+int(loop[1]):]
+	}
+	return descriptors
+}
+
+func TestMPEGEncoding(t *testing.T) {
+	assert.Equal(t, uint32(0x0376E6E7), mpegCRC32([]byte("123456789")))
+	assert.Equal(t, []byte{0xC0, 0x79, 0x12, 0x45, 0x00}, mjdTime(time.Date(1993, 10, 13, 12, 45, 0, 0, time.UTC)))
+	assert.Equal(t, []byte{0x01, 0x45, 0x30}, bcdDuration(time.Hour+45*time.Minute+30*time.Second))
+	assert.Equal(t, []byte("abc"), dvbString(" abc\n", 10))
+	assert.Equal(t, []byte("\x15caf"), dvbString("café", 5))
+	assert.Equal(t, [][]byte{[]byte("ab"), []byte("cd"), []byte("e")}, dvbStringChunks("abcde", 2, 4))
+}
+
+func TestEITServices(t *testing.T) {
+	services := EITServices(testDVBLineup(t), nil)
+	if assert.Len(t, services, 5) {
+		assert.Equal(t, EITService{StationID: "50000", ServiceID: 10301, TransportID: 1011, NetworkID: 1}, services[0])
+	}
+}
+
+func TestEITGeneratorPresentFollowing(t *testing.T) {
+	schedules, programs := testEITGuide()
+	service := EITService{StationID: "10001", ServiceID: 0x1234, TransportID: 0x0001, NetworkID: 0x233A}
+	generator := EITGenerator{Programs: programs, Version: 3}
+
+	sections, err := generator.PresentFollowing(service, schedules, time.Date(2015, 3, 3, 20, 10, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Len(t, sections, 2) {
+		return
+	}
+
+	present := sections[0]
+	assert.Equal(t, uint32(0), mpegCRC32(present))
+	assert.Equal(t, []byte{0x4E}, present[:1])
+	assert.Equal(t, len(present)-3, int(binary.BigEndian.Uint16(present[1:])&0x0FFF))
+	assert.Equal(t, []byte{0x12, 0x34, 0xC7, 0x00, 0x01, 0x00, 0x01, 0x23, 0x3A, 0x01, 0x4E}, present[3:14])
+
+	start := time.Date(2015, 3, 3, 20, 0, 0, 0, time.UTC)
+	assert.Equal(t, uint16(start.Unix()/60), binary.BigEndian.Uint16(present[14:]))
+	assert.Equal(t, mjdTime(start), present[16:21])
+	assert.Equal(t, []byte{0x00, 0x30, 0x00}, present[21:24])
+	assert.Equal(t, byte(4), present[24]>>5)
+
+	descriptors := testEITDescriptors(present)
+	if assert.Len(t, descriptors[0x4D], 1) {
+		short := descriptors[0x4D][0]
+		assert.Equal(t, "eng", string(short[:3]))
+		assert.Equal(t, "'Allo 'Allo!", string(short[4:4+short[3]]))
+	}
+	if assert.Len(t, descriptors[0x4E], 3) {
+		assert.Equal(t, byte(0x02), descriptors[0x4E][0][0])
+		assert.Equal(t, byte(0x22), descriptors[0x4E][2][0])
+		assert.Equal(t, byte(0x15), descriptors[0x4E][0][6])
+	}
+	assert.Equal(t, [][]byte{{0x14, 0x00}}, descriptors[0x54])
+	assert.Equal(t, [][]byte{{'U', 'S', 'A', 11}}, descriptors[0x55])
+
+	following := sections[1]
+	assert.Equal(t, uint32(0), mpegCRC32(following))
+	assert.Equal(t, byte(0x01), following[6])
+	assert.Equal(t, byte(1), following[24]>>5)
+	assert.Equal(t, [][]byte{{0x20, 0x00}}, testEITDescriptors(following)[0x54])
+
+	generator.OtherTransportStream = true
+	sections, err = generator.PresentFollowing(service, schedules, time.Date(2015, 3, 4, 12, 0, 0, 0, time.UTC))
+	if assert.NoError(t, err) && assert.Len(t, sections, 2) {
+		assert.Equal(t, byte(0x4F), sections[0][0])
+		assert.Len(t, sections[0], 18)
+		assert.Len(t, sections[1], 18)
+	}
+}
+
+func TestEITGeneratorSchedule(t *testing.T) {
+	schedules, programs := testEITGuide()
+	service := EITService{StationID: "10001", ServiceID: 1}
+
+	sections, err := EITGenerator{Programs: programs}.Schedule(service, schedules, time.Date(2015, 3, 3, 20, 10, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Len(t, sections, 8) {
+		return
+	}
+
+	for idx, section := range sections {
+		assert.Equal(t, uint32(0), mpegCRC32(section))
+		assert.Equal(t, byte(0x50), section[0])
+		assert.Equal(t, byte(56), section[7], "last_section_number")
+		assert.Equal(t, byte(0x50), section[13], "last_table_id")
+		if idx < 6 {
+			assert.Equal(t, byte(idx*8), section[6])
+			assert.Equal(t, byte(idx*8), section[12])
+			assert.Len(t, section, 18)
+		}
+	}
+
+	assert.Equal(t, byte(48), sections[6][6])
+	assert.Equal(t, byte(0), sections[6][24]>>5)
+	assert.Equal(t, byte(56), sections[7][6])
+	assert.Equal(t, []byte{0x02, 0x00, 0x00}, sections[7][21:24])
+
+	_, err = EITGenerator{ScheduleDays: 65}.Schedule(service, schedules, time.Now())
+	assert.Error(t, err)
+}
+
+func TestEITGeneratorATSC(t *testing.T) {
+	schedules, programs := testEITGuide()
+	service := EITService{StationID: "10001", ServiceID: 3, SourceID: 0x0102}
+	generator := EITGenerator{Programs: programs, Version: 1}
+	now := time.Date(2015, 3, 3, 20, 10, 0, 0, time.UTC)
+
+	eits, etts, err := generator.ATSCEIT(service, schedules, now, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Len(t, eits, 1) || !assert.Len(t, etts, 1) {
+		return
+	}
+
+	eit := eits[0]
+	assert.Equal(t, uint32(0), mpegCRC32(eit))
+	assert.Equal(t, []byte{0xCB}, eit[:1])
+	assert.Equal(t, []byte{0x01, 0x02, 0xC3, 0x00, 0x00, 0x00, 0x02}, eit[3:10])
+
+	start := time.Date(2015, 3, 3, 20, 0, 0, 0, time.UTC)
+	eventID := uint16(start.Unix()/60) & 0x3FFF
+	assert.Equal(t, 0xC000|eventID, binary.BigEndian.Uint16(eit[10:]))
+	assert.Equal(t, uint32(start.Unix()-315964800+18), binary.BigEndian.Uint32(eit[12:]))
+	assert.Equal(t, []byte{0xD0, 0x07, 0x08}, eit[16:19])
+
+	title := eit[20 : 20+int(eit[19])]
+	assert.Equal(t, append([]byte{0x01, 'e', 'n', 'g', 0x01, 0x00, 0x00, 12}, "'Allo 'Allo!"...), title)
+
+	descriptors := eit[22+len(title):]
+	assert.Equal(t, []byte{0x87, 0x06, 0xC1, 0x01, 0x01, 0x00, 0xF4, 0x00}, descriptors[:8])
+	assert.Equal(t, []byte{0xAB, 0x02, 0xE1, 0x34}, descriptors[8:12])
+
+	ett := etts[0]
+	assert.Equal(t, uint32(0), mpegCRC32(ett))
+	assert.Equal(t, byte(0xCC), ett[0])
+	assert.Equal(t, uint32(0x0102)<<16|uint32(eventID)<<2|0x02, binary.BigEndian.Uint32(ett[9:]))
+	assert.Equal(t, []byte{0x01, 'e', 'n', 'g', 0x02}, ett[13:18])
+	assert.Contains(t, string(ett), "Ren\xe9 is running a caf\xe9.")
+
+	eits, etts, err = generator.ATSCEIT(service, schedules, now, 1)
+	if assert.NoError(t, err) && assert.Len(t, eits, 1) {
+		assert.Equal(t, byte(1), eits[0][9])
+		assert.Empty(t, etts)
+	}
+
+	_, _, err = generator.ATSCEIT(service, schedules, now, 128)
+	assert.Error(t, err)
+}
@@ -0,0 +1,464 @@
+package schedulesdirect
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxGuideGridSlots is the most columns a guide grid may have, a week of 5 minute slots.
+const maxGuideGridSlots = 7 * 24 * 12
+
+// A GuideGridRenderer lays out schedules as a channel-by-time grid, e.g. for kiosk displays or weekly printouts.
+type GuideGridRenderer struct {
+	// Lineups set the order, channel numbers, call signs and logos of the rows.
+	// Stations with airings in the window but missing from the lineups are added after them, sorted by station ID.
+	Lineups []*ChannelResponse
+	// Programs are used for titles and episode titles, the program ID is shown for missing programs.
+	Programs map[string]*ProgramInfo
+	// Start is the beginning of the window. Its location is used to format the slot times.
+	Start time.Time
+	// Duration is the length of the window, 3 hours if zero.
+	Duration time.Duration
+	// Slot is the length of a column, 30 minutes if zero. The window may span at most 2016 slots.
+	Slot time.Duration
+	// Title is the heading of the guide, the date and time of the window if empty.
+	Title string
+}
+
+// A GuideGrid is a channel-by-time grid of airings.
+type GuideGrid struct {
+	Title string
+	// Slots are the start times of the columns.
+	Slots []time.Time
+	Rows  []GuideGridRow
+}
+
+// A GuideGridRow is the channel of a station and its airings during the window.
+type GuideGridRow struct {
+	StationID string
+	Number    string
+	CallSign  string
+	Name      string
+	Logo      string
+	// Cells cover every slot of the grid exactly once, in order.
+	Cells []GuideGridCell
+}
+
+// A GuideGridCell spans one or more slots of a row. Cells without airings are gaps in the schedule.
+type GuideGridCell struct {
+	// Airings usually holds a single airing. Airings too short to get a slot of their own share the cell of the airing before them.
+	Airings []Airing
+	// Span is the number of slots the cell covers.
+	Span int
+	// ContinuesBefore is true if the first airing began before the window.
+	ContinuesBefore bool
+	// ContinuesAfter is true if an airing ends after the window.
+	ContinuesAfter bool
+	// TimeApproximate is true if the start time of an airing is not exact, e.g. following a live sports event.
+	TimeApproximate bool
+}
+
+// Title returns the titles of the airings of the cell, joined with a slash.
+func (c GuideGridCell) Title() string {
+	titles := make([]string, 0, len(c.Airings))
+	for _, airing := range c.Airings {
+		title := airing.Title()
+		if title == "" {
+			title = airing.Program.ProgramID
+		}
+		titles = append(titles, title)
+	}
+	return strings.Join(titles, " / ")
+}
+
+// EpisodeTitle returns the episode title of the airing if the cell holds a single airing.
+func (c GuideGridCell) EpisodeTitle() string {
+	if len(c.Airings) != 1 || c.Airings[0].Info == nil {
+		return ""
+	}
+	return c.Airings[0].Info.EpisodeTitle150
+}
+
+// Badges returns the New, Live and premiere or finale badges of the airings of the cell.
+func (c GuideGridCell) Badges() []string {
+	badges := make([]string, 0)
+	add := func(badge string) {
+		if !containsStringFold(badges, badge) {
+			badges = append(badges, badge)
+		}
+	}
+	for _, airing := range c.Airings {
+		if airing.Program.New {
+			add("New")
+		}
+		if airing.Program.IsLive() {
+			add("Live")
+		}
+		if airing.Program.IsPremiereOrFinale != nil {
+			add(string(*airing.Program.IsPremiereOrFinale))
+		} else if airing.Program.Premiere {
+			add(string(Premiere))
+		}
+	}
+	return badges
+}
+
+// Grid lays out the airings of the schedules which overlap the window.
+func (r GuideGridRenderer) Grid(schedules []Schedule) (GuideGrid, error) {
+	if r.Start.IsZero() {
+		return GuideGrid{}, fmt.Errorf("guide grid start time is required")
+	}
+	duration, slot := r.Duration, r.Slot
+	if duration == 0 {
+		duration = 3 * time.Hour
+	}
+	if slot == 0 {
+		slot = 30 * time.Minute
+	}
+	if duration < 0 || slot < 0 || slot > duration {
+		return GuideGrid{}, fmt.Errorf("guide grid slot %s does not fit the window of %s", slot, duration)
+	}
+
+	start := r.Start
+	end := start.Add(duration)
+	slots := int((duration + slot - 1) / slot)
+	if slots > maxGuideGridSlots {
+		return GuideGrid{}, fmt.Errorf("guide grid window of %s has %d slots of %s, more than the maximum of %d", duration, slots, slot, maxGuideGridSlots)
+	}
+
+	grid := GuideGrid{Title: r.Title, Slots: make([]time.Time, 0, slots)}
+	if grid.Title == "" {
+		grid.Title = start.Format("Monday 2 January 2006 15:04")
+	}
+	for idx := 0; idx < slots; idx++ {
+		grid.Slots = append(grid.Slots, start.Add(time.Duration(idx)*slot))
+	}
+
+	airings := make(map[string][]Airing)
+	for _, airing := range AiringsFromSchedules(schedules, r.Programs) {
+		if airing.End().After(start) && airing.Start().Before(end) {
+			airings[airing.StationID] = append(airings[airing.StationID], airing)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, lineup := range r.Lineups {
+		if lineup == nil {
+			continue
+		}
+		stations := make(map[string]Station, len(lineup.Stations))
+		for _, station := range lineup.Stations {
+			stations[station.StationID] = station
+		}
+		for _, entry := range lineup.Map {
+			number := entry.Number()
+			key := entry.StationID + "\x00" + number
+			if entry.StationID == "" || seen[key] {
+				continue
+			}
+			seen[key], seen[entry.StationID] = true, true
+
+			station := stations[entry.StationID]
+			row := GuideGridRow{StationID: entry.StationID, Number: number, CallSign: station.CallSign, Name: station.Name}
+			if logo := station.PrimaryLogo(); logo != nil {
+				row.Logo = logo.URL
+			}
+			grid.Rows = append(grid.Rows, row)
+		}
+	}
+
+	unlisted := make([]string, 0)
+	for stationID := range airings {
+		if !seen[stationID] {
+			unlisted = append(unlisted, stationID)
+		}
+	}
+	sort.Strings(unlisted)
+	for _, stationID := range unlisted {
+		grid.Rows = append(grid.Rows, GuideGridRow{StationID: stationID})
+	}
+
+	for idx := range grid.Rows {
+		grid.Rows[idx].Cells = guideGridCells(airings[grid.Rows[idx].StationID], start, end, slot, slots)
+	}
+
+	return grid, nil
+}
+
+// guideGridCells lays out the airings of a station, sorted by start time, into cells covering all slots.
+func guideGridCells(airings []Airing, start, end time.Time, slot time.Duration, slots int) []GuideGridCell {
+	cells := make([]GuideGridCell, 0)
+	used := 0
+	for _, airing := range airings {
+		first, last := 0, slots
+		if offset := airing.Start().Sub(start); offset > 0 {
+			first = int(offset / slot)
+		}
+		if offset := airing.End().Sub(start); offset < time.Duration(slots)*slot {
+			last = int((offset + slot - 1) / slot)
+		}
+		if last <= first {
+			last = first + 1
+		}
+
+		if first < used {
+			first = used
+		}
+		if first >= last || first >= slots {
+			// The airing is too short for a slot of its own.
+			if len(cells) > 0 && len(cells[len(cells)-1].Airings) > 0 {
+				cell := &cells[len(cells)-1]
+				cell.Airings = append(cell.Airings, airing)
+				cell.ContinuesAfter = cell.ContinuesAfter || airing.End().After(end)
+				cell.TimeApproximate = cell.TimeApproximate || airing.Program.TimeApproximate
+			}
+			continue
+		}
+
+		if first > used {
+			cells = append(cells, GuideGridCell{Span: first - used})
+		}
+		cells = append(cells, GuideGridCell{
+			Airings:         []Airing{airing},
+			Span:            last - first,
+			ContinuesBefore: airing.Start().Before(start),
+			ContinuesAfter:  airing.End().After(end),
+			TimeApproximate: airing.Program.TimeApproximate,
+		})
+		used = last
+	}
+	if used < slots {
+		cells = append(cells, GuideGridCell{Span: slots - used})
+	}
+	return cells
+}
+
+// channel returns the channel number and call sign of the row, or the station ID if both are unknown.
+func (r GuideGridRow) channel() string {
+	channel := strings.TrimSpace(r.Number + " " + r.CallSign)
+	if channel == "" {
+		channel = r.StationID
+	}
+	return channel
+}
+
+// label returns the plain text of the cell, marking approximate times, badges and airings continuing outside the window.
+func (c GuideGridCell) label() string {
+	if len(c.Airings) == 0 {
+		return ""
+	}
+	label := c.Title()
+	if episode := c.EpisodeTitle(); episode != "" {
+		label += ": " + episode
+	}
+	for _, badge := range c.Badges() {
+		label += " [" + badge + "]"
+	}
+	if c.TimeApproximate {
+		label = "~" + label
+	}
+	if c.ContinuesBefore {
+		label = "« " + label
+	}
+	if c.ContinuesAfter {
+		label += " »"
+	}
+	return label
+}
+
+// WriteHTML writes the grid as a standalone HTML document.
+func (g GuideGrid) WriteHTML(w io.Writer) error {
+	lines := []string{
+		"<!DOCTYPE html>",
+		"<html>",
+		"<head>",
+		`<meta charset="utf-8">`,
+		"<title>" + html.EscapeString(g.Title) + "</title>",
+		"<style>",
+		"body { font-family: sans-serif; font-size: 12px; }",
+		"table { border-collapse: collapse; table-layout: fixed; width: 100%; }",
+		"th, td { border: 1px solid #999; padding: 2px 4px; overflow: hidden; vertical-align: top; text-align: left; }",
+		"th.channel img { max-height: 24px; max-width: 48px; display: block; }",
+		"td.gap { background: #eee; }",
+		"td.continues-before { border-left-style: dashed; }",
+		"td.continues-after { border-right-style: dashed; }",
+		".episode { display: block; font-style: italic; }",
+		".badge { font-size: 10px; font-weight: bold; border-radius: 3px; padding: 0 3px; margin-left: 2px; background: #333; color: #fff; }",
+		".approximate { color: #666; }",
+		"@media print { body { font-size: 9px; } tr { page-break-inside: avoid; } }",
+		"</style>",
+		"</head>",
+		"<body>",
+		"<h1>" + html.EscapeString(g.Title) + "</h1>",
+		`<table class="guide">`,
+		"<thead>",
+	}
+
+	header := `<tr><th class="channel">Channel</th>`
+	for _, slot := range g.Slots {
+		header += fmt.Sprintf(`<th><time datetime="%s">%s</time></th>`, slot.Format(time.RFC3339), slot.Format("15:04"))
+	}
+	lines = append(lines, header+"</tr>", "</thead>", "<tbody>")
+
+	for _, row := range g.Rows {
+		line := `<tr><th class="channel">`
+		if row.Logo != "" {
+			line += fmt.Sprintf(`<img src="%s" alt="%s">`, html.EscapeString(row.Logo), html.EscapeString(row.CallSign))
+		}
+		line += html.EscapeString(row.channel()) + "</th>"
+
+		for _, cell := range row.Cells {
+			classes := make([]string, 0, 4)
+			if len(cell.Airings) == 0 {
+				classes = append(classes, "gap")
+			}
+			if cell.ContinuesBefore {
+				classes = append(classes, "continues-before")
+			}
+			if cell.ContinuesAfter {
+				classes = append(classes, "continues-after")
+			}
+			if cell.TimeApproximate {
+				classes = append(classes, "approximate")
+			}
+
+			line += "<td"
+			if cell.Span > 1 {
+				line += fmt.Sprintf(` colspan="%d"`, cell.Span)
+			}
+			if len(classes) > 0 {
+				line += ` class="` + strings.Join(classes, " ") + `"`
+			}
+			line += ">"
+
+			if len(cell.Airings) > 0 {
+				first := cell.Airings[0].Start()
+				approximate := ""
+				if cell.TimeApproximate {
+					approximate = `~`
+				}
+				line += fmt.Sprintf(`<time datetime="%s">%s%s</time> `, first.Format(time.RFC3339), approximate, first.In(g.location()).Format("15:04"))
+				line += `<span class="title">` + html.EscapeString(cell.Title()) + "</span>"
+				for _, badge := range cell.Badges() {
+					line += fmt.Sprintf(`<span class="badge %s">%s</span>`, html.EscapeString(strings.ToLower(strings.Replace(badge, " ", "-", -1))), html.EscapeString(badge))
+				}
+				if episode := cell.EpisodeTitle(); episode != "" {
+					line += `<span class="episode">` + html.EscapeString(episode) + "</span>"
+				}
+			}
+			line += "</td>"
+		}
+		lines = append(lines, line+"</tr>")
+	}
+	lines = append(lines, "</tbody>", "</table>", "</body>", "</html>")
+
+	return writeGuideGridLines(w, lines)
+}
+
+// location returns the location of the slot times.
+func (g GuideGrid) location() *time.Location {
+	if len(g.Slots) == 0 {
+		return time.UTC
+	}
+	return g.Slots[0].Location()
+}
+
+// WriteMarkdown writes the grid as a Markdown table. Markdown has no column spans,
+// so the slots covered by an airing after its first one are marked with an arrow.
+func (g GuideGrid) WriteMarkdown(w io.Writer) error {
+	escape := strings.NewReplacer("|", `\|`, "\r", " ", "\n", " ")
+
+	header := "| Channel |"
+	rule := "| --- |"
+	for _, slot := range g.Slots {
+		header += " " + slot.Format("15:04") + " |"
+		rule += " --- |"
+	}
+	lines := []string{"# " + escape.Replace(g.Title), "", header, rule}
+
+	for _, row := range g.Rows {
+		channel := escape.Replace(row.channel())
+		if row.Logo != "" {
+			channel = fmt.Sprintf("![%s](<%s>) %s", escape.Replace(row.CallSign), strings.NewReplacer("<", "%3C", ">", "%3E", " ", "%20").Replace(row.Logo), channel)
+		}
+		line := "| " + channel + " |"
+		for _, cell := range row.Cells {
+			line += " " + escape.Replace(cell.label()) + " |"
+			for idx := 1; idx < cell.Span; idx++ {
+				if len(cell.Airings) > 0 {
+					line += " → |"
+				} else {
+					line += "  |"
+				}
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	return writeGuideGridLines(w, lines)
+}
+
+// WriteText writes the grid as a plain-text table for fixed-width fonts.
+// Each slot is slotWidth characters wide, 16 if not positive, and longer titles are truncated.
+func (g GuideGrid) WriteText(w io.Writer, slotWidth int) error {
+	if slotWidth <= 0 {
+		slotWidth = 16
+	}
+
+	channelWidth := utf8.RuneCountInString("Channel")
+	for _, row := range g.Rows {
+		if width := utf8.RuneCountInString(row.channel()); width > channelWidth {
+			channelWidth = width
+		}
+	}
+
+	header := guideGridPad("Channel", channelWidth) + " |"
+	rule := strings.Repeat("-", channelWidth+1) + "+"
+	for _, slot := range g.Slots {
+		header += " " + guideGridPad(slot.Format("15:04"), slotWidth) + " |"
+		rule += strings.Repeat("-", slotWidth+2) + "+"
+	}
+	lines := []string{g.Title, "", header, rule}
+
+	for _, row := range g.Rows {
+		line := guideGridPad(row.channel(), channelWidth) + " |"
+		for _, cell := range row.Cells {
+			// A cell spanning several slots also takes the space of the separators between them.
+			line += " " + guideGridPad(cell.label(), cell.Span*(slotWidth+3)-3) + " |"
+		}
+		lines = append(lines, line)
+	}
+
+	return writeGuideGridLines(w, lines)
+}
+
+// guideGridPad pads or truncates text to the given number of characters.
+func guideGridPad(text string, width int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	length := utf8.RuneCountInString(text)
+	if length > width {
+		runes := []rune(text)
+		if width <= 1 {
+			return string(runes[:width])
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return text + strings.Repeat(" ", width-length)
+}
+
+// writeGuideGridLines writes the lines followed by newlines.
+func writeGuideGridLines(w io.Writer, lines []string) error {
+	buf := bufio.NewWriter(w)
+	for _, line := range lines {
+		if _, err := buf.WriteString(line + "\n"); err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
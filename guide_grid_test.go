@@ -0,0 +1,118 @@
+package schedulesdirect
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testGuideGrid(t *testing.T) GuideGrid {
+	t.Helper()
+
+	earlier := testProgram("EP000000060003", "2015-03-03T19:30:00Z", 3600)
+	earlier.New = true
+	sports := testProgram("EP000000070001", "2015-03-03T20:30:00Z", 300)
+	sports.TimeApproximate = true
+	sports.LiveTapeDelay = "live"
+	seasonPremiere := SeasonPremiere
+	movie := testProgram("MV000000010000", "2015-03-03T20:00:00Z", 7200)
+	movie.IsPremiereOrFinale = &seasonPremiere
+
+	grid, err := GuideGridRenderer{
+		Lineups: []*ChannelResponse{testM3ULineup(t), nil},
+		Programs: IndexProgramInfo([]ProgramInfo{
+			{ProgramID: "EP000000060003", Titles: []Title{{Title120: "'Allo 'Allo!"}}, EpisodeTitle150: "The Nicked Airmen"},
+			{ProgramID: "EP000000070001", Titles: []Title{{Title120: "Soccer | Live"}}},
+			{ProgramID: "MV000000010000", Titles: []Title{{Title120: "Alien: Resurrection"}}},
+		}),
+		Start:    time.Date(2015, 3, 3, 20, 0, 0, 0, time.UTC),
+		Duration: 90 * time.Minute,
+	}.Grid([]Schedule{
+		{StationID: "20454", Programs: []Program{earlier, sports, testProgram("SH000000080000", "2015-03-03T20:35:00Z", 600), testProgram("SH000000090000", "2015-03-03T22:00:00Z", 600)}},
+		{StationID: "10021", Programs: []Program{movie}},
+		{StationID: "99999", Programs: []Program{testProgram("SH000000080000", "2015-03-03T21:00:00Z", 1800)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return grid
+}
+
+func TestGuideGridRenderer(t *testing.T) {
+	grid := testGuideGrid(t)
+
+	assert.Equal(t, "Tuesday 3 March 2015 20:00", grid.Title)
+	assert.Len(t, grid.Slots, 3)
+	if assert.Len(t, grid.Rows, 3) {
+		assert.Equal(t, "2.1", grid.Rows[0].Number)
+		assert.Equal(t, "https://example.com/wnbc.png", grid.Rows[1].Logo)
+		assert.Equal(t, "99999", grid.Rows[2].StationID)
+
+		cells := grid.Rows[0].Cells
+		if assert.Len(t, cells, 3) {
+			assert.Equal(t, 1, cells[0].Span)
+			assert.True(t, cells[0].ContinuesBefore)
+			assert.Equal(t, []string{"New"}, cells[0].Badges())
+			assert.Equal(t, "Soccer | Live / SH000000080000", cells[1].Title())
+			assert.True(t, cells[1].TimeApproximate)
+			assert.Empty(t, cells[1].EpisodeTitle())
+			assert.Empty(t, cells[2].Airings)
+		}
+		assert.Equal(t, 3, grid.Rows[1].Cells[0].Span)
+		assert.True(t, grid.Rows[1].Cells[0].ContinuesAfter)
+		assert.Equal(t, []string{"Season Premiere"}, grid.Rows[1].Cells[0].Badges())
+		assert.Equal(t, []int{2, 1}, []int{grid.Rows[2].Cells[0].Span, grid.Rows[2].Cells[1].Span})
+	}
+
+	_, err := GuideGridRenderer{}.Grid(nil)
+	assert.Error(t, err)
+	_, err = GuideGridRenderer{Start: time.Now(), Duration: time.Hour, Slot: 2 * time.Hour}.Grid(nil)
+	assert.Error(t, err)
+	_, err = GuideGridRenderer{Start: time.Now(), Duration: 14 * 24 * time.Hour, Slot: time.Second}.Grid(nil)
+	assert.Error(t, err)
+	_, err = GuideGridRenderer{Start: time.Now(), Duration: 7 * 24 * time.Hour, Slot: 5 * time.Minute}.Grid(nil)
+	assert.NoError(t, err)
+}
+
+func TestGuideGridWrite(t *testing.T) {
+	grid := testGuideGrid(t)
+
+	buf := &bytes.Buffer{}
+	if err := grid.WriteMarkdown(buf); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `# Tuesday 3 March 2015 20:00
+
+| Channel | 20:00 | 20:30 | 21:00 |
+| --- | --- | --- | --- |
+| ![WCBS](<https://example.com/wcbs.png>) 2.1 WCBS | « 'Allo 'Allo!: The Nicked Airmen [New] | ~Soccer \| Live / SH000000080000 [Live] |  |
+| ![WNBC](<https://example.com/wnbc.png>) 4.1 WNBC | Alien: Resurrection [Season Premiere] » | → | → |
+| 99999 |  |  | SH000000080000 |
+`, buf.String())
+
+	buf.Reset()
+	if err := grid.WriteText(buf, 12); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `Tuesday 3 March 2015 20:00
+
+Channel  | 20:00        | 20:30        | 21:00        |
+---------+--------------+--------------+--------------+
+2.1 WCBS | « 'Allo 'Al… | ~Soccer | L… |              |
+4.1 WNBC | Alien: Resurrection [Season Premiere] »    |
+99999    |                             | SH000000080… |
+`, buf.String())
+
+	buf.Reset()
+	if err := grid.WriteHTML(buf); err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, buf.String(), "<title>Tuesday 3 March 2015 20:00</title>")
+	assert.Contains(t, buf.String(), `<tr><th class="channel"><img src="https://example.com/wcbs.png" alt="WCBS">2.1 WCBS</th><td class="continues-before"><time datetime="2015-03-03T19:30:00Z">19:30</time> <span class="title">&#39;Allo &#39;Allo!</span><span class="badge new">New</span><span class="episode">The Nicked Airmen</span></td>`)
+	assert.Contains(t, buf.String(), `<td class="approximate"><time datetime="2015-03-03T20:30:00Z">~20:30</time> <span class="title">Soccer | Live / SH000000080000</span><span class="badge live">Live</span></td><td class="gap"></td></tr>`)
+	assert.Contains(t, buf.String(), `<td colspan="3" class="continues-after">`)
+	assert.Contains(t, buf.String(), `<span class="badge season-premiere">Season Premiere</span>`)
+	assert.Contains(t, buf.String(), `<tr><th class="channel">99999</th><td colspan="2" class="gap"></td>`)
+}
@@ -0,0 +1,210 @@
+// Package guideapi serves a friendly JSON API over a guide synced from Schedules Direct.
+//
+// Unlike the raw Schedules Direct API, airings come with their channel number and resolved
+// titles and descriptions, so front-ends can render a guide without further lookups.
+package guideapi
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	schedulesdirect "github.com/tellytv/go.schedulesdirect"
+)
+
+// A Channel is a station in a lineup along with the channel number it is tuned by.
+type Channel struct {
+	StationID string                       `json:"stationID"`
+	Number    string                       `json:"number,omitempty"`
+	CallSign  string                       `json:"callSign,omitempty"`
+	Name      string                       `json:"name,omitempty"`
+	Affiliate string                       `json:"affiliate,omitempty"`
+	LineupID  string                       `json:"lineup,omitempty"`
+	Logo      *schedulesdirect.StationLogo `json:"logo,omitempty"`
+}
+
+// A Guide is a snapshot of the channels, airings and programs of one or more lineups.
+// A Guide must not be modified once it is served.
+type Guide struct {
+	// Channels are in lineup order. A station in several lineups is listed once, with its first channel number.
+	Channels []Channel
+	// Airings are sorted by start time.
+	Airings  []schedulesdirect.Airing
+	Programs map[string]*schedulesdirect.ProgramInfo
+
+	channels  map[string]*Channel
+	byStation map[string][]schedulesdirect.Airing
+	byProgram map[string][]schedulesdirect.Airing
+	search    *schedulesdirect.SearchIndex
+	people    *schedulesdirect.PersonIndex
+}
+
+// NewGuide builds a guide from the channels of the lineups, the schedules of their stations and
+// the information of the scheduled programs.
+func NewGuide(lineups []*schedulesdirect.ChannelResponse, schedules []schedulesdirect.Schedule, programs []schedulesdirect.ProgramInfo) *Guide {
+	g := &Guide{
+		Channels:  make([]Channel, 0),
+		Programs:  schedulesdirect.IndexProgramInfo(programs),
+		channels:  make(map[string]*Channel),
+		byStation: make(map[string][]schedulesdirect.Airing),
+		byProgram: make(map[string][]schedulesdirect.Airing),
+		search:    schedulesdirect.NewSearchIndex(),
+		people:    schedulesdirect.NewPersonIndex(),
+	}
+
+	seen := make(map[string]bool)
+	for _, lineup := range lineups {
+		if lineup == nil {
+			continue
+		}
+		stations := make(map[string]schedulesdirect.Station, len(lineup.Stations))
+		for _, station := range lineup.Stations {
+			stations[station.StationID] = station
+		}
+		lineupID := ""
+		if lineup.Metadata != nil {
+			lineupID = lineup.Metadata.Lineup
+		}
+		for _, entry := range lineup.Map {
+			if seen[entry.StationID] || entry.StationID == "" {
+				continue
+			}
+			seen[entry.StationID] = true
+			station := stations[entry.StationID]
+			g.Channels = append(g.Channels, Channel{
+				StationID: entry.StationID,
+				Number:    entry.Number(),
+				CallSign:  station.CallSign,
+				Name:      station.Name,
+				Affiliate: station.Affiliate,
+				LineupID:  lineupID,
+				Logo:      station.PrimaryLogo(),
+			})
+		}
+	}
+	for idx := range g.Channels {
+		g.channels[g.Channels[idx].StationID] = &g.Channels[idx]
+	}
+
+	g.Airings = schedulesdirect.AiringsFromSchedules(schedules, g.Programs)
+	for _, airing := range g.Airings {
+		g.byStation[airing.StationID] = append(g.byStation[airing.StationID], airing)
+		g.byProgram[airing.Program.ProgramID] = append(g.byProgram[airing.Program.ProgramID], airing)
+	}
+
+	g.search.AddPrograms(programs...)
+	g.search.AddAirings(g.Airings)
+	g.people.AddPrograms(programs...)
+	g.people.AddAirings(g.Airings)
+
+	return g
+}
+
+// SyncGuide fetches the channels of the lineups, the given number of days of schedules starting today (UTC)
+// and the information of every scheduled program, and builds a guide from them.
+func SyncGuide(c *schedulesdirect.Client, lineupIDs []string, days int) (*Guide, error) {
+	if days < 1 {
+		return nil, fmt.Errorf("cannot sync %d days of schedules", days)
+	}
+
+	lineups := make([]*schedulesdirect.ChannelResponse, 0, len(lineupIDs))
+	requests := make([]schedulesdirect.StationScheduleRequest, 0)
+	seenStations := make(map[string]bool)
+
+	dates := make([]string, 0, days)
+	today := time.Now().UTC()
+	for day := 0; day < days; day++ {
+		dates = append(dates, today.AddDate(0, 0, day).Format("2006-01-02"))
+	}
+
+	for _, lineupID := range lineupIDs {
+		lineup, err := c.GetChannels(lineupID, true)
+		if err != nil {
+			return nil, fmt.Errorf("error getting channels of lineup %s: %s", lineupID, err)
+		}
+		lineups = append(lineups, lineup)
+
+		for _, entry := range lineup.Map {
+			if !seenStations[entry.StationID] {
+				seenStations[entry.StationID] = true
+				requests = append(requests, schedulesdirect.StationScheduleRequest{StationID: entry.StationID, Dates: dates})
+			}
+		}
+	}
+
+	schedules := make([]schedulesdirect.Schedule, 0)
+	if len(requests) > 0 {
+		var err error
+		if schedules, err = c.GetSchedules(requests); err != nil {
+			return nil, fmt.Errorf("error getting schedules: %s", err)
+		}
+	}
+
+	programIDs := make([]string, 0)
+	seenPrograms := make(map[string]bool)
+	for _, schedule := range schedules {
+		for _, program := range schedule.Programs {
+			if !seenPrograms[program.ProgramID] {
+				seenPrograms[program.ProgramID] = true
+				programIDs = append(programIDs, program.ProgramID)
+			}
+		}
+	}
+
+	programs := make([]schedulesdirect.ProgramInfo, 0)
+	if len(programIDs) > 0 {
+		var err error
+		if programs, err = c.GetProgramInfo(programIDs); err != nil {
+			return nil, fmt.Errorf("error getting programs: %s", err)
+		}
+	}
+
+	return NewGuide(lineups, schedules, programs), nil
+}
+
+// Channel returns the channel of the station, or nil if the station is not in the guide.
+func (g *Guide) Channel(stationID string) *Channel {
+	return g.channels[stationID]
+}
+
+// OnNow returns the airing of the station which is on at the given time.
+func (g *Guide) OnNow(stationID string, at time.Time) (schedulesdirect.Airing, bool) {
+	for _, airing := range g.byStation[stationID] {
+		if !airing.Start().After(at) && airing.End().After(at) {
+			return airing, true
+		}
+	}
+	return schedulesdirect.Airing{}, false
+}
+
+// UpNext returns the first airing of the station starting after the given time.
+func (g *Guide) UpNext(stationID string, at time.Time) (schedulesdirect.Airing, bool) {
+	airings := g.byStation[stationID]
+	idx := sort.Search(len(airings), func(i int) bool { return airings[i].Start().After(at) })
+	if idx == len(airings) {
+		return schedulesdirect.Airing{}, false
+	}
+	return airings[idx], true
+}
+
+// Between returns the airings of the station which overlap the time range.
+func (g *Guide) Between(stationID string, start, end time.Time) []schedulesdirect.Airing {
+	airings := make([]schedulesdirect.Airing, 0)
+	for _, airing := range g.byStation[stationID] {
+		if airing.Start().Before(end) && airing.End().After(start) {
+			airings = append(airings, airing)
+		}
+	}
+	return airings
+}
+
+// Upcoming returns the airings of the program which end after the given time.
+func (g *Guide) Upcoming(programID string, after time.Time) []schedulesdirect.Airing {
+	airings := make([]schedulesdirect.Airing, 0)
+	for _, airing := range g.byProgram[programID] {
+		if airing.End().After(after) {
+			airings = append(airings, airing)
+		}
+	}
+	return airings
+}
@@ -0,0 +1,146 @@
+package guideapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	schedulesdirect "github.com/tellytv/go.schedulesdirect"
+)
+
+const testLineup = `{
+	"map": [
+		{"stationID": "20454", "channel": "2.1"},
+		{"stationID": "10021", "channelMajor": 4, "channelMinor": 1}
+	],
+	"stations": [
+		{"stationID": "20454", "callsign": "WCBS", "name": "WCBS-DT", "affiliate": "CBS", "logo": {"URL": "https://example.com/wcbs.png", "width": 360, "height": 270}},
+		{"stationID": "10021", "callsign": "WNBC", "name": "WNBC-DT", "stationLogo": [{"URL": "https://example.com/wnbc.png", "width": 300, "height": 200}]}
+	],
+	"metadata": {"lineup": "USA-NY67791-X", "modulation": "Digital"}
+}`
+
+const testSchedules = `[
+	{"stationID": "20454", "programs": [
+		{"programID": "EP000000060003", "airDateTime": "2015-03-03T00:00:00Z", "duration": 1800, "new": true, "videoProperties": ["hdtv"]},
+		{"programID": "MV000000010000", "airDateTime": "2015-03-03T00:30:00Z", "duration": 7200}
+	]},
+	{"stationID": "10021", "programs": [
+		{"programID": "SH000000020000", "airDateTime": "2015-03-03T00:00:00Z", "duration": 3600, "liveTapeDelay": "Live"},
+		{"programID": "EP000000060003", "airDateTime": "2015-03-03T01:00:00Z", "duration": 1800}
+	]}
+]`
+
+const testPrograms = `[
+	{"programID": "EP000000060003", "titles": [{"title120": "'Allo 'Allo!"}], "episodeTitle150": "The Poloceman Cometh",
+		"descriptions": {"description100": [{"descriptionLanguage": "en", "description": "Short."}], "description1000": [{"descriptionLanguage": "en", "description": "A disguised British Intelligence officer is sent to help the airmen."}, {"descriptionLanguage": "fr", "description": "Un officier déguisé."}]},
+		"genres": ["Sitcom"], "showType": "Series", "originalAirDate": "1985-11-04",
+		"cast": [{"personId": "383774", "nameId": "392649", "name": "Gorden Kaye", "role": "Actor", "characterName": "René Artois", "billingOrder": "01"}]},
+	{"programID": "MV000000010000", "titles": [{"title120": "The Movie"}], "showType": "Feature Film",
+		"cast": [{"personId": "383774", "nameId": "392649", "name": "Gorden Kaye", "role": "Actor", "billingOrder": "03"}]},
+	{"programID": "SH000000020000", "titles": [{"title120": "Evening News"}], "showType": "Series"}
+]`
+
+func testGuide(t *testing.T) *Guide {
+	t.Helper()
+
+	lineup := &schedulesdirect.ChannelResponse{}
+	schedules := make([]schedulesdirect.Schedule, 0)
+	programs := make([]schedulesdirect.ProgramInfo, 0)
+	for data, value := range map[string]interface{}{testLineup: lineup, testSchedules: &schedules, testPrograms: &programs} {
+		if err := json.Unmarshal([]byte(data), value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return NewGuide([]*schedulesdirect.ChannelResponse{lineup, lineup}, schedules, programs)
+}
+
+func TestNewGuide(t *testing.T) {
+	guide := testGuide(t)
+
+	if assert.Len(t, guide.Channels, 2) {
+		assert.Equal(t, Channel{
+			StationID: "20454",
+			Number:    "2.1",
+			CallSign:  "WCBS",
+			Name:      "WCBS-DT",
+			Affiliate: "CBS",
+			LineupID:  "USA-NY67791-X",
+			Logo:      &schedulesdirect.StationLogo{URL: "https://example.com/wcbs.png", Width: 360, Height: 270},
+		}, guide.Channels[0])
+		assert.Equal(t, "4.1", guide.Channels[1].Number)
+		assert.Equal(t, "https://example.com/wnbc.png", guide.Channels[1].Logo.URL)
+	}
+	assert.Equal(t, "WNBC", guide.Channel("10021").CallSign)
+	assert.Nil(t, guide.Channel("99999"))
+
+	at := time.Date(2015, 3, 3, 0, 45, 0, 0, time.UTC)
+
+	now, ok := guide.OnNow("20454", at)
+	if assert.True(t, ok) {
+		assert.Equal(t, "MV000000010000", now.Program.ProgramID)
+	}
+	next, ok := guide.UpNext("10021", at)
+	if assert.True(t, ok) {
+		assert.Equal(t, "EP000000060003", next.Program.ProgramID)
+	}
+	_, ok = guide.UpNext("20454", at)
+	assert.False(t, ok)
+
+	assert.Len(t, guide.Between("20454", at, at.Add(time.Hour)), 1)
+	assert.Len(t, guide.Between("10021", at, at.Add(time.Hour)), 2)
+	assert.Len(t, guide.Upcoming("EP000000060003", at), 1)
+}
+
+func TestSyncGuide(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/20141201/lineups/USA-NY67791-X", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "true", r.Header.Get("verboseMap"))
+		fmt.Fprint(w, testLineup)
+	})
+	mux.HandleFunc("/20141201/schedules", func(w http.ResponseWriter, r *http.Request) {
+		requests := make([]schedulesdirect.StationScheduleRequest, 0)
+		if err := json.NewDecoder(r.Body).Decode(&requests); err != nil {
+			t.Fatal(err)
+		}
+		if assert.Len(t, requests, 2) {
+			assert.Equal(t, "20454", requests[0].StationID)
+			assert.Len(t, requests[0].Dates, 2)
+		}
+		fmt.Fprint(w, testSchedules)
+	})
+	mux.HandleFunc("/20141201/programs", func(w http.ResponseWriter, r *http.Request) {
+		ids := make([]string, 0)
+		if err := json.NewDecoder(r.Body).Decode(&ids); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, []string{"EP000000060003", "MV000000010000", "SH000000020000"}, ids)
+		fmt.Fprint(w, testPrograms)
+	})
+
+	client := &schedulesdirect.Client{
+		BaseURL:        fmt.Sprint(server.URL, "/"),
+		HTTP:           http.DefaultClient,
+		Token:          "d97c908ed44c25fdca302612c70584c8d5acd47a",
+		TokenExpiresAt: time.Now().Add(24 * time.Hour),
+	}
+
+	guide, err := SyncGuide(client, []string{"USA-NY67791-X"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, guide.Channels, 2)
+	assert.Len(t, guide.Airings, 4)
+	assert.Len(t, guide.Programs, 3)
+
+	_, err = SyncGuide(client, []string{"USA-NY67791-X"}, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,400 @@
+package guideapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	schedulesdirect "github.com/tellytv/go.schedulesdirect"
+)
+
+const (
+	// DefaultGridDuration is the time range of a grid request without an end.
+	DefaultGridDuration = 3 * time.Hour
+	// DefaultMaxGridDuration is the longest time range a grid request may ask for.
+	DefaultMaxGridDuration = 24 * time.Hour
+	// DefaultSearchLimit is the number of search results returned without a limit.
+	DefaultSearchLimit = 25
+)
+
+// An AiringView is an airing with its channel and program resolved.
+type AiringView struct {
+	ProgramID    string    `json:"programID"`
+	StationID    string    `json:"stationID"`
+	Channel      string    `json:"channel,omitempty"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	Title        string    `json:"title"`
+	EpisodeTitle string    `json:"episodeTitle,omitempty"`
+	Description  string    `json:"description,omitempty"`
+	New          bool      `json:"new,omitempty"`
+	Live         bool      `json:"live,omitempty"`
+	HD           bool      `json:"hd,omitempty"`
+}
+
+// A CreditView is a cast or crew member of a program.
+type CreditView struct {
+	PersonID      string `json:"personID,omitempty"`
+	Name          string `json:"name"`
+	Role          string `json:"role,omitempty"`
+	CharacterName string `json:"characterName,omitempty"`
+}
+
+// A ProgramView is a program with its title and description resolved.
+type ProgramView struct {
+	ProgramID       string                      `json:"programID"`
+	Title           string                      `json:"title"`
+	EpisodeTitle    string                      `json:"episodeTitle,omitempty"`
+	Description     string                      `json:"description,omitempty"`
+	ShowType        schedulesdirect.ShowSubType `json:"showType,omitempty"`
+	Genres          []string                    `json:"genres,omitempty"`
+	OriginalAirDate *schedulesdirect.Date       `json:"originalAirDate,omitempty"`
+	Cast            []CreditView                `json:"cast,omitempty"`
+	Crew            []CreditView                `json:"crew,omitempty"`
+	Upcoming        []AiringView                `json:"upcoming"`
+}
+
+// A Listing is what is on a channel.
+type Listing struct {
+	Channel Channel    `json:"channel"`
+	Airing  AiringView `json:"airing"`
+}
+
+// A GridRow is a channel and its airings in the time range of a grid.
+type GridRow struct {
+	Channel Channel      `json:"channel"`
+	Airings []AiringView `json:"airings"`
+}
+
+// A SearchResult is a program matching a search.
+type SearchResult struct {
+	Program ProgramView `json:"program"`
+	Score   float64     `json:"score"`
+}
+
+// A PersonCreditView is a role of a person in a program.
+type PersonCreditView struct {
+	ProgramID     string                     `json:"programID"`
+	Title         string                     `json:"title,omitempty"`
+	Type          schedulesdirect.CreditType `json:"type"`
+	Role          string                     `json:"role,omitempty"`
+	CharacterName string                     `json:"characterName,omitempty"`
+}
+
+// A PersonView is a person, their credits and the upcoming airings they appear in.
+type PersonView struct {
+	PersonID string             `json:"personID"`
+	Name     string             `json:"name"`
+	Credits  []PersonCreditView `json:"credits"`
+	Upcoming []AiringView       `json:"upcoming"`
+}
+
+// A Server serves a Guide as JSON. It is safe for concurrent use and the guide may be replaced while serving.
+//
+// The following GET endpoints are served:
+//
+//	/channels                  every channel of the guide
+//	/now?at=                   what is on each channel at the given time, now by default
+//	/next?at=                  what starts next on each channel
+//	/grid?start=&end=&channel= the airings of each (or the given) channels overlapping the time range
+//	/programs/{id}             a program and its upcoming airings
+//	/search?q=&limit=&fuzzy=   programs matching the query, best first
+//	/people/{id}               a person's credits and upcoming airings
+//
+// Times may be given in RFC 3339 format or as Unix timestamps.
+type Server struct {
+	// Languages are the preferred languages of descriptions. English, then any language, is used if none match.
+	Languages []string
+	// MaxGridDuration is the longest time range a grid request may ask for, DefaultMaxGridDuration if zero.
+	MaxGridDuration time.Duration
+
+	mu    sync.RWMutex
+	guide *Guide
+	now   func() time.Time
+}
+
+// NewServer returns a Server serving the guide.
+func NewServer(guide *Guide) *Server {
+	return &Server{guide: guide, now: time.Now}
+}
+
+// SetGuide replaces the served guide, e.g. after syncing it again.
+func (s *Server) SetGuide(guide *Guide) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.guide = guide
+}
+
+// Guide returns the served guide.
+func (s *Server) Guide() *Guide {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.guide
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	guide := s.Guide()
+	if guide == nil {
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("guide has not been synced yet"))
+		return
+	}
+
+	path := strings.Trim(r.URL.Path, "/")
+	switch {
+	case path == "channels":
+		writeJSON(w, guide.Channels)
+	case path == "now":
+		s.serveNow(w, r, guide, false)
+	case path == "next":
+		s.serveNow(w, r, guide, true)
+	case path == "grid":
+		s.serveGrid(w, r, guide)
+	case path == "search":
+		s.serveSearch(w, r, guide)
+	case strings.HasPrefix(path, "programs/"):
+		s.serveProgram(w, guide, strings.TrimPrefix(path, "programs/"))
+	case strings.HasPrefix(path, "people/"):
+		s.servePerson(w, guide, strings.TrimPrefix(path, "people/"))
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such endpoint /%s", path))
+	}
+}
+
+func (s *Server) serveNow(w http.ResponseWriter, r *http.Request, guide *Guide, next bool) {
+	at, err := parseTimeParam(r, "at", s.now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	listings := make([]Listing, 0, len(guide.Channels))
+	for _, channel := range guide.Channels {
+		var airing schedulesdirect.Airing
+		var ok bool
+		if next {
+			airing, ok = guide.UpNext(channel.StationID, at)
+		} else {
+			airing, ok = guide.OnNow(channel.StationID, at)
+		}
+		if ok {
+			listings = append(listings, Listing{Channel: channel, Airing: s.airingView(guide, airing)})
+		}
+	}
+	writeJSON(w, listings)
+}
+
+func (s *Server) serveGrid(w http.ResponseWriter, r *http.Request, guide *Guide) {
+	start, startErr := parseTimeParam(r, "start", s.now())
+	if startErr != nil {
+		writeError(w, http.StatusBadRequest, startErr)
+		return
+	}
+	end, endErr := parseTimeParam(r, "end", start.Add(DefaultGridDuration))
+	if endErr != nil {
+		writeError(w, http.StatusBadRequest, endErr)
+		return
+	}
+
+	maxDuration := s.MaxGridDuration
+	if maxDuration == 0 {
+		maxDuration = DefaultMaxGridDuration
+	}
+	if !end.After(start) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("grid end %s is not after its start %s", end.Format(time.RFC3339), start.Format(time.RFC3339)))
+		return
+	}
+	if end.Sub(start) > maxDuration {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("grid time range is longer than %s", maxDuration))
+		return
+	}
+
+	channels := guide.Channels
+	if stationIDs := r.URL.Query()["channel"]; len(stationIDs) > 0 {
+		channels = make([]Channel, 0, len(stationIDs))
+		for _, stationID := range stationIDs {
+			channel := guide.Channel(stationID)
+			if channel == nil {
+				writeError(w, http.StatusNotFound, fmt.Errorf("no such channel %s", stationID))
+				return
+			}
+			channels = append(channels, *channel)
+		}
+	}
+
+	rows := make([]GridRow, 0, len(channels))
+	for _, channel := range channels {
+		rows = append(rows, GridRow{Channel: channel, Airings: s.airingViews(guide, guide.Between(channel.StationID, start, end))})
+	}
+	writeJSON(w, rows)
+}
+
+func (s *Server) serveSearch(w http.ResponseWriter, r *http.Request, guide *Guide) {
+	query := r.URL.Query()
+
+	if strings.TrimSpace(query.Get("q")) == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("missing search query q"))
+		return
+	}
+
+	opts := schedulesdirect.SearchOptions{Prefix: true, Limit: DefaultSearchLimit, UpcomingAfter: s.now()}
+	for param, value := range map[string]*int{"limit": &opts.Limit, "fuzzy": &opts.Fuzziness} {
+		if raw := query.Get(param); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed < 0 {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid %s %q", param, raw))
+				return
+			}
+			*value = parsed
+		}
+	}
+
+	results := make([]SearchResult, 0)
+	for _, hit := range guide.search.Search(query.Get("q"), opts) {
+		program := s.programView(guide, hit.Program)
+		program.Upcoming = s.airingViews(guide, hit.Airings)
+		results = append(results, SearchResult{Program: program, Score: hit.Score})
+	}
+	writeJSON(w, results)
+}
+
+func (s *Server) serveProgram(w http.ResponseWriter, guide *Guide, programID string) {
+	program := guide.Programs[programID]
+	if program == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such program %s", programID))
+		return
+	}
+
+	view := s.programView(guide, program)
+	view.Upcoming = s.airingViews(guide, guide.Upcoming(programID, s.now()))
+	writeJSON(w, view)
+}
+
+func (s *Server) servePerson(w http.ResponseWriter, guide *Guide, personID string) {
+	filmography := guide.people.Filmography(personID)
+	if filmography == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such person %s", personID))
+		return
+	}
+
+	view := PersonView{
+		PersonID: filmography.PersonID,
+		Name:     filmography.Name,
+		Credits:  make([]PersonCreditView, 0, len(filmography.Credits)),
+		Upcoming: s.airingViews(guide, guide.people.UpcomingAirings(personID, s.now())),
+	}
+	for _, credit := range filmography.Credits {
+		title := ""
+		if credit.Program != nil {
+			title = credit.Program.Title()
+		}
+		view.Credits = append(view.Credits, PersonCreditView{
+			ProgramID:     credit.ProgramID,
+			Title:         title,
+			Type:          credit.Type,
+			Role:          credit.Role,
+			CharacterName: credit.CharacterName,
+		})
+	}
+	writeJSON(w, view)
+}
+
+func (s *Server) airingView(guide *Guide, airing schedulesdirect.Airing) AiringView {
+	view := AiringView{
+		ProgramID: airing.Program.ProgramID,
+		StationID: airing.StationID,
+		Start:     airing.Start(),
+		End:       airing.End(),
+		New:       airing.Program.New,
+		Live:      airing.Program.IsLive(),
+		HD:        airing.Program.IsHD(),
+	}
+	if channel := guide.Channel(airing.StationID); channel != nil {
+		view.Channel = channel.Number
+	}
+
+	info := airing.Info
+	if info == nil {
+		info = guide.Programs[airing.Program.ProgramID]
+	}
+	if info != nil {
+		view.Title = info.Title()
+		view.EpisodeTitle = info.EpisodeTitle150
+		view.Description = info.Description(s.Languages...)
+	}
+	return view
+}
+
+func (s *Server) airingViews(guide *Guide, airings []schedulesdirect.Airing) []AiringView {
+	views := make([]AiringView, 0, len(airings))
+	for _, airing := range airings {
+		views = append(views, s.airingView(guide, airing))
+	}
+	return views
+}
+
+func (s *Server) programView(guide *Guide, program *schedulesdirect.ProgramInfo) ProgramView {
+	credits := func(people []schedulesdirect.Person) []CreditView {
+		views := make([]CreditView, 0, len(people))
+		for _, person := range people {
+			views = append(views, CreditView{PersonID: person.PersonID, Name: person.Name, Role: person.Role, CharacterName: person.CharacterName})
+		}
+		return views
+	}
+
+	return ProgramView{
+		ProgramID:       program.ProgramID,
+		Title:           program.Title(),
+		EpisodeTitle:    program.EpisodeTitle150,
+		Description:     program.Description(s.Languages...),
+		ShowType:        program.ShowType,
+		Genres:          program.Genres,
+		OriginalAirDate: program.OriginalAirDate,
+		Cast:            credits(program.Cast),
+		Crew:            credits(program.Crew),
+		Upcoming:        make([]AiringView, 0),
+	}
+}
+
+// parseTimeParam parses an RFC 3339 time or Unix timestamp from the query, returning fallback if it is missing.
+func parseTimeParam(r *http.Request, param string, fallback time.Time) (time.Time, error) {
+	raw := r.URL.Query().Get(param)
+	if raw == "" {
+		return fallback, nil
+	}
+	if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q, expected an RFC 3339 time or Unix timestamp", param, raw)
+	}
+	return parsed, nil
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
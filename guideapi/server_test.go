@@ -0,0 +1,164 @@
+package guideapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testServer(t *testing.T) *Server {
+	t.Helper()
+
+	server := NewServer(testGuide(t))
+	server.now = func() time.Time { return time.Date(2015, 3, 3, 0, 45, 0, 0, time.UTC) }
+	return server
+}
+
+// get requests the path from the server and decodes the JSON response into value.
+func get(t *testing.T, server *Server, path string, value interface{}) int {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	if err := json.Unmarshal(recorder.Body.Bytes(), value); err != nil {
+		t.Fatalf("error decoding response of %s: %s", path, err)
+	}
+	return recorder.Code
+}
+
+func TestServerChannels(t *testing.T) {
+	channels := make([]Channel, 0)
+	assert.Equal(t, http.StatusOK, get(t, testServer(t), "/channels", &channels))
+	if assert.Len(t, channels, 2) {
+		assert.Equal(t, "2.1", channels[0].Number)
+		assert.Equal(t, "https://example.com/wcbs.png", channels[0].Logo.URL)
+	}
+}
+
+func TestServerNowAndNext(t *testing.T) {
+	server := testServer(t)
+
+	listings := make([]Listing, 0)
+	assert.Equal(t, http.StatusOK, get(t, server, "/now", &listings))
+	if assert.Len(t, listings, 2) {
+		assert.Equal(t, "The Movie", listings[0].Airing.Title)
+		assert.Equal(t, "2.1", listings[0].Airing.Channel)
+		assert.Equal(t, "Evening News", listings[1].Airing.Title)
+		assert.True(t, listings[1].Airing.Live)
+	}
+
+	assert.Equal(t, http.StatusOK, get(t, server, "/now?at=2015-03-03T00:10:00Z", &listings))
+	if assert.Len(t, listings, 2) {
+		airing := listings[0].Airing
+		assert.Equal(t, "'Allo 'Allo!", airing.Title)
+		assert.Equal(t, "The Poloceman Cometh", airing.EpisodeTitle)
+		assert.Equal(t, "A disguised British Intelligence officer is sent to help the airmen.", airing.Description)
+		assert.True(t, airing.New)
+		assert.True(t, airing.HD)
+		assert.Equal(t, time.Date(2015, 3, 3, 0, 30, 0, 0, time.UTC), airing.End)
+	}
+
+	assert.Equal(t, http.StatusOK, get(t, server, "/next", &listings))
+	if assert.Len(t, listings, 1) {
+		assert.Equal(t, "10021", listings[0].Channel.StationID)
+		assert.Equal(t, "4.1", listings[0].Airing.Channel)
+	}
+
+	server.Languages = []string{"fr"}
+	assert.Equal(t, http.StatusOK, get(t, server, "/next", &listings))
+	assert.Equal(t, "Un officier déguisé.", listings[0].Airing.Description)
+
+	errResponse := map[string]string{}
+	assert.Equal(t, http.StatusBadRequest, get(t, server, "/now?at=yesterday", &errResponse))
+	assert.Contains(t, errResponse["error"], "invalid at")
+}
+
+func TestServerGrid(t *testing.T) {
+	server := testServer(t)
+
+	rows := make([]GridRow, 0)
+	assert.Equal(t, http.StatusOK, get(t, server, "/grid?start=1425340800&end=2015-03-03T01:00:00Z", &rows))
+	if assert.Len(t, rows, 2) {
+		assert.Len(t, rows[0].Airings, 2)
+		assert.Len(t, rows[1].Airings, 1)
+	}
+
+	assert.Equal(t, http.StatusOK, get(t, server, "/grid/?channel=10021", &rows))
+	if assert.Len(t, rows, 1) {
+		assert.Equal(t, "WNBC", rows[0].Channel.CallSign)
+		assert.Len(t, rows[0].Airings, 2)
+	}
+
+	errResponse := map[string]string{}
+	assert.Equal(t, http.StatusNotFound, get(t, server, "/grid?channel=99999", &errResponse))
+	assert.Equal(t, http.StatusBadRequest, get(t, server, "/grid?start=2015-03-03T01:00:00Z&end=2015-03-03T00:00:00Z", &errResponse))
+	assert.Equal(t, http.StatusBadRequest, get(t, server, "/grid?start=2015-03-03T00:00:00Z&end=2015-03-05T00:00:00Z", &errResponse))
+}
+
+func TestServerPrograms(t *testing.T) {
+	server := testServer(t)
+
+	program := ProgramView{}
+	assert.Equal(t, http.StatusOK, get(t, server, "/programs/EP000000060003", &program))
+	assert.Equal(t, "'Allo 'Allo!", program.Title)
+	assert.Equal(t, []string{"Sitcom"}, program.Genres)
+	if assert.Len(t, program.Cast, 1) {
+		assert.Equal(t, "René Artois", program.Cast[0].CharacterName)
+	}
+	if assert.Len(t, program.Upcoming, 1) {
+		assert.Equal(t, "10021", program.Upcoming[0].StationID)
+	}
+
+	errResponse := map[string]string{}
+	assert.Equal(t, http.StatusNotFound, get(t, server, "/programs/EP999999999999", &errResponse))
+}
+
+func TestServerSearch(t *testing.T) {
+	server := testServer(t)
+
+	results := make([]SearchResult, 0)
+	assert.Equal(t, http.StatusOK, get(t, server, "/search?q=allo", &results))
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "EP000000060003", results[0].Program.ProgramID)
+		assert.Len(t, results[0].Program.Upcoming, 1)
+	}
+
+	assert.Equal(t, http.StatusOK, get(t, server, "/search?q=kaye&limit=1", &results))
+	assert.Len(t, results, 1)
+
+	errResponse := map[string]string{}
+	assert.Equal(t, http.StatusBadRequest, get(t, server, "/search", &errResponse))
+	assert.Equal(t, http.StatusBadRequest, get(t, server, "/search?q=allo&limit=-1", &errResponse))
+}
+
+func TestServerPeople(t *testing.T) {
+	server := testServer(t)
+
+	person := PersonView{}
+	assert.Equal(t, http.StatusOK, get(t, server, "/people/383774", &person))
+	assert.Equal(t, "Gorden Kaye", person.Name)
+	assert.Len(t, person.Credits, 2)
+	assert.Len(t, person.Upcoming, 2)
+
+	errResponse := map[string]string{}
+	assert.Equal(t, http.StatusNotFound, get(t, server, "/people/1", &errResponse))
+}
+
+func TestServerErrors(t *testing.T) {
+	server := testServer(t)
+
+	errResponse := map[string]string{}
+	assert.Equal(t, http.StatusNotFound, get(t, server, "/unknown", &errResponse))
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/channels", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+
+	server.SetGuide(nil)
+	assert.Equal(t, http.StatusServiceUnavailable, get(t, server, "/channels", &errResponse))
+}
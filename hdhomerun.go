@@ -0,0 +1,297 @@
+package schedulesdirect
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A HDHomeRunChannel is a single channel from the "lineup.json" output of SiliconDust's HDHomeRun devices.
+type HDHomeRunChannel struct {
+	GuideNumber string `json:"GuideNumber"`
+	GuideName   string `json:"GuideName"`
+	VideoCodec  string `json:"VideoCodec,omitempty"`
+	AudioCodec  string `json:"AudioCodec,omitempty"`
+	HD          int    `json:"HD,omitempty"`
+	Favorite    int    `json:"Favorite,omitempty"`
+	DRM         int    `json:"DRM,omitempty"`
+	Tags        string `json:"Tags,omitempty"`
+	URL         string `json:"URL,omitempty"`
+}
+
+// IsHD returns true if the device reports the channel as high definition.
+func (h HDHomeRunChannel) IsHD() bool {
+	return h.HD == 1
+}
+
+// IsFavorite returns true if the channel has been marked as a favorite on the device.
+func (h HDHomeRunChannel) IsFavorite() bool {
+	return h.Favorite == 1
+}
+
+// IsDRM returns true if the channel is copy protected.
+func (h HDHomeRunChannel) IsDRM() bool {
+	return h.DRM == 1
+}
+
+// A HDHomeRunLineup is the full "lineup.json" output of a HDHomeRun device.
+type HDHomeRunLineup []HDHomeRunChannel
+
+// ParseHDHomeRunLineup parses the "lineup.json" output of a HDHomeRun device.
+func ParseHDHomeRunLineup(data []byte) (HDHomeRunLineup, error) {
+	lineup := make(HDHomeRunLineup, 0)
+	if err := json.Unmarshal(data, &lineup); err != nil {
+		return nil, err
+	}
+	return lineup, nil
+}
+
+// AutomapHDHomeRunLineup is a typed wrapper around AutomapLineup.
+// It returns the potential lineup matches ranked by their score, best first.
+func (c *Client) AutomapHDHomeRunLineup(lineup HDHomeRunLineup) ([]AutomapCandidate, error) {
+	js, jsErr := json.Marshal(lineup)
+	if jsErr != nil {
+		return nil, jsErr
+	}
+
+	matches, err := c.AutomapLineup(js)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]AutomapCandidate, 0, len(matches))
+	for lineupID, score := range matches {
+		candidates = append(candidates, AutomapCandidate{LineupID: lineupID, Score: score})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].LineupID < candidates[j].LineupID
+	})
+
+	return candidates, nil
+}
+
+// SubmitHDHomeRunLineup is a typed wrapper around SubmitLineup.
+func (c *Client) SubmitHDHomeRunLineup(lineup HDHomeRunLineup, lineupID string) error {
+	js, jsErr := json.Marshal(lineup)
+	if jsErr != nil {
+		return jsErr
+	}
+
+	return c.SubmitLineup(js, lineupID)
+}
+
+// An AutomapCandidate is a lineup that Schedules Direct believes may match a HDHomeRun lineup.
+type AutomapCandidate struct {
+	LineupID string
+	// Score is the match score reported by Schedules Direct.
+	Score int
+
+	// Preview, Matched and Unmatched are only filled once the candidate has been previewed.
+	Preview   []StationPreview
+	Matched   []HDHomeRunChannel
+	Unmatched []HDHomeRunChannel
+}
+
+// MatchRatio returns the fraction of HDHomeRun channels found in the candidate preview.
+func (a AutomapCandidate) MatchRatio() float64 {
+	total := len(a.Matched) + len(a.Unmatched)
+	if total == 0 {
+		return 0
+	}
+	return float64(len(a.Matched)) / float64(total)
+}
+
+// A HDHomeRunAutomapResult is the outcome of AutomapHDHomeRun.
+type HDHomeRunAutomapResult struct {
+	Lineup HDHomeRunLineup
+	// Candidates are ordered best match first.
+	Candidates []AutomapCandidate
+}
+
+// Best returns the best candidate, or nil if there are none.
+func (r *HDHomeRunAutomapResult) Best() *AutomapCandidate {
+	if len(r.Candidates) == 0 {
+		return nil
+	}
+	return &r.Candidates[0]
+}
+
+// NeedsSubmission returns true if no candidate matched any of the HDHomeRun channels.
+// In that case, once you have identified the lineup yourself, SubmitHDHomeRunLineup
+// should be called so Schedules Direct can improve its automapping.
+func (r *HDHomeRunAutomapResult) NeedsSubmission() bool {
+	best := r.Best()
+	return best == nil || len(best.Matched) == 0
+}
+
+// AutomapHDHomeRun runs the automap for the given HDHomeRun lineup, previews the candidates
+// and matches the HDHomeRun channels against each preview.
+//
+// Only the previewLimit best scored candidates are previewed, all of them if previewLimit is 0.
+// The candidates are then ordered by the number of channels matched, then by score.
+func (c *Client) AutomapHDHomeRun(lineup HDHomeRunLineup, previewLimit int) (*HDHomeRunAutomapResult, error) {
+	candidates, err := c.AutomapHDHomeRunLineup(lineup)
+	if err != nil {
+		return nil, err
+	}
+
+	if previewLimit > 0 && len(candidates) > previewLimit {
+		candidates = candidates[:previewLimit]
+	}
+
+	for idx := range candidates {
+		candidate := &candidates[idx]
+
+		preview, previewErr := c.PreviewLineup(candidate.LineupID)
+		if previewErr != nil {
+			return nil, fmt.Errorf("error previewing automap candidate %s: %s", candidate.LineupID, previewErr)
+		}
+
+		candidate.Preview = preview
+		candidate.Matched, candidate.Unmatched = matchHDHomeRunPreview(lineup, preview)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if len(candidates[i].Matched) != len(candidates[j].Matched) {
+			return len(candidates[i].Matched) > len(candidates[j].Matched)
+		}
+		return candidates[i].Score > candidates[j].Score
+	})
+
+	return &HDHomeRunAutomapResult{Lineup: lineup, Candidates: candidates}, nil
+}
+
+// matchHDHomeRunPreview splits the lineup into channels which are and are not in the preview.
+func matchHDHomeRunPreview(lineup HDHomeRunLineup, preview []StationPreview) ([]HDHomeRunChannel, []HDHomeRunChannel) {
+	byChannel := make(map[string]bool)
+	byCallSign := make(map[string]bool)
+	for _, station := range preview {
+		if number := normalizeChannelNumber(station.Channel); number != "" {
+			byChannel[number] = true
+		}
+		if callSign := normalizeCallSign(station.CallSign); callSign != "" {
+			byCallSign[callSign] = true
+		}
+	}
+
+	matched := make([]HDHomeRunChannel, 0)
+	unmatched := make([]HDHomeRunChannel, 0)
+	for _, channel := range lineup {
+		number, callSign := normalizeChannelNumber(channel.GuideNumber), normalizeCallSign(channel.GuideName)
+		if (number != "" && byChannel[number]) || (callSign != "" && byCallSign[callSign]) {
+			matched = append(matched, channel)
+		} else {
+			unmatched = append(unmatched, channel)
+		}
+	}
+	return matched, unmatched
+}
+
+// A HDHomeRunChannelMatch pairs a HDHomeRun channel with its Schedules Direct channel map entry.
+type HDHomeRunChannelMatch struct {
+	Channel    HDHomeRunChannel
+	ChannelMap ChannelMap
+	// Station is the station the ChannelMap entry points at, if it was part of the response.
+	Station *Station
+}
+
+// MatchHDHomeRunChannels matches the channels of a HDHomeRun lineup with the channel map
+// of a lineup on the account, as returned by GetChannels. Channels are matched by number first
+// and by call sign second, ignoring suffixes such as "-HD" or "-DT2". HDHomeRun channels which could not be matched are returned separately.
+func MatchHDHomeRunChannels(lineup HDHomeRunLineup, channels *ChannelResponse) ([]HDHomeRunChannelMatch, []HDHomeRunChannel) {
+	stations := make(map[string]*Station)
+	for idx := range channels.Stations {
+		stations[channels.Stations[idx].StationID] = &channels.Stations[idx]
+	}
+
+	byChannel := make(map[string]ChannelMap)
+	byCallSign := make(map[string]ChannelMap)
+	for _, entry := range channels.Map {
+		for _, number := range channelMapNumbers(entry) {
+			if _, ok := byChannel[number]; !ok && number != "" {
+				byChannel[number] = entry
+			}
+		}
+		if station, ok := stations[entry.StationID]; ok {
+			if callSign := normalizeCallSign(station.CallSign); callSign != "" {
+				byCallSign[callSign] = entry
+			}
+		}
+	}
+
+	matched := make([]HDHomeRunChannelMatch, 0)
+	unmatched := make([]HDHomeRunChannel, 0)
+	for _, channel := range lineup {
+		var entry ChannelMap
+		ok := false
+		if number := normalizeChannelNumber(channel.GuideNumber); number != "" {
+			entry, ok = byChannel[number]
+		}
+		if callSign := normalizeCallSign(channel.GuideName); !ok && callSign != "" {
+			entry, ok = byCallSign[callSign]
+		}
+		if !ok {
+			unmatched = append(unmatched, channel)
+			continue
+		}
+		matched = append(matched, HDHomeRunChannelMatch{
+			Channel:    channel,
+			ChannelMap: entry,
+			Station:    stations[entry.StationID],
+		})
+	}
+	return matched, unmatched
+}
+
+// channelMapNumbers returns every normalized channel number a ChannelMap entry may be known by.
+func channelMapNumbers(entry ChannelMap) []string {
+	numbers := make([]string, 0)
+	for _, number := range []string{entry.Channel, entry.VirtualChannel, entry.LogicalChannelNumber} {
+		if number != "" {
+			numbers = append(numbers, normalizeChannelNumber(number))
+		}
+	}
+	if entry.ChannelMajor > 0 {
+		numbers = append(numbers, normalizeChannelNumber(fmt.Sprintf("%d.%d", entry.ChannelMajor, entry.ChannelMinor)))
+	}
+	return numbers
+}
+
+// normalizeChannelNumber makes "2-1", "2.1" and "002.01" compare equal and strips a trailing ".0".
+func normalizeChannelNumber(number string) string {
+	number = strings.Replace(strings.TrimSpace(number), "-", ".", -1)
+	parts := strings.Split(number, ".")
+	for idx, part := range parts {
+		trimmed := strings.TrimLeft(part, "0")
+		if trimmed == "" && part != "" {
+			trimmed = "0"
+		}
+		parts[idx] = trimmed
+	}
+	if len(parts) == 2 && parts[1] == "0" {
+		parts = parts[:1]
+	}
+	return strings.Join(parts, ".")
+}
+
+// normalizeCallSign makes "WCBS", "wcbs-hd", "WCBS-DT2" and "WCBSDT" compare equal by upper casing,
+// dropping everything after a separator and stripping a trailing HD, DT or SD with its subchannel number.
+func normalizeCallSign(callSign string) string {
+	callSign = strings.ToUpper(strings.TrimSpace(callSign))
+	if idx := strings.IndexAny(callSign, "-_. "); idx >= 0 {
+		callSign = callSign[:idx]
+	}
+	base := strings.TrimRight(callSign, "0123456789")
+	for _, suffix := range []string{"HD", "DT", "SD"} {
+		// Keep at least a three letter call sign, e.g. for "WHD".
+		if strings.HasSuffix(base, suffix) && len(base)-len(suffix) >= 3 {
+			return base[:len(base)-len(suffix)]
+		}
+	}
+	return callSign
+}
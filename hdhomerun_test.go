@@ -0,0 +1,158 @@
+package schedulesdirect
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testHDHomeRunLineup = `[{"GuideNumber":"2.1","GuideName":"WCBS-HD","VideoCodec":"MPEG2","AudioCodec":"AC3","HD":1,"URL":"http://192.168.1.2:5004/auto/v2.1"},{"GuideNumber":"4.1","GuideName":"WNBC-HD","HD":1,"Favorite":1,"URL":"http://192.168.1.2:5004/auto/v4.1"},{"GuideNumber":"99.1","GuideName":"MYSTERY","URL":"http://192.168.1.2:5004/auto/v99.1"}]`
+
+func TestHDHomeRunLineupEquality(t *testing.T) {
+	lineup, err := ParseHDHomeRunLineup([]byte(testHDHomeRunLineup))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, lineup, 3)
+	assert.True(t, lineup[0].IsHD())
+	assert.True(t, lineup[1].IsFavorite())
+	assert.False(t, lineup[2].IsHD())
+
+	marshalled, marshalErr := json.Marshal(lineup)
+	if marshalErr != nil {
+		t.Fatal(marshalErr)
+	}
+
+	assert.JSONEq(t, testHDHomeRunLineup, string(marshalled))
+}
+
+func TestAutomapHDHomeRunOK(t *testing.T) {
+	mux, client := setup()
+
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/map/lineup"),
+		func(w http.ResponseWriter, r *http.Request) {
+			ensureMethod(t, r, "POST")
+			ensureHeader(t, r, "token", "d97c908ed44c25fdca302612c70584c8d5acd47a")
+
+			fmt.Fprint(w, `{"USA-NY31519-X":2,"USA-OTA-10001":1}`)
+		},
+	)
+
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/lineups/preview/USA-NY31519-X"),
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `[{"channel":"002","callsign":"WCBS"}]`)
+		},
+	)
+
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/lineups/preview/USA-OTA-10001"),
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `[{"channel":"2-1","callsign":"WCBS"},{"channel":"4-1","callsign":"WNBC"}]`)
+		},
+	)
+
+	lineup, _ := ParseHDHomeRunLineup([]byte(testHDHomeRunLineup))
+
+	result, err := client.AutomapHDHomeRun(lineup, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, result.Candidates, 2) {
+		assert.Equal(t, "USA-OTA-10001", result.Best().LineupID)
+		assert.Len(t, result.Best().Matched, 2)
+		assert.Equal(t, "99.1", result.Best().Unmatched[0].GuideNumber)
+		assert.Equal(t, 2, result.Candidates[1].Score)
+		assert.Len(t, result.Candidates[1].Matched, 1, "WCBS-HD matches WCBS by call sign")
+	}
+	assert.False(t, result.NeedsSubmission())
+}
+
+func TestAutomapHDHomeRunNoCandidates(t *testing.T) {
+	mux, client := setup()
+
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/map/lineup"),
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{}`)
+		},
+	)
+
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/map/lineup/USA-OTA-10001"),
+		func(w http.ResponseWriter, r *http.Request) {
+			ensureMethod(t, r, "POST")
+			ensurePayload(t, r, []byte(`[{"GuideNumber":"2.1","GuideName":"WCBS-HD"}]`))
+			fmt.Fprint(w, getBaseResponse(ErrOK))
+		},
+	)
+
+	lineup := HDHomeRunLineup{{GuideNumber: "2.1", GuideName: "WCBS-HD"}}
+
+	result, err := client.AutomapHDHomeRun(lineup, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Nil(t, result.Best())
+	assert.True(t, result.NeedsSubmission())
+	assert.NoError(t, client.SubmitHDHomeRunLineup(lineup, "USA-OTA-10001"))
+}
+
+func TestMatchHDHomeRunChannels(t *testing.T) {
+	lineup, _ := ParseHDHomeRunLineup([]byte(testHDHomeRunLineup))
+
+	channels := &ChannelResponse{
+		Map: []ChannelMap{
+			{StationID: "10001", ChannelMajor: 2, ChannelMinor: 1},
+			{StationID: "10002", Channel: "44"},
+		},
+		Stations: []Station{
+			{StationID: "10001", CallSign: "WCBS"},
+			{StationID: "10002", CallSign: "WNBC"},
+		},
+	}
+
+	matched, unmatched := MatchHDHomeRunChannels(lineup, channels)
+
+	if assert.Len(t, matched, 2) {
+		assert.Equal(t, "10001", matched[0].ChannelMap.StationID)
+		assert.Equal(t, "WCBS", matched[0].Station.CallSign)
+		assert.Equal(t, "10002", matched[1].ChannelMap.StationID)
+	}
+	if assert.Len(t, unmatched, 1) {
+		assert.Equal(t, "MYSTERY", unmatched[0].GuideName)
+	}
+}
+
+func TestMatchHDHomeRunChannelsEmpty(t *testing.T) {
+	lineup := HDHomeRunLineup{{GuideNumber: "", GuideName: ""}, {GuideNumber: "5.1", GuideName: "-HD"}}
+	channels := &ChannelResponse{
+		Map:      []ChannelMap{{StationID: "10001"}},
+		Stations: []Station{{StationID: "10001"}},
+	}
+
+	matched, unmatched := MatchHDHomeRunChannels(lineup, channels)
+	assert.Empty(t, matched)
+	assert.Len(t, unmatched, 2)
+
+	previewMatched, _ := matchHDHomeRunPreview(lineup, []StationPreview{{}})
+	assert.Empty(t, previewMatched)
+}
+
+func TestNormalizeCallSign(t *testing.T) {
+	for _, callSign := range []string{"WCBS", "wcbs-hd", "WCBS-DT", "WCBS-DT2", "WCBSDT", "WCBS HD", "WCBS.2"} {
+		assert.Equal(t, "WCBS", normalizeCallSign(callSign), callSign)
+	}
+	assert.Equal(t, "KABC", normalizeCallSign("KABC-TV"))
+	assert.Equal(t, "WHD", normalizeCallSign("WHD"))
+	assert.Empty(t, normalizeCallSign("-HD"))
+}
+
+func TestNormalizeChannelNumber(t *testing.T) {
+	assert.Equal(t, "2.1", normalizeChannelNumber("2-1"))
+	assert.Equal(t, "2.1", normalizeChannelNumber("002.01"))
+	assert.Equal(t, "2", normalizeChannelNumber("2.0"))
+	assert.Equal(t, "0", normalizeChannelNumber("0"))
+}
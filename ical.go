@@ -0,0 +1,314 @@
+package schedulesdirect
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// icalTimeFormat is the UTC date-time format of RFC 5545.
+	icalTimeFormat = "20060102T150405Z"
+	// icalSequenceRetention is how long events are kept in ICalendarSequences after they started.
+	icalSequenceRetention = 7 * 24 * time.Hour
+)
+
+// An ICalendarSequence is the revision of an exported event.
+type ICalendarSequence struct {
+	// Fingerprint is the MD5 of the event's schedule MD5 and exported properties.
+	Fingerprint string `json:"fingerprint"`
+	// Sequence is the SEQUENCE of the event, incremented whenever the fingerprint changes.
+	Sequence int `json:"sequence"`
+	// Modified is the LAST-MODIFIED time of the event.
+	Modified time.Time `json:"modified"`
+	// Start is the start time of the airing the event was last written for.
+	Start time.Time `json:"start"`
+}
+
+// ICalendarSequences are the revisions of exported events keyed by UID.
+// Persist them between exports so calendar apps pick up changed events.
+// Events which started more than a week ago and are no longer exported are removed.
+type ICalendarSequences map[string]ICalendarSequence
+
+// An ICalendarWriter writes airings as an RFC 5545 calendar, e.g. to subscribe to upcoming airings of favorite shows.
+//
+// Each airing becomes a VEVENT whose UID is made of its station ID, program ID and start time,
+// e.g. "20454.EP000000060003.20150303T200000Z@schedulesdirect.org", so the same airing keeps its UID across exports.
+// With Sequences, an upcoming airing moved to another time keeps the UID of its original start time and gets a new
+// SEQUENCE, so calendar apps update the event instead of deleting it and adding a new one.
+type ICalendarWriter struct {
+	// Name is the X-WR-CALNAME of the calendar, shown by calendar apps as its title.
+	Name string
+	// Lineups are used to set the location of events to the channel number and call sign of their station.
+	Lineups []*ChannelResponse
+	// DescriptionLanguages are passed to ProgramInfo.Description to choose the description.
+	DescriptionLanguages []string
+	// Sequences are updated with the revision of each exported event if not nil.
+	// Without them every event is written with SEQUENCE 0.
+	Sequences ICalendarSequences
+	// Now returns the DTSTAMP of the events, time.Now if nil.
+	Now func() time.Time
+}
+
+// Write writes the airings as a calendar. Airings without a start time are skipped.
+func (c ICalendarWriter) Write(w io.Writer, airings []Airing) error {
+	now := time.Now
+	if c.Now != nil {
+		now = c.Now
+	}
+	stamp := now().UTC().Truncate(time.Second)
+
+	locations := make(map[string]string)
+	for _, lineup := range c.Lineups {
+		if lineup == nil {
+			continue
+		}
+		callSigns := make(map[string]string, len(lineup.Stations))
+		for _, station := range lineup.Stations {
+			callSigns[station.StationID] = station.CallSign
+		}
+		for _, entry := range lineup.Map {
+			if _, ok := locations[entry.StationID]; !ok {
+				locations[entry.StationID] = strings.TrimSpace(entry.Number() + " " + callSigns[entry.StationID])
+			}
+		}
+	}
+
+	buf := bufio.NewWriter(w)
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//tellytv//go.schedulesdirect//EN", "CALSCALE:GREGORIAN", "METHOD:PUBLISH"}
+	if c.Name != "" {
+		lines = append(lines, "X-WR-CALNAME:"+icalText(c.Name))
+	}
+
+	uids := c.uids(airings, stamp)
+	written := make(map[string]bool, len(uids))
+	for idx, airing := range airings {
+		if airing.Program.AirDateTime == nil {
+			continue
+		}
+
+		uid := uids[idx]
+		written[uid] = true
+		properties := c.eventProperties(airing, locations[airing.StationID])
+
+		fingerprint := md5.New()
+		_, _ = io.WriteString(fingerprint, airing.Program.MD5+"\n"+strings.Join(properties, "\n"))
+		sequence := c.sequence(uid, hex.EncodeToString(fingerprint.Sum(nil)), airing.Start(), stamp)
+
+		lines = append(lines, "BEGIN:VEVENT", "UID:"+uid, "DTSTAMP:"+stamp.Format(icalTimeFormat))
+		lines = append(lines, properties...)
+		lines = append(lines, fmt.Sprintf("SEQUENCE:%d", sequence.Sequence))
+		if !sequence.Modified.IsZero() {
+			lines = append(lines, "LAST-MODIFIED:"+sequence.Modified.UTC().Format(icalTimeFormat))
+		}
+		lines = append(lines, "TRANSP:TRANSPARENT", "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+	c.prune(written, stamp)
+
+	for _, line := range lines {
+		if _, err := buf.WriteString(icalFold(line) + "\r\n"); err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
+
+// eventProperties returns the properties of the event describing the airing, which make up its fingerprint.
+func (c ICalendarWriter) eventProperties(airing Airing, location string) []string {
+	properties := []string{
+		"DTSTART:" + airing.Start().UTC().Format(icalTimeFormat),
+		"DTEND:" + airing.End().UTC().Format(icalTimeFormat),
+	}
+
+	summary := airing.Title()
+	if summary == "" {
+		summary = airing.Program.ProgramID
+	}
+	details := make([]string, 0, 3)
+	if airing.Info != nil {
+		if airing.Info.EpisodeTitle150 != "" {
+			summary += " - " + airing.Info.EpisodeTitle150
+		}
+		if season, episode := airing.Info.SeasonEpisode(); episode > 0 {
+			details = append(details, fmt.Sprintf("S%02dE%02d", season, episode))
+		}
+	}
+	if airing.Program.New {
+		details = append(details, "New")
+	}
+	if airing.Program.IsLive() {
+		details = append(details, "Live")
+	}
+	properties = append(properties, "SUMMARY:"+icalText(summary))
+
+	if location != "" {
+		properties = append(properties, "LOCATION:"+icalText(location))
+	}
+
+	description := strings.Join(details, ", ")
+	if airing.Info != nil {
+		if text := airing.Info.Description(c.DescriptionLanguages...); text != "" {
+			description = strings.TrimSpace(description + "\n\n" + text)
+		}
+	}
+	if description != "" {
+		properties = append(properties, "DESCRIPTION:"+icalText(description))
+	}
+
+	if airing.Info != nil && len(airing.Info.Genres) > 0 {
+		categories := make([]string, 0, len(airing.Info.Genres))
+		for _, genre := range airing.Info.Genres {
+			categories = append(categories, icalText(genre))
+		}
+		properties = append(properties, "CATEGORIES:"+strings.Join(categories, ","))
+	}
+
+	return properties
+}
+
+// uids returns the UID of each airing with a start time, by index.
+//
+// Airings are matched to the events in Sequences of the same station and program, first by start time and then,
+// for airings moved to another time, to the upcoming events no airing starts at any more. Other airings get a new
+// UID made of their station ID, program ID and start time.
+func (c ICalendarWriter) uids(airings []Airing, now time.Time) map[int]string {
+	type event struct {
+		uid     string
+		start   time.Time
+		matched bool
+	}
+
+	events := make(map[string][]*event)
+	used := make(map[string]bool, len(c.Sequences))
+	for uid, sequence := range c.Sequences {
+		used[uid] = true
+		name := strings.TrimSuffix(uid, "@schedulesdirect.org")
+		dot := strings.LastIndex(name, ".")
+		if dot < 0 || name == uid {
+			continue
+		}
+		prefix := name[:dot]
+		events[prefix] = append(events[prefix], &event{uid: uid, start: sequence.Start})
+	}
+	for _, candidates := range events {
+		sort.Slice(candidates, func(i, j int) bool {
+			if !candidates[i].start.Equal(candidates[j].start) {
+				return candidates[i].start.Before(candidates[j].start)
+			}
+			return candidates[i].uid < candidates[j].uid
+		})
+	}
+
+	uids := make(map[int]string, len(airings))
+	order := make([]int, 0, len(airings))
+	for idx, airing := range airings {
+		if airing.Program.AirDateTime != nil {
+			order = append(order, idx)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool { return airingLess(airings[order[i]], airings[order[j]]) })
+
+	prefixOf := func(airing Airing) string {
+		return airing.StationID + "." + airing.Program.ProgramID
+	}
+
+	for _, idx := range order {
+		airing := airings[idx]
+		for _, candidate := range events[prefixOf(airing)] {
+			if !candidate.matched && candidate.start.Equal(airing.Start()) {
+				candidate.matched = true
+				uids[idx] = candidate.uid
+				break
+			}
+		}
+	}
+
+	for _, idx := range order {
+		if _, ok := uids[idx]; ok {
+			continue
+		}
+		airing := airings[idx]
+		prefix := prefixOf(airing)
+		for _, candidate := range events[prefix] {
+			if !candidate.matched && candidate.start.After(now) {
+				candidate.matched = true
+				uids[idx] = candidate.uid
+				break
+			}
+		}
+		if _, ok := uids[idx]; ok {
+			continue
+		}
+
+		// A moved event may have kept the UID made of this start time, e.g. when another airing takes its place.
+		name := prefix + "." + airing.Start().UTC().Format(icalTimeFormat)
+		uid := name + "@schedulesdirect.org"
+		for n := 1; used[uid]; n++ {
+			uid = fmt.Sprintf("%s-%d@schedulesdirect.org", name, n)
+		}
+		used[uid] = true
+		uids[idx] = uid
+	}
+
+	return uids
+}
+
+// prune removes the events of Sequences which were not written and started more than icalSequenceRetention ago.
+func (c ICalendarWriter) prune(written map[string]bool, now time.Time) {
+	for uid, sequence := range c.Sequences {
+		if !written[uid] && sequence.Start.Before(now.Add(-icalSequenceRetention)) {
+			delete(c.Sequences, uid)
+		}
+	}
+}
+
+// sequence returns the revision of the event, recording it in Sequences.
+func (c ICalendarWriter) sequence(uid, fingerprint string, start, now time.Time) ICalendarSequence {
+	if c.Sequences == nil {
+		return ICalendarSequence{Fingerprint: fingerprint, Start: start}
+	}
+
+	sequence, ok := c.Sequences[uid]
+	switch {
+	case !ok:
+		sequence = ICalendarSequence{Fingerprint: fingerprint, Modified: now}
+	case sequence.Fingerprint != fingerprint:
+		sequence = ICalendarSequence{Fingerprint: fingerprint, Sequence: sequence.Sequence + 1, Modified: now}
+	}
+	sequence.Start = start
+	c.Sequences[uid] = sequence
+	return sequence
+}
+
+// icalText escapes a TEXT value as defined in RFC 5545 section 3.3.11.
+func icalText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(text)
+}
+
+// icalFold folds a content line into lines of at most 75 octets, without splitting UTF-8 characters.
+func icalFold(line string) string {
+	if len(line) <= 75 {
+		return line
+	}
+
+	parts := make([]string, 0, len(line)/74+1)
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		parts = append(parts, line[:cut])
+		line = line[cut:]
+		// Continuation lines start with a space, which counts towards their length.
+		limit = 74
+	}
+	return strings.Join(append(parts, line), "\r\n ")
+}
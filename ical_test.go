@@ -0,0 +1,145 @@
+package schedulesdirect
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestICalendarWriter(t *testing.T) {
+	episode := testProgram("EP000000060003", "2015-03-03T20:00:00Z", 1800)
+	episode.New = true
+	episode.MD5 = "Sy8HEMBPcuiAx3FBukUhKQ"
+
+	airings := AiringsFromSchedules([]Schedule{
+		{StationID: "20454", Programs: []Program{episode, {ProgramID: "EP000000060004"}}},
+		{StationID: "10021", Programs: []Program{testProgram("SH000000070000", "2015-03-03T21:00:00Z", 3600)}},
+	}, IndexProgramInfo([]ProgramInfo{{
+		ProgramID:       "EP000000060003",
+		Titles:          []Title{{Title120: "'Allo 'Allo!"}},
+		EpisodeTitle150: "The Nicked Airmen",
+		Genres:          []string{"Sitcom", "Comedy, British"},
+		Metadata:        []map[string]Metadata{{"Gracenote": {Season: 2, Episode: 4}}},
+		Descriptions: map[string][]Description{
+			"description1000": {{Language: "en", Description: "René hides two British airmen; the Germans search the café, the cellar and the upstairs rooms."}},
+		},
+	}}))
+
+	sequences := ICalendarSequences{}
+	writer := ICalendarWriter{
+		Name:      "My shows",
+		Lineups:   []*ChannelResponse{testM3ULineup(t), nil},
+		Sequences: sequences,
+		Now:       func() time.Time { return time.Date(2015, 3, 1, 12, 0, 0, 0, time.UTC) },
+	}
+
+	buf := &bytes.Buffer{}
+	if err := writer.Write(buf, airings); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, strings.Replace(`BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//tellytv//go.schedulesdirect//EN
+CALSCALE:GREGORIAN
+METHOD:PUBLISH
+X-WR-CALNAME:My shows
+BEGIN:VEVENT
+UID:20454.EP000000060003.20150303T200000Z@schedulesdirect.org
+DTSTAMP:20150301T120000Z
+DTSTART:20150303T200000Z
+DTEND:20150303T203000Z
+SUMMARY:'Allo 'Allo! - The Nicked Airmen
+LOCATION:2.1 WCBS
+DESCRIPTION:S02E04\, New\n\nRené hides two British airmen\; the Germans se
+ arch the café\, the cellar and the upstairs rooms.
+CATEGORIES:Sitcom,Comedy\, British
+SEQUENCE:0
+LAST-MODIFIED:20150301T120000Z
+TRANSP:TRANSPARENT
+END:VEVENT
+BEGIN:VEVENT
+UID:10021.SH000000070000.20150303T210000Z@schedulesdirect.org
+DTSTAMP:20150301T120000Z
+DTSTART:20150303T210000Z
+DTEND:20150303T220000Z
+SUMMARY:SH000000070000
+LOCATION:4.1 WNBC
+SEQUENCE:0
+LAST-MODIFIED:20150301T120000Z
+TRANSP:TRANSPARENT
+END:VEVENT
+END:VCALENDAR
+`, "\n", "\r\n", -1), buf.String())
+
+	// Unchanged events keep their sequence, changed events are revised.
+	writer.Now = func() time.Time { return time.Date(2015, 3, 2, 12, 0, 0, 0, time.UTC) }
+	airings[0].Program.MD5 = "changed"
+	buf.Reset()
+	if err := writer.Write(buf, airings); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, sequences["20454.EP000000060003.20150303T200000Z@schedulesdirect.org"].Sequence)
+	assert.Equal(t, 0, sequences["10021.SH000000070000.20150303T210000Z@schedulesdirect.org"].Sequence)
+	assert.Contains(t, buf.String(), "SEQUENCE:1\r\nLAST-MODIFIED:20150302T120000Z\r\n")
+	assert.Contains(t, buf.String(), "SEQUENCE:0\r\nLAST-MODIFIED:20150301T120000Z\r\n")
+
+	// A moved airing keeps its UID, a new airing of the same program gets the UID of its start time.
+	moved := time.Date(2015, 3, 3, 20, 30, 0, 0, time.UTC)
+	airings[0].Program.AirDateTime = &moved
+	airings = append(airings, Airing{StationID: "20454", Program: testProgram("EP000000060003", "2015-03-04T20:00:00Z", 1800)})
+	buf.Reset()
+	if err := writer.Write(buf, airings); err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, buf.String(), "UID:20454.EP000000060003.20150303T200000Z@schedulesdirect.org\r\nDTSTAMP:20150302T120000Z\r\nDTSTART:20150303T203000Z\r\n")
+	assert.Contains(t, buf.String(), "UID:20454.EP000000060003.20150304T200000Z@schedulesdirect.org\r\nDTSTAMP:20150302T120000Z\r\nDTSTART:20150304T200000Z\r\n")
+	assert.Equal(t, 2, sequences["20454.EP000000060003.20150303T200000Z@schedulesdirect.org"].Sequence)
+	assert.Equal(t, moved, sequences["20454.EP000000060003.20150303T200000Z@schedulesdirect.org"].Start)
+
+	// An airing at the original time of a moved airing does not take over its UID.
+	airings = append(airings, Airing{StationID: "20454", Program: testProgram("EP000000060003", "2015-03-03T20:00:00Z", 1800)})
+	buf.Reset()
+	if err := writer.Write(buf, airings); err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, buf.String(), "UID:20454.EP000000060003.20150303T200000Z-1@schedulesdirect.org\r\nDTSTAMP:20150302T120000Z\r\nDTSTART:20150303T200000Z\r\n")
+	assert.Len(t, sequences, 4)
+
+	// Without sequences the UID does not depend on the other airings of the export.
+	buf.Reset()
+	if err := (ICalendarWriter{}).Write(buf, airings[1:2]); err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, buf.String(), "UID:10021.SH000000070000.20150303T210000Z@schedulesdirect.org")
+	assert.NotContains(t, buf.String(), "LAST-MODIFIED")
+
+	// Events which are no longer exported are forgotten a week after they started.
+	writer.Now = func() time.Time { return time.Date(2015, 3, 10, 22, 0, 0, 0, time.UTC) }
+	buf.Reset()
+	if err := writer.Write(buf, airings[2:3]); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"20454.EP000000060003.20150304T200000Z@schedulesdirect.org"}, sequenceUIDs(sequences))
+}
+
+func sequenceUIDs(sequences ICalendarSequences) []string {
+	uids := make([]string, 0, len(sequences))
+	for uid := range sequences {
+		uids = append(uids, uid)
+	}
+	return uids
+}
+
+func TestICalendarFold(t *testing.T) {
+	line := strings.Repeat("é", 60)
+	folded := icalFold(line)
+	for _, part := range strings.Split(folded, "\r\n") {
+		assert.True(t, len(part) <= 75, part)
+	}
+	assert.Equal(t, line, strings.Replace(folded, "\r\n ", "", -1))
+	assert.Equal(t, `a\\b\;c\,d\ne`, icalText("a\\b;c,d\r\ne"))
+}
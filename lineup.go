@@ -83,6 +83,19 @@ type Station struct {
 	IsRadioStation      bool             `json:"isRadioStation,omitempty"`
 }
 
+// PrimaryLogo returns the logo of the station, falling back to the first of its alternative logos.
+// It returns nil if the station has no logo.
+func (s Station) PrimaryLogo() *StationLogo {
+	if s.Logo != nil {
+		return s.Logo
+	}
+	if len(s.Logos) > 0 {
+		logo := s.Logos[0]
+		return &logo
+	}
+	return nil
+}
+
 // A StationLogo stores the information to locate a station logo
 type StationLogo struct {
 	URL    string `json:"URL,omitempty"`
@@ -121,8 +134,35 @@ type ChannelMap struct {
 	VirtualChannel       string `json:"virtualChannel,omitempty"`
 }
 
+// Number returns the number the channel is tuned by, preferring the lineup's own channel number
+// over the virtual channel, the logical channel number and the major and minor channel numbers.
+func (m ChannelMap) Number() string {
+	for _, number := range []string{m.Channel, m.VirtualChannel, m.LogicalChannelNumber} {
+		if number != "" {
+			return number
+		}
+	}
+	if m.ChannelMajor > 0 {
+		return fmt.Sprintf("%d.%d", m.ChannelMajor, m.ChannelMinor)
+	}
+	return ""
+}
+
 // AddLineup adds the given lineup uri to the users SchedulesDirect account.
 func (c *Client) AddLineup(lineupID string) (*ChangeLineupResponse, error) {
+	id, idErr := ParseLineupID(lineupID)
+	if idErr != nil {
+		return nil, idErr
+	}
+	return c.AddLineupID(id)
+}
+
+// AddLineupID adds the given lineup to the users SchedulesDirect account.
+func (c *Client) AddLineupID(lineupID LineupID) (*ChangeLineupResponse, error) {
+	if idErr := validateLineupID(lineupID.String()); idErr != nil {
+		return nil, idErr
+	}
+
 	url := fmt.Sprint(c.BaseURL, APIVersion, "/lineups/", lineupID)
 
 	req, httpErr := http.NewRequest("PUT", url, nil)
@@ -132,17 +172,35 @@ func (c *Client) AddLineup(lineupID string) (*ChangeLineupResponse, error) {
 
 	_, data, err := c.SendRequest(req, true)
 	if err != nil {
+		c.trackLineupChangeError(err)
 		return nil, err
 	}
 
 	r := &ChangeLineupResponse{}
 
 	err = json.Unmarshal(data, &r)
+	if err == nil {
+		c.lineupChangesRemaining = int(r.ChangesRemaining)
+		c.lineupChangesKnown = true
+	}
 	return r, err
 }
 
 // DeleteLineup deletes the given lineup uri from the users SchedulesDirect account.
 func (c *Client) DeleteLineup(lineupID string) (*ChangeLineupResponse, error) {
+	id, idErr := ParseLineupID(lineupID)
+	if idErr != nil {
+		return nil, idErr
+	}
+	return c.DeleteLineupID(id)
+}
+
+// DeleteLineupID deletes the given lineup from the users SchedulesDirect account.
+func (c *Client) DeleteLineupID(lineupID LineupID) (*ChangeLineupResponse, error) {
+	if idErr := validateLineupID(lineupID.String()); idErr != nil {
+		return nil, idErr
+	}
+
 	url := fmt.Sprint(c.BaseURL, APIVersion, "/lineups/", lineupID)
 
 	req, httpErr := http.NewRequest("DELETE", url, nil)
@@ -152,17 +210,43 @@ func (c *Client) DeleteLineup(lineupID string) (*ChangeLineupResponse, error) {
 
 	_, data, err := c.SendRequest(req, true)
 	if err != nil {
+		c.trackLineupChangeError(err)
 		return nil, err
 	}
 
 	r := &ChangeLineupResponse{}
 
 	err = json.Unmarshal(data, &r)
+	if err == nil {
+		c.lineupChangesRemaining = int(r.ChangesRemaining)
+		c.lineupChangesKnown = true
+	}
 	return r, err
 }
 
+// trackLineupChangeError records that no lineup changes remain if err says so.
+func (c *Client) trackLineupChangeError(err error) {
+	if baseErr, ok := err.(*BaseResponse); ok && baseErr.Code == ErrMaxLineupChangesReached {
+		c.lineupChangesRemaining = 0
+		c.lineupChangesKnown = true
+	}
+}
+
 // PreviewLineup returns a slice of StationPreview containing the channels available in the provided lineupID.
 func (c *Client) PreviewLineup(lineupID string) ([]StationPreview, error) {
+	id, idErr := ParseLineupID(lineupID)
+	if idErr != nil {
+		return nil, idErr
+	}
+	return c.PreviewLineupID(id)
+}
+
+// PreviewLineupID returns a slice of StationPreview containing the channels available in the provided lineup.
+func (c *Client) PreviewLineupID(lineupID LineupID) ([]StationPreview, error) {
+	if idErr := validateLineupID(lineupID.String()); idErr != nil {
+		return nil, idErr
+	}
+
 	url := fmt.Sprint(c.BaseURL, APIVersion, "/lineups/preview/", lineupID)
 
 	req, httpErr := http.NewRequest("GET", url, nil)
@@ -205,6 +289,10 @@ func (c *Client) AutomapLineup(hdhrLineupJSON []byte) (map[string]int, error) {
 // SubmitLineup should be called if AutomapLineup doesn't return candidates after you identify
 // the lineup you were trying to find via automapping.
 func (c *Client) SubmitLineup(hdhrLineupJSON []byte, lineupID string) error {
+	if idErr := validateLineupID(lineupID); idErr != nil {
+		return idErr
+	}
+
 	url := fmt.Sprint(c.BaseURL, APIVersion, "/map/lineup/", lineupID)
 
 	req, httpErr := http.NewRequest("POST", url, bytes.NewBuffer(hdhrLineupJSON))
@@ -245,6 +333,19 @@ func (c *Client) GetHeadends(countryCode, postalCode string) ([]Headend, error)
 
 // GetChannels returns the channels in a given lineup
 func (c *Client) GetChannels(lineupID string, verbose bool) (*ChannelResponse, error) {
+	id, idErr := ParseLineupID(lineupID)
+	if idErr != nil {
+		return nil, idErr
+	}
+	return c.GetChannelsForLineupID(id, verbose)
+}
+
+// GetChannelsForLineupID returns the channels in the given lineup.
+func (c *Client) GetChannelsForLineupID(lineupID LineupID, verbose bool) (*ChannelResponse, error) {
+	if idErr := validateLineupID(lineupID.String()); idErr != nil {
+		return nil, idErr
+	}
+
 	url := fmt.Sprint(c.BaseURL, APIVersion, "/lineups/", lineupID)
 
 	req, httpErr := http.NewRequest("GET", url, nil)
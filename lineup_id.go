@@ -0,0 +1,153 @@
+package schedulesdirect
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LineupTransport is the way a lineup is delivered to the viewer.
+type LineupTransport string
+
+const (
+	// AntennaTransport means the lineup is received over the air.
+	AntennaTransport LineupTransport = "Antenna"
+	// CableTransport means the lineup is delivered by a cable provider.
+	CableTransport LineupTransport = "Cable"
+	// SatelliteTransport means the lineup is delivered by a satellite provider.
+	SatelliteTransport LineupTransport = "Satellite"
+	// DVBTransport means the lineup is a DVB-T, DVB-C or DVB-S lineup.
+	DVBTransport LineupTransport = "DVB"
+)
+
+// otaLineup is the lineup segment of an over the air lineup ID.
+const otaLineup = "OTA"
+
+// A LineupID is a parsed Schedules Direct lineup ID, formatted either
+// COUNTRY-LINEUP-DEVICE (e.g. USA-NY31519-X) or COUNTRY-OTA-POSTALCODE (e.g. USA-OTA-10001).
+type LineupID struct {
+	// Country is the ISO-3166-1 alpha 3 country code.
+	Country string
+	// Lineup is the headend (or "OTA" for over the air lineups).
+	Lineup string
+	// Device is the device type of the headend, or the postal code for over the air lineups.
+	Device string
+}
+
+// ParseLineupID parses and validates a lineup ID string.
+// A *BaseResponse with code ErrLineupWrongFormat is returned if it is not valid.
+func ParseLineupID(lineupID string) (LineupID, error) {
+	parts := strings.SplitN(lineupID, "-", 3)
+	if len(parts) != 3 {
+		return LineupID{}, lineupWrongFormat(lineupID, "expected 3 dash separated parts")
+	}
+
+	id := LineupID{Country: parts[0], Lineup: parts[1], Device: parts[2]}
+
+	if len(id.Country) != 3 || !isUpperAlpha(id.Country) {
+		return LineupID{}, lineupWrongFormat(lineupID, "country must be a ISO-3166-1 alpha 3 code")
+	}
+
+	if id.Lineup == "" || !isAlphanumeric(id.Lineup) {
+		return LineupID{}, lineupWrongFormat(lineupID, "lineup must be alphanumeric")
+	}
+
+	if id.Device == "" || strings.ContainsAny(id.Device, " \t\r\n/?#") {
+		if id.IsOTA() {
+			return LineupID{}, lineupWrongFormat(lineupID, "postal code is missing or invalid")
+		}
+		return LineupID{}, lineupWrongFormat(lineupID, "device is missing or invalid")
+	}
+
+	return id, nil
+}
+
+// String returns the lineup ID as understood by Schedules Direct.
+func (l LineupID) String() string {
+	return fmt.Sprintf("%s-%s-%s", l.Country, l.Lineup, l.Device)
+}
+
+// IsOTA returns true if the lineup is an over the air lineup.
+func (l LineupID) IsOTA() bool {
+	return l.Lineup == otaLineup
+}
+
+// PostalCode returns the postal code of an over the air lineup, or an empty string for other lineups.
+func (l LineupID) PostalCode() string {
+	if l.IsOTA() {
+		return l.Device
+	}
+	return ""
+}
+
+// Transport returns the transport the lineup is most likely delivered by.
+//
+// Only over the air lineups can be identified with certainty from the ID alone,
+// the other transports are a best guess. Headend.Transport is authoritative when available.
+func (l LineupID) Transport() LineupTransport {
+	switch {
+	case l.IsOTA():
+		return AntennaTransport
+	case strings.HasPrefix(l.Lineup, "DISH") || strings.HasPrefix(l.Lineup, "DITV") || strings.HasPrefix(l.Lineup, "SKY"):
+		return SatelliteTransport
+	case l.Country != "USA" && l.Country != "CAN" && isNumeric(l.Lineup):
+		return DVBTransport
+	}
+	return CableTransport
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (l LineupID) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (l *LineupID) UnmarshalText(text []byte) error {
+	id, err := ParseLineupID(string(text))
+	if err != nil {
+		return err
+	}
+	*l = id
+	return nil
+}
+
+// validateLineupID returns an error if lineupID is not a valid lineup ID.
+func validateLineupID(lineupID string) error {
+	_, err := ParseLineupID(lineupID)
+	return err
+}
+
+// lineupWrongFormat returns a BaseResponse like Schedules Direct would for a malformed lineup ID.
+func lineupWrongFormat(lineupID, reason string) *BaseResponse {
+	return &BaseResponse{
+		Response: ErrLineupWrongFormat.InternalCode(),
+		Code:     ErrLineupWrongFormat,
+		Message:  fmt.Sprintf("invalid lineup ID %q: %s", lineupID, reason),
+	}
+}
+
+func isUpperAlpha(str string) bool {
+	for _, r := range str {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isNumeric(str string) bool {
+	for _, r := range str {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return str != ""
+}
+
+func isAlphanumeric(str string) bool {
+	for _, r := range str {
+		if (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
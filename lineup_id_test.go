@@ -0,0 +1,110 @@
+package schedulesdirect
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLineupIDOK(t *testing.T) {
+	ota, err := ParseLineupID("CAN-OTA-J8T3T6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "CAN", ota.Country)
+	assert.True(t, ota.IsOTA())
+	assert.Equal(t, "J8T3T6", ota.PostalCode())
+	assert.Equal(t, AntennaTransport, ota.Transport())
+	assert.Equal(t, "CAN-OTA-J8T3T6", ota.String())
+
+	cable, err := ParseLineupID("USA-NY31519-X")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "NY31519", cable.Lineup)
+	assert.Equal(t, "X", cable.Device)
+	assert.Equal(t, "", cable.PostalCode())
+	assert.Equal(t, CableTransport, cable.Transport())
+
+	assert.Equal(t, SatelliteTransport, LineupID{Country: "USA", Lineup: "DITV", Device: "X"}.Transport())
+	assert.Equal(t, DVBTransport, LineupID{Country: "GBR", Lineup: "1000014", Device: "DEFAULT"}.Transport())
+}
+
+func TestParseLineupIDFailsWrongFormat(t *testing.T) {
+	for _, lineupID := range []string{"", "USA", "USA-OTA", "usa-OTA-10001", "US-OTA-10001", "USA-O T A-10001", "USA-OTA-", "USA-NY31519-X/../token"} {
+		_, err := ParseLineupID(lineupID)
+		ensureError(t, err, ErrLineupWrongFormat)
+	}
+}
+
+func TestLineupIDJSON(t *testing.T) {
+	var ids []LineupID
+	if err := json.Unmarshal([]byte(`["USA-OTA-10001","GBR-1000014-DEFAULT"]`), &ids); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "10001", ids[0].PostalCode())
+
+	marshalled, err := json.Marshal(ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.JSONEq(t, `["USA-OTA-10001","GBR-1000014-DEFAULT"]`, string(marshalled))
+
+	assert.Error(t, json.Unmarshal([]byte(`["USA-10001"]`), &ids))
+}
+
+func TestAddLineupFailsWrongFormatWithoutRequest(t *testing.T) {
+	mux, client := setup()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("unexpected request %s %s", r.Method, r.URL.Path)
+	})
+
+	_, errAddLineup := client.AddLineup("CAN0000001X")
+	ensureError(t, errAddLineup, ErrLineupWrongFormat)
+
+	_, errGetChannels := client.GetChannels("CAN-0000001-X?verbose", true)
+	ensureError(t, errGetChannels, ErrLineupWrongFormat)
+
+	_, errPreview := client.PreviewLineup("")
+	ensureError(t, errPreview, ErrLineupWrongFormat)
+
+	_, errAddLineupID := client.AddLineupID(LineupID{Country: "CAN", Lineup: "0000001"})
+	ensureError(t, errAddLineupID, ErrLineupWrongFormat)
+
+	_, errDeleteLineupID := client.DeleteLineupID(LineupID{})
+	ensureError(t, errDeleteLineupID, ErrLineupWrongFormat)
+
+	_, errGetChannelsForLineupID := client.GetChannelsForLineupID(LineupID{Country: "CAN", Lineup: "0000001", Device: "X/Y"}, false)
+	ensureError(t, errGetChannelsForLineupID, ErrLineupWrongFormat)
+
+	_, errPreviewLineupID := client.PreviewLineupID(LineupID{Country: "usa", Lineup: "OTA", Device: "10001"})
+	ensureError(t, errPreviewLineupID, ErrLineupWrongFormat)
+}
+
+func TestAddLineupID(t *testing.T) {
+	mux, client := setup()
+
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/lineups/USA-OTA-10001"),
+		func(w http.ResponseWriter, r *http.Request) {
+			ensureMethod(t, r, "PUT")
+
+			baseResp := getBaseResponse(ErrOK)
+
+			fmt.Fprintf(w, `%s, "changesRemaining": "4"}`, baseResp[:len(baseResp)-1])
+		},
+	)
+
+	id, err := ParseLineupID("USA-OTA-10001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changeResp, errAddLineup := client.AddLineupID(id)
+	if assert.NoError(t, errAddLineup) {
+		assert.Equal(t, 4, int(changeResp.ChangesRemaining))
+	}
+}
@@ -0,0 +1,287 @@
+package schedulesdirect
+
+import (
+	"fmt"
+	"sort"
+)
+
+// LineupChangeAction is the kind of change a LineupChange will make to the account.
+type LineupChangeAction string
+
+const (
+	// AddLineupAction means the lineup will be added to the account.
+	AddLineupAction LineupChangeAction = "add"
+	// DeleteLineupAction means the lineup will be deleted from the account.
+	DeleteLineupAction LineupChangeAction = "delete"
+)
+
+// UnknownChangesRemaining is used as LineupPlan.ChangesRemaining when the number of
+// lineup changes left for today is not known.
+const UnknownChangesRemaining = -1
+
+// A LineupChange is a single step of a LineupPlan.
+type LineupChange struct {
+	Action   LineupChangeAction
+	LineupID string
+
+	// Applied is set once the change has been successfully sent to Schedules Direct.
+	Applied bool
+	// ChangesRemaining is the number of changes remaining today as reported after applying this change.
+	ChangesRemaining int
+}
+
+// A LineupPlan describes the changes needed to bring the lineups on an account
+// in line with a desired set of lineup IDs.
+type LineupPlan struct {
+	// Current is the set of lineup IDs on the account when the plan was made.
+	Current []string
+	// Desired is the set of lineup IDs the account should end up with.
+	Desired []string
+	// Unchanged is the set of lineup IDs which are both current and desired.
+	Unchanged []string
+	// Changes is the ordered list of changes to apply.
+	Changes []LineupChange
+
+	// MaxLineups is the maximum number of lineups allowed on the account, or 0 if unknown.
+	MaxLineups int
+	// ChangesRemaining is the number of lineup changes left for today,
+	// or UnknownChangesRemaining if it is not known.
+	ChangesRemaining int
+}
+
+// Adds returns the lineup IDs the plan will add.
+func (p *LineupPlan) Adds() []string {
+	return p.lineupIDsFor(AddLineupAction)
+}
+
+// Deletes returns the lineup IDs the plan will delete.
+func (p *LineupPlan) Deletes() []string {
+	return p.lineupIDsFor(DeleteLineupAction)
+}
+
+func (p *LineupPlan) lineupIDsFor(action LineupChangeAction) []string {
+	ids := make([]string, 0)
+	for _, change := range p.Changes {
+		if change.Action == action {
+			ids = append(ids, change.LineupID)
+		}
+	}
+	return ids
+}
+
+// Validate checks the plan against the account limits.
+//
+// It returns a *BaseResponse with code ErrMaxLineups if the desired lineups do not fit in the account
+// and ErrMaxLineupChangesReached if the changes not yet applied are more than remain today.
+//
+// Schedules Direct only reports the remaining changes in response to a lineup change, so the
+// second check is skipped while ChangesRemaining is UnknownChangesRemaining. This is always the
+// case for plans made by a Client which has not added or deleted a lineup yet, so a dry run on
+// a fresh Client cannot detect an exhausted daily quota.
+func (p *LineupPlan) Validate() error {
+	if p.MaxLineups > 0 && len(p.Desired) > p.MaxLineups {
+		return lineupPlanError(ErrMaxLineups, "%d lineups are desired but the account allows %d", len(p.Desired), p.MaxLineups)
+	}
+
+	pending := 0
+	for _, change := range p.Changes {
+		if !change.Applied {
+			pending++
+		}
+	}
+	if p.ChangesRemaining != UnknownChangesRemaining && pending > p.ChangesRemaining {
+		return lineupPlanError(ErrMaxLineupChangesReached, "%d lineup changes are pending but %d remain today", pending, p.ChangesRemaining)
+	}
+
+	return nil
+}
+
+// lineupPlanError returns a BaseResponse like Schedules Direct would for a plan exceeding the account limits.
+func lineupPlanError(code ErrorCode, format string, args ...interface{}) *BaseResponse {
+	return &BaseResponse{
+		Response: code.InternalCode(),
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// PlanLineupChanges compares the desired lineup IDs with the lineups subscribed to by this
+// account and returns the changes needed to reconcile them. Nothing is changed on the account.
+//
+// The changes are ordered so that the account keeps as many desired lineups as possible
+// at every step: additions which fit into free slots come first, then each remaining addition
+// is preceded by a single deletion, and any leftover deletions come last.
+func (c *Client) PlanLineupChanges(desired []string) (*LineupPlan, error) {
+	for _, lineupID := range desired {
+		if idErr := validateLineupID(lineupID); idErr != nil {
+			return nil, idErr
+		}
+	}
+
+	current := make([]string, 0)
+
+	lineups, lineupsErr := c.GetLineups()
+	if lineupsErr != nil {
+		if baseErr, ok := lineupsErr.(*BaseResponse); !ok || baseErr.Code != ErrNoLineups {
+			return nil, lineupsErr
+		}
+	} else {
+		for _, lineup := range lineups.Lineups {
+			id := lineup.Lineup
+			if id == "" {
+				id = lineup.ID
+			}
+			current = append(current, id)
+		}
+	}
+
+	status, statusErr := c.GetStatus()
+	if statusErr != nil {
+		return nil, statusErr
+	}
+
+	maxLineups := 0
+	if status.Account != nil {
+		maxLineups = status.Account.MaxLineups
+	}
+
+	plan := newLineupPlan(current, desired, maxLineups)
+
+	plan.ChangesRemaining = UnknownChangesRemaining
+	if c.lineupChangesKnown {
+		plan.ChangesRemaining = c.lineupChangesRemaining
+	}
+
+	return plan, nil
+}
+
+// newLineupPlan builds an ordered LineupPlan out of the current and desired lineup IDs.
+func newLineupPlan(current, desired []string, maxLineups int) *LineupPlan {
+	plan := &LineupPlan{
+		Current:    dedupeStrings(current),
+		Desired:    dedupeStrings(desired),
+		Unchanged:  make([]string, 0),
+		Changes:    make([]LineupChange, 0),
+		MaxLineups: maxLineups,
+	}
+
+	currentSet := make(map[string]bool)
+	for _, id := range plan.Current {
+		currentSet[id] = true
+	}
+
+	desiredSet := make(map[string]bool)
+	for _, id := range plan.Desired {
+		desiredSet[id] = true
+	}
+
+	adds := make([]string, 0)
+	for _, id := range plan.Desired {
+		if currentSet[id] {
+			plan.Unchanged = append(plan.Unchanged, id)
+		} else {
+			adds = append(adds, id)
+		}
+	}
+
+	deletes := make([]string, 0)
+	for _, id := range plan.Current {
+		if !desiredSet[id] {
+			deletes = append(deletes, id)
+		}
+	}
+
+	freeSlots := len(adds)
+	if maxLineups > 0 {
+		freeSlots = maxLineups - len(plan.Current)
+		if freeSlots < 0 {
+			freeSlots = 0
+		}
+	}
+
+	for _, id := range adds {
+		if freeSlots == 0 && len(deletes) > 0 {
+			plan.Changes = append(plan.Changes, LineupChange{Action: DeleteLineupAction, LineupID: deletes[0]})
+			deletes = deletes[1:]
+		} else if freeSlots > 0 {
+			freeSlots--
+		}
+		plan.Changes = append(plan.Changes, LineupChange{Action: AddLineupAction, LineupID: id})
+	}
+
+	for _, id := range deletes {
+		plan.Changes = append(plan.Changes, LineupChange{Action: DeleteLineupAction, LineupID: id})
+	}
+
+	return plan
+}
+
+// ApplyLineupPlan validates the plan and then applies its changes in order.
+//
+// Applying stops at the first failed change, whose error is returned. Changes which
+// were applied before the failure are marked as such in the plan.
+func (c *Client) ApplyLineupPlan(plan *LineupPlan) error {
+	if validateErr := plan.Validate(); validateErr != nil {
+		return validateErr
+	}
+
+	for idx := range plan.Changes {
+		change := &plan.Changes[idx]
+		if change.Applied {
+			continue
+		}
+
+		var resp *ChangeLineupResponse
+		var changeErr error
+
+		switch change.Action {
+		case AddLineupAction:
+			resp, changeErr = c.AddLineup(change.LineupID)
+		case DeleteLineupAction:
+			resp, changeErr = c.DeleteLineup(change.LineupID)
+		default:
+			changeErr = fmt.Errorf("unknown lineup change action %q", change.Action)
+		}
+
+		if changeErr != nil {
+			return changeErr
+		}
+
+		change.Applied = true
+		change.ChangesRemaining = int(resp.ChangesRemaining)
+		plan.ChangesRemaining = change.ChangesRemaining
+	}
+
+	return nil
+}
+
+// ReconcileLineups plans the changes needed to make the account subscribe to exactly the
+// desired lineup IDs and, unless dryRun is set, applies them.
+// The returned plan is valid even if applying it failed part way through.
+func (c *Client) ReconcileLineups(desired []string, dryRun bool) (*LineupPlan, error) {
+	plan, planErr := c.PlanLineupChanges(desired)
+	if planErr != nil {
+		return nil, planErr
+	}
+
+	if dryRun {
+		return plan, plan.Validate()
+	}
+
+	return plan, c.ApplyLineupPlan(plan)
+}
+
+// dedupeStrings returns the unique, non-empty strings of sl in sorted order.
+func dedupeStrings(sl []string) []string {
+	seen := make(map[string]bool)
+	unique := make([]string, 0, len(sl))
+	for _, str := range sl {
+		if str == "" || seen[str] {
+			continue
+		}
+		seen[str] = true
+		unique = append(unique, str)
+	}
+	sort.Strings(unique)
+	return unique
+}
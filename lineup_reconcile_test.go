@@ -0,0 +1,120 @@
+package schedulesdirect
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLineupPlanOrdering(t *testing.T) {
+	plan := newLineupPlan(
+		[]string{"USA-OTA-10001", "USA-NY31519-X", "USA-DITV-X"},
+		[]string{"USA-OTA-10001", "CAN-OTA-H0H0H0", "GBR-1000014-DEFAULT", "USA-OTA-10001"},
+		4,
+	)
+
+	assert.Equal(t, []string{"USA-OTA-10001"}, plan.Unchanged)
+	assert.Equal(t, []LineupChange{
+		{Action: AddLineupAction, LineupID: "CAN-OTA-H0H0H0"},
+		{Action: DeleteLineupAction, LineupID: "USA-DITV-X"},
+		{Action: AddLineupAction, LineupID: "GBR-1000014-DEFAULT"},
+		{Action: DeleteLineupAction, LineupID: "USA-NY31519-X"},
+	}, plan.Changes)
+	assert.Equal(t, []string{"CAN-OTA-H0H0H0", "GBR-1000014-DEFAULT"}, plan.Adds())
+	assert.Equal(t, []string{"USA-DITV-X", "USA-NY31519-X"}, plan.Deletes())
+}
+
+func TestLineupPlanValidate(t *testing.T) {
+	plan := newLineupPlan([]string{"USA-OTA-10001"}, []string{"USA-OTA-10002", "USA-OTA-10003"}, 1)
+	plan.ChangesRemaining = UnknownChangesRemaining
+	ensureError(t, plan.Validate(), ErrMaxLineups)
+
+	plan = newLineupPlan([]string{"USA-OTA-10001"}, []string{"USA-OTA-10002"}, 4)
+	plan.ChangesRemaining = 1
+	ensureError(t, plan.Validate(), ErrMaxLineupChangesReached)
+
+	plan.ChangesRemaining = 2
+	assert.NoError(t, plan.Validate())
+
+	// Resuming a partly applied plan only needs quota for the changes left.
+	plan.Changes[0].Applied = true
+	plan.ChangesRemaining = 1
+	assert.NoError(t, plan.Validate())
+}
+
+func TestReconcileLineupsOK(t *testing.T) {
+	mux, client := setup()
+
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/status"),
+		func(w http.ResponseWriter, r *http.Request) {
+			ensureMethod(t, r, "GET")
+			fmt.Fprint(w, `{"account":{"expires":"2014-09-26T19:07:28Z","messages":[],"maxLineups":2},"code":0}`)
+		},
+	)
+
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/lineups"),
+		func(w http.ResponseWriter, r *http.Request) {
+			ensureMethod(t, r, "GET")
+			fmt.Fprint(w, `{"lineups":[{"lineup":"USA-OTA-10001","name":"name1","uri":"uri1"},{"lineup":"USA-NY31519-X","name":"name2","uri":"uri2"}]}`)
+		},
+	)
+
+	requests := make([]string, 0)
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/lineups/"),
+		func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, fmt.Sprint(r.Method, " ", r.URL.Path))
+
+			baseResp := getBaseResponse(ErrOK)
+			fmt.Fprintf(w, `%s, "changesRemaining": "%d"}`, baseResp[:len(baseResp)-1], 6-len(requests))
+		},
+	)
+
+	plan, err := client.ReconcileLineups([]string{"USA-OTA-10001", "USA-OTA-10002"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, requests)
+	assert.Equal(t, UnknownChangesRemaining, plan.ChangesRemaining)
+
+	plan, err = client.ReconcileLineups([]string{"USA-OTA-10001", "USA-OTA-10002"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{
+		fmt.Sprint("DELETE /", APIVersion, "/lineups/USA-NY31519-X"),
+		fmt.Sprint("PUT /", APIVersion, "/lineups/USA-OTA-10002"),
+	}, requests)
+	assert.Equal(t, 4, plan.ChangesRemaining)
+	for _, change := range plan.Changes {
+		assert.True(t, change.Applied)
+	}
+}
+
+func TestReconcileLineupsFailsMaxLineups(t *testing.T) {
+	mux, client := setup()
+
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/status"),
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"account":{"expires":"2014-09-26T19:07:28Z","messages":[],"maxLineups":1},"code":0}`)
+		},
+	)
+
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/lineups"),
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, getBaseResponse(ErrNoLineups))
+		},
+	)
+
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/lineups/"),
+		func(w http.ResponseWriter, r *http.Request) {
+			t.Fatalf("unexpected lineup change %s %s", r.Method, r.URL.Path)
+		},
+	)
+
+	plan, err := client.ReconcileLineups([]string{"USA-OTA-10001", "USA-OTA-10002"}, false)
+	ensureError(t, err, ErrMaxLineups)
+	assert.Equal(t, []string{"USA-OTA-10001", "USA-OTA-10002"}, plan.Adds())
+}
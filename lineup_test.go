@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net/http"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestGetHeadendsOK(t *testing.T) {
@@ -292,3 +294,17 @@ func TestGetChannelsFailsLineupNotFound(t *testing.T) {
 	_, errGetChannels := client.GetChannels("CAN-0000001-X", false)
 	ensureError(t, errGetChannels, ErrLineupNotFound)
 }
+
+func TestChannelMapNumber(t *testing.T) {
+	assert.Equal(t, "2.1", ChannelMap{Channel: "2.1", VirtualChannel: "7"}.Number())
+	assert.Equal(t, "7", ChannelMap{VirtualChannel: "7", LogicalChannelNumber: "101"}.Number())
+	assert.Equal(t, "101", ChannelMap{LogicalChannelNumber: "101"}.Number())
+	assert.Equal(t, "4.2", ChannelMap{ChannelMajor: 4, ChannelMinor: 2}.Number())
+	assert.Equal(t, "", ChannelMap{StationID: "10021"}.Number())
+}
+
+func TestStationPrimaryLogo(t *testing.T) {
+	assert.Nil(t, Station{}.PrimaryLogo())
+	assert.Equal(t, "b.png", Station{Logos: []StationLogo{{URL: "b.png"}, {URL: "c.png"}}}.PrimaryLogo().URL)
+	assert.Equal(t, "a.png", Station{Logo: &StationLogo{URL: "a.png"}, Logos: []StationLogo{{URL: "b.png"}}}.PrimaryLogo().URL)
+}
@@ -0,0 +1,212 @@
+package schedulesdirect
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// An M3UEntry is a single channel of an M3U playlist.
+type M3UEntry struct {
+	// ID is the tvg-id, which matches the channel ID of the XMLTV guide.
+	ID string
+	// Name is the tvg-name, the station's call sign.
+	Name string
+	// Number is the tvg-chno.
+	Number string
+	// Logo is the tvg-logo URL.
+	Logo string
+	// Group is the group-title.
+	Group string
+	// Title is the display name following the #EXTINF attributes.
+	Title string
+	// URL is the stream URL.
+	URL string
+}
+
+// An M3UGenerator writes M3U playlists from lineup channel maps.
+//
+// StreamURL is a template for the stream URL of each channel. The placeholders
+// {channel}, {stationID}, {callsign} and {lineup} are replaced with the channel number,
+// station ID, call sign and lineup ID, e.g. "http://192.168.1.10:5004/auto/v{channel}" for an HDHomeRun.
+type M3UGenerator struct {
+	StreamURL string
+	// GuideURL is the location of the XMLTV guide, written as the url-tvg attribute of the playlist if set.
+	GuideURL string
+	// ChannelID returns the tvg-id of a station. It must return the channel IDs used in the XMLTV guide,
+	// which this package does not write. The station ID is used if nil.
+	ChannelID func(stationID string) string
+	// Group returns the group-title of a channel. The lineup ID is used if nil.
+	Group func(lineupID string, entry ChannelMap, station Station) string
+}
+
+// m3uPlaceholders are the placeholders supported by stream URL templates.
+func m3uPlaceholders() []string {
+	return []string{"channel", "stationID", "callsign", "lineup"}
+}
+
+// validateStreamURL returns an error if the template contains an unknown or unclosed placeholder.
+func validateStreamURL(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("m3u stream url template is empty")
+	}
+	rest := template
+	for {
+		open := strings.Index(rest, "{")
+		if open < 0 {
+			return nil
+		}
+		end := strings.Index(rest[open:], "}")
+		if end < 0 {
+			return fmt.Errorf("m3u stream url template %q has an unclosed placeholder", template)
+		}
+		name := rest[open+1 : open+end]
+		known := false
+		for _, placeholder := range m3uPlaceholders() {
+			known = known || placeholder == name
+		}
+		if !known {
+			return fmt.Errorf("m3u stream url template %q has unknown placeholder {%s}, expected one of {%s}", template, name, strings.Join(m3uPlaceholders(), "}, {"))
+		}
+		rest = rest[open+end+1:]
+	}
+}
+
+// Entries returns a playlist entry for each channel of the lineups, in lineup order.
+// A station mapped to the same channel number more than once is only listed once.
+func (g M3UGenerator) Entries(lineups ...*ChannelResponse) ([]M3UEntry, error) {
+	if err := validateStreamURL(g.StreamURL); err != nil {
+		return nil, err
+	}
+
+	entries := make([]M3UEntry, 0)
+	seen := make(map[string]bool)
+
+	for _, lineup := range lineups {
+		if lineup == nil {
+			continue
+		}
+
+		lineupID := ""
+		if lineup.Metadata != nil {
+			lineupID = lineup.Metadata.Lineup
+		}
+
+		stations := make(map[string]Station, len(lineup.Stations))
+		for _, station := range lineup.Stations {
+			stations[station.StationID] = station
+		}
+
+		for _, entry := range lineup.Map {
+			number := entry.Number()
+			key := entry.StationID + "\x00" + number
+			if entry.StationID == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			station := stations[entry.StationID]
+
+			id := entry.StationID
+			if g.ChannelID != nil {
+				id = g.ChannelID(entry.StationID)
+			}
+
+			group := lineupID
+			if g.Group != nil {
+				group = g.Group(lineupID, entry, station)
+			}
+
+			logo := ""
+			if stationLogo := station.PrimaryLogo(); stationLogo != nil {
+				logo = stationLogo.URL
+			}
+
+			name := station.CallSign
+			if name == "" {
+				name = station.Name
+			}
+			title := station.Name
+			if title == "" {
+				title = name
+			}
+			if title == "" {
+				title = entry.StationID
+			}
+
+			replacer := strings.NewReplacer(
+				"{channel}", url.PathEscape(number),
+				"{stationID}", url.PathEscape(entry.StationID),
+				"{callsign}", url.PathEscape(station.CallSign),
+				"{lineup}", url.PathEscape(lineupID),
+			)
+
+			entries = append(entries, M3UEntry{
+				ID:     id,
+				Name:   name,
+				Number: number,
+				Logo:   logo,
+				Group:  group,
+				Title:  title,
+				URL:    replacer.Replace(g.StreamURL),
+			})
+		}
+	}
+
+	return entries, nil
+}
+
+// Write writes an M3U playlist of the channels of the lineups.
+func (g M3UGenerator) Write(w io.Writer, lineups ...*ChannelResponse) error {
+	entries, err := g.Entries(lineups...)
+	if err != nil {
+		return err
+	}
+	return WriteM3U(w, g.GuideURL, entries)
+}
+
+// WriteM3U writes the entries as an extended M3U playlist. guideURL is written as the url-tvg attribute if set.
+func WriteM3U(w io.Writer, guideURL string, entries []M3UEntry) error {
+	buf := bufio.NewWriter(w)
+
+	header := "#EXTM3U"
+	if guideURL != "" {
+		header += fmt.Sprintf(` url-tvg="%s" x-tvg-url="%s"`, m3uAttribute(guideURL), m3uAttribute(guideURL))
+	}
+	if _, err := fmt.Fprintln(buf, header); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		attributes := make([]string, 0, 5)
+		for _, attribute := range [][2]string{
+			{"tvg-id", entry.ID},
+			{"tvg-name", entry.Name},
+			{"tvg-chno", entry.Number},
+			{"tvg-logo", entry.Logo},
+			{"group-title", entry.Group},
+		} {
+			if attribute[1] != "" {
+				attributes = append(attributes, fmt.Sprintf(`%s="%s"`, attribute[0], m3uAttribute(attribute[1])))
+			}
+		}
+
+		if _, err := fmt.Fprintf(buf, "#EXTINF:-1 %s,%s\n%s\n", strings.Join(attributes, " "), m3uLine(entry.Title), m3uLine(entry.URL)); err != nil {
+			return err
+		}
+	}
+
+	return buf.Flush()
+}
+
+// m3uAttribute makes a value safe to use in a double quoted M3U attribute.
+func m3uAttribute(value string) string {
+	return strings.Replace(m3uLine(value), `"`, "'", -1)
+}
+
+// m3uLine makes a value safe to write on a single M3U line.
+func m3uLine(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
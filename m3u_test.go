@@ -0,0 +1,80 @@
+package schedulesdirect
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testM3ULineup(t *testing.T) *ChannelResponse {
+	t.Helper()
+
+	lineup := &ChannelResponse{}
+	if err := json.Unmarshal([]byte(`{
+		"map": [
+			{"stationID": "20454", "channel": "2.1"},
+			{"stationID": "20454", "channel": "2.1"},
+			{"stationID": "10021", "channelMajor": 4, "channelMinor": 1}
+		],
+		"stations": [
+			{"stationID": "20454", "callsign": "WCBS", "name": "WCBS \"DT\"", "logo": {"URL": "https://example.com/wcbs.png"}},
+			{"stationID": "10021", "callsign": "WNBC", "stationLogo": [{"URL": "https://example.com/wnbc.png"}]}
+		],
+		"metadata": {"lineup": "USA-NY67791-X"}
+	}`), lineup); err != nil {
+		t.Fatal(err)
+	}
+	return lineup
+}
+
+func TestM3UGenerator(t *testing.T) {
+	generator := M3UGenerator{
+		StreamURL: "http://192.168.1.10:5004/auto/v{channel}",
+		GuideURL:  "http://192.168.1.10/guide.xml",
+	}
+
+	buf := &bytes.Buffer{}
+	if err := generator.Write(buf, testM3ULineup(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, `#EXTM3U url-tvg="http://192.168.1.10/guide.xml" x-tvg-url="http://192.168.1.10/guide.xml"
+#EXTINF:-1 tvg-id="20454" tvg-name="WCBS" tvg-chno="2.1" tvg-logo="https://example.com/wcbs.png" group-title="USA-NY67791-X",WCBS "DT"
+http://192.168.1.10:5004/auto/v2.1
+#EXTINF:-1 tvg-id="10021" tvg-name="WNBC" tvg-chno="4.1" tvg-logo="https://example.com/wnbc.png" group-title="USA-NY67791-X",WNBC
+http://192.168.1.10:5004/auto/v4.1
+`, buf.String())
+}
+
+func TestM3UGeneratorOptions(t *testing.T) {
+	generator := M3UGenerator{
+		StreamURL: "http://iptv.example.com/{lineup}/{stationID}/{callsign}",
+		ChannelID: func(stationID string) string { return "I" + stationID + ".json.schedulesdirect.org" },
+		Group: func(lineupID string, entry ChannelMap, station Station) string {
+			return station.Name + `"`
+		},
+	}
+
+	entries, err := generator.Entries(testM3ULineup(t), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "I20454.json.schedulesdirect.org", entries[0].ID)
+		assert.Equal(t, "http://iptv.example.com/USA-NY67791-X/20454/WCBS", entries[0].URL)
+		assert.Equal(t, "WNBC", entries[1].Title)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := WriteM3U(buf, "", entries[:1]); err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, buf.String(), `group-title="WCBS 'DT''"`)
+
+	for _, template := range []string{"", "http://host/{channel", "http://host/{number}"} {
+		_, err := M3UGenerator{StreamURL: template}.Entries(testM3ULineup(t))
+		assert.Error(t, err, template)
+	}
+}
@@ -0,0 +1,337 @@
+package schedulesdirect
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// An NFOGenerator writes Kodi/Jellyfin NFO files and Plex/Jellyfin style file names for recordings.
+type NFOGenerator struct {
+	// Programs are used to look up the series of episodes, whose original air date is the year of the show.
+	Programs map[string]*ProgramInfo
+	// Artwork is the artwork of programs keyed by the IDs returned by GetArtworkForProgramIDs, see IndexArtwork.
+	Artwork map[string][]Artwork
+	// ImageURL returns the URL of an artwork URI, e.g. Client.GetImageURL. URIs are used as they are if nil.
+	ImageURL func(uri string) string
+	// DescriptionLanguages are passed to ProgramInfo.Description to choose the plot.
+	DescriptionLanguages []string
+	// Country is the ISO 3166-1 alpha-3 country whose content rating is preferred for the mpaa element, "USA" if empty.
+	Country string
+}
+
+type nfoUniqueID struct {
+	Type    string `xml:"type,attr"`
+	Default bool   `xml:"default,attr"`
+	Value   string `xml:",chardata"`
+}
+
+type nfoThumb struct {
+	Aspect string `xml:"aspect,attr,omitempty"`
+	URL    string `xml:",chardata"`
+}
+
+type nfoActor struct {
+	Name  string `xml:"name"`
+	Role  string `xml:"role,omitempty"`
+	Order int    `xml:"order"`
+}
+
+type nfoRating struct {
+	Name  string  `xml:"name,attr"`
+	Max   float64 `xml:"max,attr"`
+	Value float64 `xml:"value"`
+}
+
+type nfoRatings struct {
+	Ratings []nfoRating `xml:"rating"`
+}
+
+type nfoFanart struct {
+	Thumbs []nfoThumb `xml:"thumb"`
+}
+
+// nfoDetails are the elements shared by movie, tvshow and episodedetails NFO files, in Kodi's order.
+type nfoDetails struct {
+	XMLName   xml.Name
+	Title     string      `xml:"title"`
+	ShowTitle string      `xml:"showtitle,omitempty"`
+	Season    int         `xml:"season,omitempty"`
+	Episode   int         `xml:"episode,omitempty"`
+	Ratings   *nfoRatings `xml:"ratings"`
+	Outline   string      `xml:"outline,omitempty"`
+	Plot      string      `xml:"plot,omitempty"`
+	Runtime   int         `xml:"runtime,omitempty"`
+	Thumbs    []nfoThumb  `xml:"thumb"`
+	Fanart    *nfoFanart  `xml:"fanart"`
+	MPAA      string      `xml:"mpaa,omitempty"`
+	UniqueID  nfoUniqueID `xml:"uniqueid"`
+	Genres    []string    `xml:"genre"`
+	Credits   []string    `xml:"credits"`
+	Directors []string    `xml:"director"`
+	Premiered string      `xml:"premiered,omitempty"`
+	Year      int         `xml:"year,omitempty"`
+	Aired     string      `xml:"aired,omitempty"`
+	Actors    []nfoActor  `xml:"actor"`
+}
+
+// isMovie returns true if the program is a movie.
+func isMovie(info *ProgramInfo) bool {
+	return info.EntityType == MovieEntityType || strings.HasPrefix(info.ProgramID, "MV")
+}
+
+// WriteNFO writes a movie NFO for movies and an episodedetails NFO for everything else.
+func (g NFOGenerator) WriteNFO(w io.Writer, info *ProgramInfo) error {
+	if info == nil {
+		return fmt.Errorf("cannot write an nfo without program information")
+	}
+
+	details := g.details(info)
+	if isMovie(info) {
+		details.XMLName.Local = "movie"
+		details.Outline = g.description(info, "description100")
+		if info.Movie != nil {
+			details.Runtime = info.Movie.Duration / 60
+			for _, rating := range (StarRatingNormalizer{Scale: TenStarScale}).All(info.Movie) {
+				if details.Ratings == nil {
+					details.Ratings = &nfoRatings{}
+				}
+				details.Ratings.Ratings = append(details.Ratings.Ratings, nfoRating{Name: rating.RatingsBody, Max: rating.Scale, Value: rating.Stars})
+			}
+		}
+		details.Thumbs, details.Fanart = g.posterAndFanart(programArtwork(info, g.Artwork))
+	} else {
+		details.XMLName.Local = "episodedetails"
+		details.ShowTitle = details.Title
+		if info.EpisodeTitle150 != "" {
+			details.Title = info.EpisodeTitle150
+		}
+		details.Season, details.Episode = info.SeasonEpisode()
+		details.Aired = details.Premiered
+		details.Premiered, details.Year = "", 0
+		// Prefer a still of the episode itself over the artwork of its series.
+		landscape := func(a Artwork) bool { return a.Aspect == SixteenByNineAspectRatio }
+		stills := make([]Artwork, 0)
+		if info.EpisodeImage != nil {
+			stills = append(stills, *info.EpisodeImage)
+		}
+		if len(info.ProgramID) >= 10 {
+			stills = append(append(stills, g.Artwork[info.ProgramID]...), g.Artwork[info.ProgramID[:10]]...)
+		}
+		thumb := selectArtwork(stills, landscape)
+		if thumb == nil {
+			thumb = selectArtwork(programArtwork(info, g.Artwork), landscape)
+		}
+		if thumb != nil {
+			details.Thumbs = []nfoThumb{{URL: g.imageURL(thumb.URI)}}
+		}
+	}
+	if details.Runtime == 0 {
+		details.Runtime = info.Duration / 60
+	}
+
+	return writeNFO(w, details)
+}
+
+// WriteTVShowNFO writes the tvshow NFO of the series the episode belongs to. The series is looked up in Programs
+// by its SH program ID, falling back to the titles, genres and cast of the episode if it is missing.
+func (g NFOGenerator) WriteTVShowNFO(w io.Writer, episode *ProgramInfo) error {
+	if episode == nil {
+		return fmt.Errorf("cannot write an nfo without program information")
+	}
+
+	series := g.series(episode)
+	details := g.details(series)
+	details.XMLName.Local = "tvshow"
+	if series == episode {
+		// The plot, ratings and dates of an episode do not describe the show.
+		details.Plot, details.MPAA, details.Premiered, details.Year = "", "", "", 0
+		details.UniqueID.Value = episode.ShowID()
+		if details.UniqueID.Value == "" {
+			details.UniqueID.Value = episode.ProgramID
+		}
+	}
+
+	lookup := &ProgramInfo{ProgramID: details.UniqueID.Value}
+	details.Thumbs, details.Fanart = g.posterAndFanart(programArtwork(lookup, g.Artwork))
+
+	return writeNFO(w, details)
+}
+
+// RecordingName returns the path of a recording of the program relative to the library root, without an extension.
+//
+// Episodes are named "Show (Year)/Season 01/Show - S01E02 - Title", the year being the original air date of the series
+// if it is known. Episodes without a season and episode number are named by date, "Show/Season 2015/Show - 2015-03-03 - Title",
+// using the original air date or, if it is unknown, the time of the recording. Media servers treat season 0 as specials,
+// so episodes numbered without a season are named by date too. Movies are named "Movie (Year)/Movie (Year)".
+func (g NFOGenerator) RecordingName(info *ProgramInfo, recorded time.Time) string {
+	title := nfoFileName(info.Title())
+	if title == "" {
+		title = info.ProgramID
+	}
+
+	if isMovie(info) {
+		if info.Movie != nil && info.Movie.Year != nil && info.Movie.Year.Time != nil {
+			title = fmt.Sprintf("%s (%d)", title, info.Movie.Year.Year())
+		}
+		return path.Join(title, title)
+	}
+
+	show := title
+	if series := g.series(info); series != info && series.OriginalAirDate != nil && series.OriginalAirDate.Time != nil {
+		show = fmt.Sprintf("%s (%d)", title, series.OriginalAirDate.Year())
+	}
+
+	episodeTitle := nfoFileName(info.EpisodeTitle150)
+	name := func(parts ...string) string {
+		if episodeTitle != "" {
+			parts = append(parts, episodeTitle)
+		}
+		return strings.Join(parts, " - ")
+	}
+
+	if season, episode := info.SeasonEpisode(); season > 0 && episode > 0 {
+		return path.Join(show, fmt.Sprintf("Season %02d", season), name(title, fmt.Sprintf("S%02dE%02d", season, episode)))
+	}
+
+	aired := recorded
+	if info.OriginalAirDate != nil && info.OriginalAirDate.Time != nil {
+		aired = *info.OriginalAirDate.Time
+	}
+	return path.Join(show, fmt.Sprintf("Season %d", aired.Year()), name(title, aired.Format("2006-01-02")))
+}
+
+// series returns the program information of the series of the episode, or the episode itself if it is unknown.
+func (g NFOGenerator) series(info *ProgramInfo) *ProgramInfo {
+	if len(info.ProgramID) >= 10 {
+		if series, ok := g.Programs[info.ShowID()]; ok && series != nil {
+			return series
+		}
+	}
+	return info
+}
+
+// details returns the elements common to all NFO files of the program.
+func (g NFOGenerator) details(info *ProgramInfo) nfoDetails {
+	details := nfoDetails{
+		Title:     info.Title(),
+		Plot:      info.Description(g.DescriptionLanguages...),
+		MPAA:      g.rating(info),
+		UniqueID:  nfoUniqueID{Type: "schedulesdirect", Default: true, Value: info.ProgramID},
+		Genres:    append([]string{}, info.Genres...),
+		Credits:   make([]string, 0),
+		Directors: make([]string, 0),
+		Actors:    make([]nfoActor, 0),
+	}
+
+	if info.OriginalAirDate != nil && info.OriginalAirDate.Time != nil {
+		details.Premiered = info.OriginalAirDate.Format("2006-01-02")
+		details.Year = info.OriginalAirDate.Year()
+	} else if info.Movie != nil && info.Movie.Year != nil && info.Movie.Year.Time != nil {
+		details.Year = info.Movie.Year.Year()
+	}
+
+	for _, person := range info.Crew {
+		switch role := strings.ToLower(person.Role); {
+		case role == "director":
+			details.Directors = append(details.Directors, person.Name)
+		case strings.Contains(role, "writer") || strings.Contains(role, "screenwriter"):
+			details.Credits = append(details.Credits, person.Name)
+		}
+	}
+	// Billing orders start at 1, actors without one are numbered after the highest known order.
+	lastOrder := 0
+	for _, person := range info.Cast {
+		if order, err := strconv.Atoi(person.BillingOrder); err == nil && order > lastOrder {
+			lastOrder = order
+		}
+	}
+	for _, person := range info.Cast {
+		order, err := strconv.Atoi(person.BillingOrder)
+		if err != nil {
+			lastOrder++
+			order = lastOrder
+		}
+		details.Actors = append(details.Actors, nfoActor{Name: person.Name, Role: person.CharacterName, Order: order})
+	}
+
+	return details
+}
+
+// description returns the description of the given length, e.g. description100, in the preferred languages.
+func (g NFOGenerator) description(info *ProgramInfo, key string) string {
+	short := &ProgramInfo{Descriptions: map[string][]Description{key: info.Descriptions[key]}}
+	return short.Description(g.DescriptionLanguages...)
+}
+
+// rating returns the code of the first known content rating of the preferred country, then of any country.
+func (g NFOGenerator) rating(info *ProgramInfo) string {
+	country := g.Country
+	if country == "" {
+		country = "USA"
+	}
+
+	fallback := ""
+	for _, rating := range info.ContentRating {
+		normalized := NormalizeContentRating(rating)
+		if !normalized.Known {
+			continue
+		}
+		if strings.EqualFold(rating.Country, country) {
+			return rating.Code
+		}
+		if fallback == "" {
+			fallback = rating.Code
+		}
+	}
+	return fallback
+}
+
+// posterAndFanart returns the poster and banner thumbs and the fanart of the artwork.
+func (g NFOGenerator) posterAndFanart(artwork []Artwork) ([]nfoThumb, *nfoFanart) {
+	thumbs := make([]nfoThumb, 0, 2)
+	if poster := selectArtwork(artwork, func(a Artwork) bool { return a.Aspect == TwoByThreeAspectRatio }); poster != nil {
+		thumbs = append(thumbs, nfoThumb{Aspect: "poster", URL: g.imageURL(poster.URI)})
+	}
+	if banner := selectArtwork(artwork, func(a Artwork) bool {
+		return strings.HasPrefix(string(a.Category), "Banner") && a.Aspect == SixteenByNineAspectRatio
+	}); banner != nil {
+		thumbs = append(thumbs, nfoThumb{Aspect: "landscape", URL: g.imageURL(banner.URI)})
+	}
+
+	var fanart *nfoFanart
+	if background := selectArtwork(artwork, func(a Artwork) bool { return a.Aspect == SixteenByNineAspectRatio && !a.Text.bool }); background != nil {
+		fanart = &nfoFanart{Thumbs: []nfoThumb{{URL: g.imageURL(background.URI)}}}
+	}
+	return thumbs, fanart
+}
+
+func (g NFOGenerator) imageURL(uri string) string {
+	if g.ImageURL != nil {
+		return g.ImageURL(uri)
+	}
+	return uri
+}
+
+func writeNFO(w io.Writer, details nfoDetails) error {
+	if _, err := io.WriteString(w, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+"\n"); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(details); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// nfoFileName makes a title safe to use as a file or directory name on common file systems.
+func nfoFileName(title string) string {
+	title = strings.NewReplacer(": ", " - ", ":", "-", "/", "-", "\\", "-", "<", "", ">", "", `"`, "'", "|", "-", "?", "", "*", "").Replace(title)
+	return strings.Trim(strings.Join(strings.Fields(title), " "), ". ")
+}
@@ -0,0 +1,176 @@
+package schedulesdirect
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testNFOPrograms(t *testing.T) map[string]*ProgramInfo {
+	t.Helper()
+
+	programs := make([]ProgramInfo, 0)
+	if err := json.Unmarshal([]byte(`[
+		{
+			"programID": "EP000000060003",
+			"titles": [{"title120": "'Allo 'Allo!"}],
+			"episodeTitle150": "The Nicked Airmen",
+			"descriptions": {"description1000": [{"descriptionLanguage": "en", "description": "René hides two airmen."}]},
+			"originalAirDate": "1984-11-19",
+			"genres": ["Sitcom"],
+			"duration": 1800,
+			"metadata": [{"TheTVDB": {"season": 9, "episode": 9}}, {"Gracenote": {"season": 2, "episode": 4}}],
+			"contentRating": [{"body": "British Board of Film Classification", "code": "PG", "country": "GBR"}, {"body": "USA Parental Rating", "code": "TVPG", "country": "USA"}],
+			"cast": [{"name": "Gorden Kaye", "role": "Actor", "characterName": "René Artois", "billingOrder": "01"}, {"name": "Carmen Silvera", "role": "Actor"}],
+			"crew": [{"name": "David Croft", "role": "Director"}, {"name": "Jeremy Lloyd", "role": "Writer"}, {"name": "Someone", "role": "Producer"}]
+		},
+		{
+			"programID": "SH000000060000",
+			"titles": [{"title120": "'Allo 'Allo!"}],
+			"descriptions": {"description100": [{"descriptionLanguage": "en", "description": "A café in occupied France."}]},
+			"originalAirDate": "1982-12-30",
+			"genres": ["Sitcom"]
+		},
+		{
+			"programID": "MV000000010000",
+			"entityType": "Movie",
+			"titles": [{"title120": "Alien: Resurrection"}],
+			"descriptions": {
+				"description100": [{"descriptionLanguage": "en", "description": "Ripley is cloned."}],
+				"description1000": [{"descriptionLanguage": "en", "description": "Ripley is cloned 200 years later."}]
+			},
+			"movie": {"year": "1997", "duration": 6540, "qualityRating": [{"ratingsBody": "Gracenote", "rating": "2.5", "minRating": "1", "maxRating": "4", "increment": ".5"}]}
+		}
+	]`), &programs); err != nil {
+		t.Fatal(err)
+	}
+	return IndexProgramInfo(programs)
+}
+
+func TestNFOGeneratorEpisode(t *testing.T) {
+	programs := testNFOPrograms(t)
+	generator := NFOGenerator{
+		Programs: programs,
+		Artwork: map[string][]Artwork{
+			"EP00000006": {{Aspect: SixteenByNineAspectRatio, URI: "assets/ep.jpg"}},
+			"SH00000006": {
+				{Aspect: TwoByThreeAspectRatio, URI: "assets/poster.jpg", Category: Iconic},
+				{Aspect: SixteenByNineAspectRatio, URI: "assets/banner.jpg", Category: BannerL1, Text: ConvertibleBoolean{bool: true}},
+				{Aspect: SixteenByNineAspectRatio, URI: "assets/iconic.jpg", Category: Iconic},
+			},
+		},
+		ImageURL: func(uri string) string { return "https://images.example.com/" + uri },
+	}
+
+	buf := &bytes.Buffer{}
+	if err := generator.WriteNFO(buf, programs["EP000000060003"]); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<episodedetails>
+  <title>The Nicked Airmen</title>
+  <showtitle>&#39;Allo &#39;Allo!</showtitle>
+  <season>2</season>
+  <episode>4</episode>
+  <plot>René hides two airmen.</plot>
+  <runtime>30</runtime>
+  <thumb>https://images.example.com/assets/ep.jpg</thumb>
+  <mpaa>TVPG</mpaa>
+  <uniqueid type="schedulesdirect" default="true">EP000000060003</uniqueid>
+  <genre>Sitcom</genre>
+  <credits>Jeremy Lloyd</credits>
+  <director>David Croft</director>
+  <aired>1984-11-19</aired>
+  <actor>
+    <name>Gorden Kaye</name>
+    <role>René Artois</role>
+    <order>1</order>
+  </actor>
+  <actor>
+    <name>Carmen Silvera</name>
+    <order>2</order>
+  </actor>
+</episodedetails>
+`, buf.String())
+
+	buf.Reset()
+	if err := generator.WriteTVShowNFO(buf, programs["EP000000060003"]); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<tvshow>
+  <title>&#39;Allo &#39;Allo!</title>
+  <plot>A café in occupied France.</plot>
+  <thumb aspect="poster">https://images.example.com/assets/poster.jpg</thumb>
+  <thumb aspect="landscape">https://images.example.com/assets/banner.jpg</thumb>
+  <fanart>
+    <thumb>https://images.example.com/assets/iconic.jpg</thumb>
+  </fanart>
+  <uniqueid type="schedulesdirect" default="true">SH000000060000</uniqueid>
+  <genre>Sitcom</genre>
+  <premiered>1982-12-30</premiered>
+  <year>1982</year>
+</tvshow>
+`, buf.String())
+
+	assert.Equal(t, "'Allo 'Allo! (1982)/Season 02/'Allo 'Allo! - S02E04 - The Nicked Airmen", generator.RecordingName(programs["EP000000060003"], time.Time{}))
+
+	buf.Reset()
+	assert.NoError(t, NFOGenerator{}.WriteTVShowNFO(buf, programs["EP000000060003"]))
+	assert.Contains(t, buf.String(), "<uniqueid type=\"schedulesdirect\" default=\"true\">SH000000060000</uniqueid>")
+	assert.NotContains(t, buf.String(), "<plot>")
+	assert.Contains(t, buf.String(), "<name>Gorden Kaye</name>")
+
+	assert.Error(t, generator.WriteNFO(buf, nil))
+}
+
+func TestNFOGeneratorMovie(t *testing.T) {
+	programs := testNFOPrograms(t)
+	generator := NFOGenerator{Programs: programs}
+
+	buf := &bytes.Buffer{}
+	if err := generator.WriteNFO(buf, programs["MV000000010000"]); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<movie>
+  <title>Alien: Resurrection</title>
+  <ratings>
+    <rating name="Gracenote" max="10">
+      <value>5</value>
+    </rating>
+  </ratings>
+  <outline>Ripley is cloned.</outline>
+  <plot>Ripley is cloned 200 years later.</plot>
+  <runtime>109</runtime>
+  <uniqueid type="schedulesdirect" default="true">MV000000010000</uniqueid>
+  <year>1997</year>
+</movie>
+`, buf.String())
+
+	assert.Equal(t, "Alien - Resurrection (1997)/Alien - Resurrection (1997)", generator.RecordingName(programs["MV000000010000"], time.Time{}))
+}
+
+func TestNFOGeneratorRecordingName(t *testing.T) {
+	recorded := time.Date(2015, 3, 3, 20, 0, 0, 0, time.UTC)
+	generator := NFOGenerator{}
+
+	assert.Equal(t, "News at 6/Season 2015/News at 6 - 2015-03-03", generator.RecordingName(&ProgramInfo{
+		ProgramID: "EP000000070001",
+		Titles:    []Title{{Title120: "News at 6"}},
+	}, recorded))
+
+	// Without a season the episode would be filed as a special, so it is named by date.
+	assert.Equal(t, "What - If/Season 2015/What - If - 2015-03-03 - Who is it", generator.RecordingName(&ProgramInfo{
+		ProgramID:       "EP000000080001",
+		Titles:          []Title{{Title120: " What: If "}},
+		EpisodeTitle150: "Who is it?",
+		Metadata:        []map[string]Metadata{{"Gracenote": {Episode: 1}}},
+	}, recorded))
+
+	assert.Equal(t, "'Allo 'Allo! (1982)/Season 02/'Allo 'Allo! - S02E04 - The Nicked Airmen",
+		NFOGenerator{Programs: testNFOPrograms(t)}.RecordingName(testNFOPrograms(t)["EP000000060003"], recorded))
+}
@@ -0,0 +1,160 @@
+package schedulesdirect
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RequestMetrics describes a single HTTP request made to Schedules Direct.
+type RequestMetrics struct {
+	// Method is the HTTP method of the request.
+	Method string
+	// Endpoint is the API path of the request with any IDs removed, e.g. "/lineups" or "/metadata/programs".
+	// It is safe to use as a metric label.
+	Endpoint string
+	// StatusCode is the HTTP status code of the response, or 0 if no response was received.
+	StatusCode int
+	// ErrorCode is the Schedules Direct error code of the response.
+	ErrorCode ErrorCode
+	// Err is the error the request failed with, if any.
+	Err error
+	// Latency is the time taken from sending the request until the response body was read.
+	Latency time.Duration
+	// CompressedBytes is the number of bytes read off the wire.
+	CompressedBytes int64
+	// UncompressedBytes is the number of bytes after decompression.
+	// It equals CompressedBytes if the response was not compressed.
+	UncompressedBytes int64
+}
+
+// A MetricsHook is notified of every HTTP request made by a Client.
+// It can be used to feed Prometheus or any other metrics system.
+//
+// RequestCompleted is called synchronously so it should return quickly.
+type MetricsHook interface {
+	RequestCompleted(metrics RequestMetrics)
+}
+
+// MetricsHookFunc is an adapter to allow the use of ordinary functions as a MetricsHook.
+type MetricsHookFunc func(metrics RequestMetrics)
+
+// RequestCompleted calls f(metrics).
+func (f MetricsHookFunc) RequestCompleted(metrics RequestMetrics) {
+	f(metrics)
+}
+
+// endpointForURL returns the endpoint, without any IDs, that u points at.
+func endpointForURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	if u.Host == "s3.amazonaws.com" {
+		return "/image"
+	}
+
+	path := u.Path
+	if idx := strings.Index(path, "/"+APIVersion); idx >= 0 {
+		path = path[idx+len(APIVersion)+1:]
+	}
+
+	// The API paths Schedules Direct serves, longest first so the most specific wins.
+	knownEndpoints := []string{
+		"/available/transmitters",
+		"/available/countries",
+		"/available/languages",
+		"/metadata/stillRunning",
+		"/metadata/description",
+		"/metadata/celebrity",
+		"/metadata/programs",
+		"/lineups/preview",
+		"/available/dvb-s",
+		"/schedules/md5",
+		"/map/lineup",
+		"/schedules",
+		"/available",
+		"/headends",
+		"/messages",
+		"/programs",
+		"/lineups",
+		"/status",
+		"/token",
+		"/image",
+		"/xref",
+	}
+
+	for _, endpoint := range knownEndpoints {
+		if path == endpoint || strings.HasPrefix(path, endpoint+"/") {
+			return endpoint
+		}
+	}
+
+	return "other"
+}
+
+// redactSecret hides all but the first few characters of a token or password hash.
+func redactSecret(secret string) string {
+	if len(secret) <= 4 {
+		return "[REDACTED]"
+	}
+	return secret[:4] + "[REDACTED]"
+}
+
+// redactedHeaders returns a copy of the headers that is safe to log.
+func redactedHeaders(header http.Header) http.Header {
+	redacted := header.Clone()
+	if redacted.Get("token") != "" {
+		redacted.Set("token", redactSecret(redacted.Get("token")))
+	}
+	return redacted
+}
+
+// log logs msg with the given attributes if the client has a Logger.
+func (c Client) log(level slog.Level, msg string, args ...any) {
+	if c.Logger == nil {
+		return
+	}
+	c.Logger.Log(context.Background(), level, msg, args...)
+}
+
+// observe logs the request and passes its metrics to the client's MetricsHook.
+func (c Client) observe(metrics RequestMetrics) {
+	level := slog.LevelDebug
+	args := []any{
+		slog.String("method", metrics.Method),
+		slog.String("endpoint", metrics.Endpoint),
+		slog.Int("status", metrics.StatusCode),
+		slog.Duration("latency", metrics.Latency),
+		slog.Int64("compressedBytes", metrics.CompressedBytes),
+		slog.Int64("uncompressedBytes", metrics.UncompressedBytes),
+	}
+	if metrics.ErrorCode != ErrOK {
+		args = append(args, slog.String("errorCode", metrics.ErrorCode.InternalCode()))
+	}
+	if metrics.Err != nil {
+		level = slog.LevelWarn
+		args = append(args, slog.String("error", metrics.Err.Error()))
+	}
+	c.log(level, "schedules direct request completed", args...)
+
+	if c.Metrics != nil {
+		c.Metrics.RequestCompleted(metrics)
+	}
+}
+
+// countingReader counts the bytes read from the underlying reader.
+type countingReader struct {
+	io.Reader
+	count int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
@@ -0,0 +1,119 @@
+package schedulesdirect
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointForURL(t *testing.T) {
+	for raw, expected := range map[string]string{
+		"https://json.schedulesdirect.org/20141201/lineups/USA-OTA-10001":         "/lineups",
+		"https://json.schedulesdirect.org/20141201/lineups/preview/USA-OTA-10001": "/lineups/preview",
+		"https://json.schedulesdirect.org/20141201/metadata/stillRunning/SP1":     "/metadata/stillRunning",
+		"https://json.schedulesdirect.org/20141201/schedules/md5":                 "/schedules/md5",
+		"https://json.schedulesdirect.org/20141201/headends?country=USA":          "/headends",
+		"https://s3.amazonaws.com/schedulesdirect/assets/p1.jpg":                  "/image",
+		"https://json.schedulesdirect.org/20141201/unknown":                       "other",
+	} {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, expected, endpointForURL(u), raw)
+	}
+}
+
+func TestSendRequestMetrics(t *testing.T) {
+	mux, client := setup()
+
+	payload := []byte(`[{"programID":"program1","md5":"edbb1c792032ba8685fd021c28c6ea74"}]`)
+
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/programs"),
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			if _, err := gz.Write(payload); err != nil {
+				t.Fatal(err)
+			}
+			if err := gz.Close(); err != nil {
+				t.Fatal(err)
+			}
+		},
+	)
+
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/lineups/CAN-0000001-X"),
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, getBaseResponse(ErrDuplicateLineup))
+		},
+	)
+
+	observed := make([]RequestMetrics, 0)
+	client.Metrics = MetricsHookFunc(func(metrics RequestMetrics) {
+		observed = append(observed, metrics)
+	})
+
+	if _, err := client.GetProgramInfo([]string{"program1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, errAddLineup := client.AddLineup("CAN-0000001-X")
+	ensureError(t, errAddLineup, ErrDuplicateLineup)
+
+	if assert.Len(t, observed, 2) {
+		assert.Equal(t, "/programs", observed[0].Endpoint)
+		assert.Equal(t, "POST", observed[0].Method)
+		assert.Equal(t, http.StatusOK, observed[0].StatusCode)
+		assert.Equal(t, int64(len(payload)), observed[0].UncompressedBytes)
+		assert.NotEqual(t, observed[0].UncompressedBytes, observed[0].CompressedBytes)
+		assert.NoError(t, observed[0].Err)
+
+		assert.Equal(t, "/lineups", observed[1].Endpoint)
+		assert.Equal(t, "PUT", observed[1].Method)
+		assert.Equal(t, ErrDuplicateLineup, observed[1].ErrorCode)
+		assert.Error(t, observed[1].Err)
+	}
+}
+
+func TestLoggingRedactsSecrets(t *testing.T) {
+	mux, client := setup()
+
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/token"),
+		func(w http.ResponseWriter, r *http.Request) {
+			baseResp := getBaseResponse(ErrOK)
+			fmt.Fprintf(w, `%s, "token": "f3e2a1b0c9d8e7f6a5b4c3d2e1f0a9b8c7d6e5f4"}`, baseResp[:len(baseResp)-1])
+		},
+	)
+
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/status"),
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"systemStatus":[{"status":"Online"}],"code":0}`)
+		},
+	)
+
+	logs := &bytes.Buffer{}
+	client.Logger = slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	token, err := client.GetToken("user1", "testpassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Token = token
+
+	if _, err := client.GetStatus(); err != nil {
+		t.Fatal(err)
+	}
+
+	output := logs.String()
+	assert.True(t, strings.Contains(output, "received schedules direct token"))
+	assert.True(t, strings.Contains(output, "endpoint=/status"))
+	assert.False(t, strings.Contains(output, token))
+	assert.False(t, strings.Contains(output, "8bb6118f8fd6935ad0876a3be34a717d32708ffd"))
+}
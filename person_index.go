@@ -0,0 +1,260 @@
+package schedulesdirect
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CreditType is whether a credit is for a cast or a crew member.
+type CreditType string
+
+const (
+	// CastCredit means the person appears in the program.
+	CastCredit CreditType = "Cast"
+	// CrewCredit means the person worked on the program.
+	CrewCredit CreditType = "Crew"
+)
+
+// A Credit is a single role of a person in a program.
+type Credit struct {
+	ProgramID     string
+	Program       *ProgramInfo
+	Type          CreditType
+	Role          string
+	CharacterName string
+	BillingOrder  string
+}
+
+// A PersonAward is an award given to a person for a program.
+type PersonAward struct {
+	ProgramID string
+	Award     Award
+}
+
+// A Filmography is everything known about a person across the indexed programs.
+type Filmography struct {
+	PersonID string
+	NameIDs  []string
+	Name     string
+	Credits  []Credit
+	Awards   []PersonAward
+	// Headshot is only set once fetched with PersonIndex.FetchHeadshots.
+	Headshot *Artwork
+}
+
+// ProgramIDs returns the IDs of every program the person is credited in.
+func (f Filmography) ProgramIDs() []string {
+	ids := make([]string, 0, len(f.Credits))
+	for _, credit := range f.Credits {
+		ids = append(ids, credit.ProgramID)
+	}
+	return dedupeStrings(ids)
+}
+
+// A PersonIndex relates people to the programs they are credited or awarded in.
+// It is safe for concurrent use.
+type PersonIndex struct {
+	mu      sync.RWMutex
+	people  map[string]*Filmography
+	airings map[string][]Airing
+	// airingKeys are the airings already added.
+	airingKeys map[AiringKey]bool
+}
+
+// NewPersonIndex returns an empty PersonIndex.
+func NewPersonIndex() *PersonIndex {
+	return &PersonIndex{
+		people:     make(map[string]*Filmography),
+		airings:    make(map[string][]Airing),
+		airingKeys: make(map[AiringKey]bool),
+	}
+}
+
+// AddPrograms indexes the cast, crew and awards of the given programs,
+// replacing anything previously indexed for the same program IDs.
+func (i *PersonIndex) AddPrograms(programs ...ProgramInfo) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for idx := range programs {
+		program := programs[idx]
+		i.removeProgram(program.ProgramID)
+
+		for _, person := range program.Cast {
+			i.addCredit(person, Credit{ProgramID: program.ProgramID, Program: &program, Type: CastCredit})
+		}
+		for _, person := range program.Crew {
+			i.addCredit(person, Credit{ProgramID: program.ProgramID, Program: &program, Type: CrewCredit})
+		}
+
+		for _, award := range program.Awards {
+			if award.PersonID == "" {
+				continue
+			}
+			filmography := i.person(award.PersonID, award.Name)
+			filmography.Awards = append(filmography.Awards, PersonAward{ProgramID: program.ProgramID, Award: award})
+		}
+	}
+}
+
+// AddAirings makes the airings available to UpcomingAirings. Airings which were already added are ignored.
+func (i *PersonIndex) AddAirings(airings []Airing) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, airing := range airings {
+		if i.airingKeys[airing.Key()] {
+			continue
+		}
+		i.airingKeys[airing.Key()] = true
+		i.airings[airing.Program.ProgramID] = append(i.airings[airing.Program.ProgramID], airing)
+	}
+}
+
+// Filmography returns everything known about the person with the given ID, or nil.
+func (i *PersonIndex) Filmography(personID string) *Filmography {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	filmography, ok := i.people[personID]
+	if !ok {
+		return nil
+	}
+
+	copied := *filmography
+	copied.Credits = append([]Credit{}, filmography.Credits...)
+	copied.Awards = append([]PersonAward{}, filmography.Awards...)
+	return &copied
+}
+
+// FindByName returns the IDs of people whose name contains name, ignoring case.
+func (i *PersonIndex) FindByName(name string) []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	name = strings.ToLower(name)
+	ids := make([]string, 0)
+	for id, filmography := range i.people {
+		if strings.Contains(strings.ToLower(filmography.Name), name) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// UpcomingAirings returns the airings of programs the person is credited in which end after the given time.
+func (i *PersonIndex) UpcomingAirings(personID string, after time.Time) []Airing {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	upcoming := make([]Airing, 0)
+
+	filmography, ok := i.people[personID]
+	if !ok {
+		return upcoming
+	}
+
+	seen := make(map[AiringKey]bool)
+	for _, credit := range filmography.Credits {
+		for _, airing := range i.airings[credit.ProgramID] {
+			if !airing.End().After(after) || seen[airing.Key()] {
+				continue
+			}
+			seen[airing.Key()] = true
+			if airing.Info == nil {
+				airing.Info = credit.Program
+			}
+			upcoming = append(upcoming, airing)
+		}
+	}
+	sortAirings(upcoming)
+
+	return upcoming
+}
+
+// FetchHeadshots fetches the celebrity artwork of the given people and attaches their headshot.
+// People without a headshot are left untouched.
+func (i *PersonIndex) FetchHeadshots(c *Client, personIDs ...string) error {
+	for _, personID := range personIDs {
+		artwork, err := c.GetCelebrityArtwork(personID)
+		if err != nil {
+			return err
+		}
+
+		headshot := selectArtwork(artwork, func(a Artwork) bool { return a.Category == PhotoHeadshot })
+		if headshot == nil {
+			continue
+		}
+
+		i.mu.Lock()
+		if filmography, ok := i.people[personID]; ok {
+			filmography.Headshot = headshot
+		}
+		i.mu.Unlock()
+	}
+	return nil
+}
+
+func (i *PersonIndex) addCredit(person Person, credit Credit) {
+	personID := person.PersonID
+	if personID == "" {
+		personID = person.NameID
+	}
+	if personID == "" {
+		return
+	}
+
+	credit.Role = person.Role
+	credit.CharacterName = person.CharacterName
+	credit.BillingOrder = person.BillingOrder
+
+	filmography := i.person(personID, person.Name)
+	if person.NameID != "" && !containsString(filmography.NameIDs, person.NameID) {
+		filmography.NameIDs = append(filmography.NameIDs, person.NameID)
+	}
+	filmography.Credits = append(filmography.Credits, credit)
+}
+
+func (i *PersonIndex) person(personID, name string) *Filmography {
+	filmography, ok := i.people[personID]
+	if !ok {
+		filmography = &Filmography{
+			PersonID: personID,
+			NameIDs:  make([]string, 0),
+			Credits:  make([]Credit, 0),
+			Awards:   make([]PersonAward, 0),
+		}
+		i.people[personID] = filmography
+	}
+	if filmography.Name == "" {
+		filmography.Name = name
+	}
+	return filmography
+}
+
+func (i *PersonIndex) removeProgram(programID string) {
+	for personID, filmography := range i.people {
+		credits := filmography.Credits[:0]
+		for _, credit := range filmography.Credits {
+			if credit.ProgramID != programID {
+				credits = append(credits, credit)
+			}
+		}
+		filmography.Credits = credits
+
+		awards := filmography.Awards[:0]
+		for _, award := range filmography.Awards {
+			if award.ProgramID != programID {
+				awards = append(awards, award)
+			}
+		}
+		filmography.Awards = awards
+
+		if len(filmography.Credits) == 0 && len(filmography.Awards) == 0 {
+			delete(i.people, personID)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package schedulesdirect
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testPersonIndex() *PersonIndex {
+	index := NewPersonIndex()
+	index.AddPrograms(
+		ProgramInfo{
+			ProgramID: "EP000000060003",
+			Titles:    []Title{{Title120: "'Allo 'Allo!"}},
+			Cast: []Person{
+				{PersonID: "71581", NameID: "71581", Name: "Gorden Kaye", Role: "Actor", CharacterName: "René Artois", BillingOrder: "01"},
+				{PersonID: "71582", NameID: "71582", Name: "Carmen Silvera", Role: "Actor", CharacterName: "Edith Artois", BillingOrder: "02"},
+			},
+			Crew: []Person{{PersonID: "71581", NameID: "9001", Name: "Gorden Kaye", Role: "Writer"}},
+		},
+		ProgramInfo{
+			ProgramID: "MV000000010000",
+			Titles:    []Title{{Title120: "Brazil"}},
+			Cast:      []Person{{PersonID: "71581", Name: "Gorden Kaye", Role: "Actor", CharacterName: "M.O.I. Lobby Porter"}},
+			Awards:    []Award{{AwardName: "BAFTA", Category: "Best Actor", Name: "Gorden Kaye", PersonID: "71581"}},
+		},
+	)
+	index.AddAirings(AiringsFromSchedules([]Schedule{
+		{StationID: "10001", Programs: []Program{
+			testProgram("EP000000060003", "2015-03-03T20:00:00Z", 1800),
+			testProgram("MV000000010000", "2015-03-05T20:00:00Z", 8400),
+		}},
+		{StationID: "10002", Programs: []Program{
+			testProgram("EP000000060003", "2015-03-01T20:00:00Z", 1800),
+		}},
+	}, nil))
+	return index
+}
+
+func TestPersonIndexFilmography(t *testing.T) {
+	index := testPersonIndex()
+
+	filmography := index.Filmography("71581")
+	if assert.NotNil(t, filmography) {
+		assert.Equal(t, "Gorden Kaye", filmography.Name)
+		assert.Equal(t, []string{"71581", "9001"}, filmography.NameIDs)
+		assert.Equal(t, []string{"EP000000060003", "MV000000010000"}, filmography.ProgramIDs())
+		if assert.Len(t, filmography.Credits, 3) {
+			assert.Equal(t, CastCredit, filmography.Credits[0].Type)
+			assert.Equal(t, "René Artois", filmography.Credits[0].CharacterName)
+			assert.Equal(t, CrewCredit, filmography.Credits[1].Type)
+			assert.Equal(t, "Writer", filmography.Credits[1].Role)
+			assert.Equal(t, "Brazil", filmography.Credits[2].Program.Titles[0].Title120)
+		}
+		if assert.Len(t, filmography.Awards, 1) {
+			assert.Equal(t, "MV000000010000", filmography.Awards[0].ProgramID)
+		}
+	}
+
+	assert.Nil(t, index.Filmography("00000"))
+	assert.Equal(t, []string{"71581"}, index.FindByName("KAYE"))
+	assert.Equal(t, []string{"71582"}, index.FindByName("carmen"))
+
+	index.AddPrograms(ProgramInfo{ProgramID: "EP000000060003", Cast: []Person{{PersonID: "71581", Name: "Gorden Kaye"}}})
+	assert.Len(t, index.Filmography("71581").Credits, 2)
+	assert.Nil(t, index.Filmography("71582"))
+}
+
+func TestPersonIndexUpcomingAirings(t *testing.T) {
+	index := testPersonIndex()
+
+	upcoming := index.UpcomingAirings("71581", time.Date(2015, 3, 2, 0, 0, 0, 0, time.UTC))
+	if assert.Len(t, upcoming, 2) {
+		assert.Equal(t, "EP000000060003", upcoming[0].Program.ProgramID)
+		assert.Equal(t, "'Allo 'Allo!", upcoming[0].Title())
+		assert.Equal(t, "MV000000010000", upcoming[1].Program.ProgramID)
+	}
+
+	assert.Len(t, index.UpcomingAirings("71582", time.Time{}), 2)
+
+	// Adding the same airings again does not duplicate them.
+	index.AddAirings(AiringsFromSchedules([]Schedule{
+		{StationID: "10001", Programs: []Program{testProgram("EP000000060003", "2015-03-03T20:00:00Z", 1800)}},
+	}, nil))
+	assert.Len(t, index.UpcomingAirings("71581", time.Date(2015, 3, 2, 0, 0, 0, 0, time.UTC)), 2)
+	assert.Empty(t, index.UpcomingAirings("00000", time.Time{}))
+}
+
+func TestPersonIndexFetchHeadshots(t *testing.T) {
+	mux, client := setup()
+
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/metadata/celebrity/71581"),
+		func(w http.ResponseWriter, r *http.Request) {
+			ensureMethod(t, r, "GET")
+
+			fmt.Fprint(w, `[{"width":"270","height":"360","uri":"assets/71581_small.jpg","size":"Md","aspect":"3x4","category":"Photo-headshot","primary":"false"},{"width":"540","height":"720","uri":"assets/71581_large.jpg","size":"Lg","aspect":"3x4","category":"Photo-headshot","primary":"false"},{"width":"1280","height":"720","uri":"assets/71581_still.jpg","size":"Lg","aspect":"16x9","category":"Photo","primary":"true"}]`)
+		},
+	)
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/metadata/celebrity/71582"),
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `[]`)
+		},
+	)
+
+	index := testPersonIndex()
+	if err := index.FetchHeadshots(client, "71581", "71582"); err != nil {
+		t.Fatal(err)
+	}
+
+	if headshot := index.Filmography("71581").Headshot; assert.NotNil(t, headshot) {
+		assert.Equal(t, "assets/71581_large.jpg", headshot.URI)
+	}
+	assert.Nil(t, index.Filmography("71582").Headshot)
+}
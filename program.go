@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -68,6 +71,65 @@ func (p *ProgramInfo) ArtworkLookupIDs() []string {
 	return []string{p.ProgramID}
 }
 
+// Title returns the first title of the program, or an empty string if it has none.
+func (p *ProgramInfo) Title() string {
+	if len(p.Titles) == 0 {
+		return ""
+	}
+	return p.Titles[0].Title120
+}
+
+// Description returns the longest description of the program in the first of the given languages which has one.
+// Languages match on their primary subtag, so "en" matches "en-GB". If none of the languages have a description,
+// English is used, then any other language. An empty string is returned if the program has no descriptions.
+func (p *ProgramInfo) Description(languages ...string) string {
+	preferred := append(append([]string{}, languages...), "en", "")
+	for _, language := range preferred {
+		for _, key := range []string{"description1000", "description100"} {
+			for _, description := range p.Descriptions[key] {
+				if description.Description != "" && (language == "" || descriptionLanguageMatches(description.Language, language)) {
+					return description.Description
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// SeasonEpisode returns the season and episode numbers of the program from its metadata, preferring Gracenote's
+// numbering over other providers. Zero is returned for numbers which are unknown.
+func (p *ProgramInfo) SeasonEpisode() (season, episode int) {
+	providers := make([]string, 0)
+	for _, metadata := range p.Metadata {
+		for provider := range metadata {
+			providers = append(providers, provider)
+		}
+	}
+	sort.SliceStable(providers, func(a, b int) bool {
+		if (providers[a] == "Gracenote") != (providers[b] == "Gracenote") {
+			return providers[a] == "Gracenote"
+		}
+		return providers[a] < providers[b]
+	})
+
+	for _, provider := range providers {
+		for _, metadata := range p.Metadata {
+			if numbers, ok := metadata[provider]; ok && numbers.Episode > 0 {
+				return numbers.Season, numbers.Episode
+			}
+		}
+	}
+	return 0, 0
+}
+
+// descriptionLanguageMatches returns true if both languages have the same primary subtag, ignoring case.
+func descriptionLanguageMatches(a, b string) bool {
+	primary := func(language string) string {
+		return strings.ToLower(strings.SplitN(strings.Replace(language, "_", "-", -1), "-", 2)[0])
+	}
+	return primary(a) == primary(b)
+}
+
 // Animation is the type of animation employed by the Program
 type Animation string
 
@@ -155,9 +217,9 @@ type Person struct {
 
 // A ContentRating stores ratings board information for a program
 type ContentRating struct {
-	Body    string `json:"body,omitempty"`
-	Code    string `json:"code,omitempty"`
-	Country string `json:"country,omitempty"`
+	Body    string `json:"body,omitempty" xml:"body,attr,omitempty"`
+	Code    string `json:"code,omitempty" xml:"code,attr,omitempty"`
+	Country string `json:"country,omitempty" xml:"country,attr,omitempty"`
 }
 
 // Description provides a generic description of a program.
@@ -276,6 +338,7 @@ func (c *Client) GetProgramInfo(programIDs []string) ([]ProgramInfo, error) {
 	// chunking the requests for them.
 	// Obviously you can disable this behavior by passing less than 5000 IDs.
 	if len(programIDs) > 5000 {
+		c.log(slog.LevelDebug, "chunking schedules direct request", slog.String("endpoint", "/programs"), slog.Int("ids", len(programIDs)), slog.Int("chunkSize", 5000))
 		allResponses := make([]ProgramInfo, len(programIDs))
 		for _, chunk := range chunkStringSlice(programIDs, 5000) {
 			resp, err := c.GetProgramInfo(chunk)
@@ -325,6 +388,7 @@ func (c *Client) GetProgramDescription(programIDs []string) (map[string]ProgramD
 	// chunking the requests for them.
 	// Obviously you can disable this behavior by passing less than 500 IDs.
 	if len(programIDs) > 500 {
+		c.log(slog.LevelDebug, "chunking schedules direct request", slog.String("endpoint", "/metadata/description"), slog.Int("ids", len(programIDs)), slog.Int("chunkSize", 500))
 		allResponses := make(map[string]ProgramDescription)
 		for _, chunk := range chunkStringSlice(programIDs, 500) {
 			resp, err := c.GetProgramDescription(chunk)
@@ -375,6 +439,7 @@ func (c *Client) GetLanguageCrossReference(programIDs []string) (map[string][]La
 	// chunking the requests for them.
 	// Obviously you can disable this behavior by passing less than 500 IDs.
 	if len(programIDs) > 500 {
+		c.log(slog.LevelDebug, "chunking schedules direct request", slog.String("endpoint", "/xref"), slog.Int("ids", len(programIDs)), slog.Int("chunkSize", 500))
 		allResponses := make(map[string][]LanguageCrossReference)
 		for _, chunk := range chunkStringSlice(programIDs, 500) {
 			resp, err := c.GetLanguageCrossReference(chunk)
@@ -99,3 +99,29 @@ func TestGetProgramInfoFailsDeflateRequired(t *testing.T) {
 	})
 	ensureError(t, err, ErrDeflateRequired)
 }
+
+func TestProgramInfoTitleAndDescription(t *testing.T) {
+	program := &ProgramInfo{
+		Titles: []Title{{Title120: "'Allo 'Allo!"}},
+		Descriptions: map[string][]Description{
+			"description100": {
+				{Language: "en", Description: "Short English description."},
+				{Language: "fr-FR", Description: "Courte description."},
+			},
+			"description1000": {
+				{Language: "en", Description: "Long English description."},
+				{Language: "de", Description: "Lange Beschreibung."},
+			},
+		},
+	}
+
+	assert.Equal(t, "'Allo 'Allo!", program.Title())
+	assert.Equal(t, "Long English description.", program.Description())
+	assert.Equal(t, "Lange Beschreibung.", program.Description("de-DE"))
+	assert.Equal(t, "Courte description.", program.Description("fr", "de"))
+	assert.Equal(t, "Long English description.", program.Description("es"))
+
+	empty := &ProgramInfo{}
+	assert.Equal(t, "", empty.Title())
+	assert.Equal(t, "", empty.Description("en"))
+}
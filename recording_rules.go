@@ -0,0 +1,252 @@
+package schedulesdirect
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A TimeWindow restricts a RecordingRule to airings starting at certain times of day.
+type TimeWindow struct {
+	// From and To are offsets from midnight, e.g. 18*time.Hour for 18:00.
+	// If To is before From the window wraps past midnight.
+	From time.Duration
+	To   time.Duration
+	// Days limits the window to the given weekdays. All days match if empty.
+	Days []time.Weekday
+	// Location is the time zone the window is evaluated in. UTC is used if nil.
+	Location *time.Location
+}
+
+// Contains returns true if t falls inside the window.
+func (w TimeWindow) Contains(t time.Time) bool {
+	loc := w.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+
+	if len(w.Days) > 0 {
+		dayMatch := false
+		for _, day := range w.Days {
+			if t.Weekday() == day {
+				dayMatch = true
+				break
+			}
+		}
+		if !dayMatch {
+			return false
+		}
+	}
+
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.To < w.From {
+		return sinceMidnight >= w.From || sinceMidnight < w.To
+	}
+	return sinceMidnight >= w.From && sinceMidnight < w.To
+}
+
+// A RecordingRule describes which airings should be recorded.
+// Every criteria which is set must match for an airing to be recorded.
+type RecordingRule struct {
+	// Name identifies the rule in a PlannedRecording.
+	Name string
+
+	// ShowID matches airings of the series with the given SH program ID, or the given program ID itself.
+	ShowID string
+	// Title matches airings whose title equals it, ignoring case.
+	Title string
+	// StationIDs matches airings on any of the given stations.
+	StationIDs []string
+	// NewOnly matches only airings flagged as new.
+	NewOnly bool
+	// PremiereTypes matches airings whose IsPremiereOrFinale is any of the given types.
+	PremiereTypes []PremiereType
+	// Genres matches airings which have any of the given genres, ignoring case.
+	Genres []string
+	// Window matches airings which start inside it.
+	Window *TimeWindow
+
+	// FirstAiringOnly records only the earliest airing of each program ID.
+	FirstAiringOnly bool
+
+	// StartPadding and EndPadding are added before the start and after the end of each recording.
+	StartPadding time.Duration
+	EndPadding   time.Duration
+
+	// Priority decides which recordings are kept when there are not enough tuners, higher wins.
+	Priority int
+}
+
+// Validate returns an error if the rule would match every airing.
+func (r RecordingRule) Validate() error {
+	if r.ShowID == "" && r.Title == "" && len(r.StationIDs) == 0 && len(r.Genres) == 0 && len(r.PremiereTypes) == 0 && r.Window == nil && !r.NewOnly {
+		return fmt.Errorf("recording rule %q has no criteria and would record everything", r.Name)
+	}
+	return nil
+}
+
+// Matches returns true if the airing satisfies every criteria of the rule.
+func (r RecordingRule) Matches(airing Airing) bool {
+	if r.ShowID != "" && r.ShowID != airing.ShowID() && r.ShowID != airing.Program.ProgramID {
+		return false
+	}
+
+	if r.Title != "" && !strings.EqualFold(r.Title, airing.Title()) {
+		return false
+	}
+
+	if len(r.StationIDs) > 0 && !containsString(r.StationIDs, airing.StationID) {
+		return false
+	}
+
+	if r.NewOnly && !airing.Program.New {
+		return false
+	}
+
+	if len(r.PremiereTypes) > 0 {
+		if airing.Program.IsPremiereOrFinale == nil {
+			return false
+		}
+		premiereMatch := false
+		for _, premiereType := range r.PremiereTypes {
+			if *airing.Program.IsPremiereOrFinale == premiereType {
+				premiereMatch = true
+				break
+			}
+		}
+		if !premiereMatch {
+			return false
+		}
+	}
+
+	if len(r.Genres) > 0 {
+		if airing.Info == nil {
+			return false
+		}
+		genreMatch := false
+		for _, genre := range airing.Info.Genres {
+			if containsStringFold(r.Genres, genre) {
+				genreMatch = true
+				break
+			}
+		}
+		if !genreMatch {
+			return false
+		}
+	}
+
+	if r.Window != nil && !r.Window.Contains(airing.Start()) {
+		return false
+	}
+
+	return true
+}
+
+// A PlannedRecording is an airing selected for recording by one or more rules.
+type PlannedRecording struct {
+	Airing
+	// Rules are the names of the rules which matched the airing.
+	Rules []string
+	// RecordStart and RecordEnd are the padded recording times.
+	RecordStart time.Time
+	RecordEnd   time.Time
+	// Priority is the highest priority of the rules which matched the airing.
+	Priority int
+}
+
+// EvaluateRecordingRules returns the airings which should be recorded according to rules, sorted by start time.
+//
+// Each airing is returned at most once even if it appears several times in airings or matches several rules.
+// When several rules match, the largest padding and highest priority of any of them is used.
+// An error is returned, and nothing is planned, if any of the rules is invalid.
+func EvaluateRecordingRules(rules []RecordingRule, airings []Airing) ([]PlannedRecording, error) {
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	sorted := make([]Airing, len(airings))
+	copy(sorted, airings)
+	sortAirings(sorted)
+
+	recordings := make([]PlannedRecording, 0)
+	byAiring := make(map[AiringKey]int)
+
+	for _, rule := range rules {
+		seenPrograms := make(map[string]bool)
+
+		for _, airing := range sorted {
+			if airing.Program.AirDateTime == nil || !rule.Matches(airing) {
+				continue
+			}
+
+			if rule.FirstAiringOnly {
+				if seenPrograms[airing.Program.ProgramID] {
+					continue
+				}
+				seenPrograms[airing.Program.ProgramID] = true
+			}
+
+			start := airing.Start().Add(-rule.StartPadding)
+			end := airing.End().Add(rule.EndPadding)
+
+			key := airing.Key()
+			if idx, ok := byAiring[key]; ok {
+				recording := &recordings[idx]
+				if !containsString(recording.Rules, rule.Name) {
+					recording.Rules = append(recording.Rules, rule.Name)
+				}
+				if start.Before(recording.RecordStart) {
+					recording.RecordStart = start
+				}
+				if end.After(recording.RecordEnd) {
+					recording.RecordEnd = end
+				}
+				if rule.Priority > recording.Priority {
+					recording.Priority = rule.Priority
+				}
+				continue
+			}
+
+			byAiring[key] = len(recordings)
+			recordings = append(recordings, PlannedRecording{
+				Airing:      airing,
+				Rules:       []string{rule.Name},
+				RecordStart: start,
+				RecordEnd:   end,
+				Priority:    rule.Priority,
+			})
+		}
+	}
+
+	sortPlannedRecordings(recordings)
+
+	return recordings, nil
+}
+
+func sortPlannedRecordings(recordings []PlannedRecording) {
+	sort.SliceStable(recordings, func(i, j int) bool {
+		return airingLess(recordings[i].Airing, recordings[j].Airing)
+	})
+}
+
+func containsString(sl []string, str string) bool {
+	for _, s := range sl {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+func containsStringFold(sl []string, str string) bool {
+	for _, s := range sl {
+		if strings.EqualFold(s, str) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package schedulesdirect
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testProgram(programID, airDateTime string, duration int) Program {
+	airTime, err := time.Parse(time.RFC3339, airDateTime)
+	if err != nil {
+		panic(err)
+	}
+	return Program{ProgramID: programID, AirDateTime: &airTime, Duration: duration}
+}
+
+func testRecordingAirings() []Airing {
+	premiere := SeasonPremiere
+
+	newEpisode := testProgram("EP000000060003", "2015-03-03T20:00:00Z", 1800)
+	newEpisode.New = true
+	newEpisode.IsPremiereOrFinale = &premiere
+
+	schedules := []Schedule{
+		{StationID: "10001", Programs: []Program{
+			newEpisode,
+			testProgram("EP000000060002", "2015-03-03T20:30:00Z", 1800),
+			testProgram("MV000000010000", "2015-03-03T23:00:00Z", 7200),
+		}},
+		{StationID: "10002", Programs: []Program{
+			testProgram("EP000000060003", "2015-03-04T02:00:00Z", 1800),
+			testProgram("EP000000060002", "2015-03-03T20:30:00Z", 1800),
+			{ProgramID: "EP000000060004"},
+		}},
+	}
+
+	info := IndexProgramInfo([]ProgramInfo{
+		{ProgramID: "EP000000060003", Titles: []Title{{Title120: "'Allo 'Allo!"}}, Genres: []string{"Sitcom"}},
+		{ProgramID: "EP000000060002", Titles: []Title{{Title120: "'Allo 'Allo!"}}, Genres: []string{"Sitcom"}},
+		{ProgramID: "MV000000010000", Titles: []Title{{Title120: "A Movie"}}, Genres: []string{"Action", "Drama"}},
+	})
+
+	return AiringsFromSchedules(schedules, info)
+}
+
+func TestAiringsFromSchedules(t *testing.T) {
+	airings := testRecordingAirings()
+
+	if assert.Len(t, airings, 5) {
+		assert.Equal(t, "10001", airings[0].StationID)
+		assert.Equal(t, "'Allo 'Allo!", airings[0].Title())
+		assert.Equal(t, "SH000000060000", airings[0].ShowID())
+		assert.Equal(t, "MV000000010000", airings[3].ShowID())
+		assert.Equal(t, time.Date(2015, 3, 3, 20, 30, 0, 0, time.UTC), airings[0].End())
+	}
+}
+
+func TestEvaluateRecordingRules(t *testing.T) {
+	rules := []RecordingRule{
+		{Name: "allo", ShowID: "SH000000060000", StartPadding: time.Minute, EndPadding: 2 * time.Minute},
+		{Name: "allo-first", Title: "'allo 'allo!", FirstAiringOnly: true, EndPadding: 5 * time.Minute},
+	}
+
+	recordings, err := EvaluateRecordingRules(rules, testRecordingAirings())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, recordings, 4) {
+		assert.Equal(t, "EP000000060003", recordings[0].Program.ProgramID)
+		assert.Equal(t, []string{"allo", "allo-first"}, recordings[0].Rules)
+		assert.Equal(t, time.Date(2015, 3, 3, 19, 59, 0, 0, time.UTC), recordings[0].RecordStart)
+		assert.Equal(t, time.Date(2015, 3, 3, 20, 35, 0, 0, time.UTC), recordings[0].RecordEnd)
+
+		assert.Equal(t, "10001", recordings[1].StationID)
+		assert.Equal(t, []string{"allo", "allo-first"}, recordings[1].Rules)
+		assert.Equal(t, "10002", recordings[2].StationID)
+		assert.Equal(t, []string{"allo"}, recordings[2].Rules)
+		assert.Equal(t, []string{"allo"}, recordings[3].Rules)
+	}
+}
+
+func TestEvaluateRecordingRulesInvalid(t *testing.T) {
+	rules := []RecordingRule{
+		{Name: "allo", ShowID: "SH000000060000"},
+		{Name: "everything"},
+	}
+
+	recordings, err := EvaluateRecordingRules(rules, testRecordingAirings())
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `"everything"`)
+	}
+	assert.Nil(t, recordings)
+}
+
+func TestRecordingRuleMatches(t *testing.T) {
+	airings := testRecordingAirings()
+
+	premieres := RecordingRule{NewOnly: true, PremiereTypes: []PremiereType{SeasonPremiere, SeriesPremiere}}
+	assert.True(t, premieres.Matches(airings[0]))
+	assert.False(t, premieres.Matches(airings[1]))
+
+	drama := RecordingRule{Genres: []string{"drama"}, StationIDs: []string{"10001"}}
+	assert.True(t, drama.Matches(airings[3]))
+	assert.False(t, drama.Matches(airings[0]))
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	primeTime := RecordingRule{Window: &TimeWindow{From: 18 * time.Hour, To: 23 * time.Hour, Days: []time.Weekday{time.Tuesday}, Location: newYork}}
+	assert.False(t, primeTime.Matches(airings[0]))
+	assert.True(t, primeTime.Matches(airings[3]))
+	assert.True(t, primeTime.Matches(airings[4]))
+
+	lateNight := TimeWindow{From: 22 * time.Hour, To: 2 * time.Hour}
+	assert.True(t, lateNight.Contains(airings[3].Start()))
+	assert.True(t, lateNight.Contains(airings[4].Start().Add(-30*time.Minute)))
+	assert.False(t, lateNight.Contains(airings[4].Start()))
+}
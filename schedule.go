@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -44,13 +45,157 @@ const (
 	Delayed LiveTapeDelay = "Delayed"
 )
 
+// AudioProperty is an audio property of a Program.
+type AudioProperty string
+
+const (
+	// ClosedCaptionedAudio means the program is closed captioned.
+	ClosedCaptionedAudio AudioProperty = "cc"
+	// StereoAudio means the program is in stereo.
+	StereoAudio AudioProperty = "stereo"
+	// DolbyAudio means the program is in Dolby.
+	DolbyAudio AudioProperty = "dolby"
+	// DolbyDigitalAudio means the program is in Dolby Digital.
+	DolbyDigitalAudio AudioProperty = "DD"
+	// DolbyDigital51Audio means the program is in Dolby Digital 5.1.
+	DolbyDigital51Audio AudioProperty = "DD 5.1"
+	// DolbyAtmosAudio means the program is in Dolby Atmos.
+	DolbyAtmosAudio AudioProperty = "Atmos"
+	// SurroundAudio means the program is in surround sound.
+	SurroundAudio AudioProperty = "surround"
+	// DescribedVideoAudio means the program has a descriptive video service (audio description) track.
+	DescribedVideoAudio AudioProperty = "dvs"
+	// SecondaryAudio means the program has a secondary audio program.
+	SecondaryAudio AudioProperty = "SAP"
+	// SubtitledAudio means the program is subtitled.
+	SubtitledAudio AudioProperty = "subtitled"
+	// DubbedAudio means the program is dubbed.
+	DubbedAudio AudioProperty = "dubbed"
+)
+
+// Known returns true if p is one of the AudioProperty constants, ignoring case.
+func (p AudioProperty) Known() bool {
+	switch strings.ToLower(string(p)) {
+	case "cc", "stereo", "dolby", "dd", "dd 5.1", "atmos", "surround", "dvs", "sap", "subtitled", "dubbed":
+		return true
+	}
+	return false
+}
+
+// AudioProperties is a list of audio properties.
+type AudioProperties []AudioProperty
+
+// Has returns true if the list contains property, ignoring case.
+func (p AudioProperties) Has(property AudioProperty) bool {
+	for _, candidate := range p {
+		if strings.EqualFold(string(candidate), string(property)) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalJSON decodes either a list or a single string, keeping unknown values as is.
+func (p *AudioProperties) UnmarshalJSON(data []byte) error {
+	values, err := unmarshalLenientStrings(data)
+	if err != nil {
+		return err
+	}
+	*p = make(AudioProperties, 0, len(values))
+	for _, value := range values {
+		*p = append(*p, AudioProperty(value))
+	}
+	return nil
+}
+
+// VideoProperty is a video property of a Program.
+type VideoProperty string
+
+const (
+	// HDTVVideo means the program is in high definition.
+	HDTVVideo VideoProperty = "hdtv"
+	// UHDTVVideo means the program is in ultra high definition (4K).
+	UHDTVVideo VideoProperty = "uhdtv"
+	// HDRVideo means the program is in high dynamic range.
+	HDRVideo VideoProperty = "hdr"
+	// SDTVVideo means the program is in standard definition.
+	SDTVVideo VideoProperty = "sdtv"
+	// LetterboxVideo means the program is letterboxed.
+	LetterboxVideo VideoProperty = "letterbox"
+	// ThreeDVideo means the program is in 3D.
+	ThreeDVideo VideoProperty = "3d"
+	// EnhancedVideo means the program is in enhanced definition.
+	EnhancedVideo VideoProperty = "enhanced"
+)
+
+// Known returns true if p is one of the VideoProperty constants, ignoring case.
+func (p VideoProperty) Known() bool {
+	switch strings.ToLower(string(p)) {
+	case "hdtv", "uhdtv", "hdr", "sdtv", "letterbox", "3d", "enhanced":
+		return true
+	}
+	return false
+}
+
+// VideoProperties is a list of video properties.
+type VideoProperties []VideoProperty
+
+// Has returns true if the list contains property, ignoring case.
+func (p VideoProperties) Has(property VideoProperty) bool {
+	for _, candidate := range p {
+		if strings.EqualFold(string(candidate), string(property)) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalJSON decodes either a list or a single string, keeping unknown values as is.
+func (p *VideoProperties) UnmarshalJSON(data []byte) error {
+	values, err := unmarshalLenientStrings(data)
+	if err != nil {
+		return err
+	}
+	*p = make(VideoProperties, 0, len(values))
+	for _, value := range values {
+		*p = append(*p, VideoProperty(value))
+	}
+	return nil
+}
+
+// unmarshalLenientStrings decodes a list of strings, a single string or null.
+// Values in the list which are not strings are skipped.
+func unmarshalLenientStrings(data []byte) ([]string, error) {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		if single == "" {
+			return nil, nil
+		}
+		return []string{single}, nil
+	}
+
+	raw := make([]json.RawMessage, 0)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	values := make([]string, 0, len(raw))
+	for _, element := range raw {
+		var value string
+		if err := json.Unmarshal(element, &value); err == nil && value != "" {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
 // A Program stores the information to describing a single television program.
 type Program struct {
 	ProgramID           string           `json:"programID,omitempty"`
 	AirDateTime         *time.Time       `json:"airDateTime,omitempty"`
 	MD5                 string           `json:"md5,omitempty"`
 	Duration            int              `json:"duration,omitempty"`
-	LiveTapeDelay       string           `json:"liveTapeDelay,omitempty"`
+	LiveTapeDelay       LiveTapeDelay    `json:"liveTapeDelay,omitempty"`
 	IsPremiereOrFinale  *PremiereType    `json:"isPremiereOrFinale,omitempty"`
 	New                 bool             `json:"new,omitempty"`
 	CableInTheClassroom bool             `json:"cableInTheClassRoom,omitempty"`
@@ -65,11 +210,11 @@ type Program struct {
 	Signed              bool             `json:"signed,omitempty"`            //- Program has an on-screen person providing sign-language translation.
 	SubjectToBlackout   bool             `json:"subjectToBlackout,omitempty"` //subjectToBlackout
 	TimeApproximate     bool             `json:"timeApproximate,omitempty"`
-	AudioProperties     []string         `json:"audioProperties,omitempty"`
+	AudioProperties     AudioProperties  `json:"audioProperties,omitempty"`
 	Syndication         *SyndicationType `json:"syndication,omitempty"`
 	Ratings             []ContentRating  `json:"ratings,omitempty"`
 	ProgramPart         *Part            `json:"multipart,omitempty"`
-	VideoProperties     []string         `json:"videoProperties,omitempty"`
+	VideoProperties     VideoProperties  `json:"videoProperties,omitempty"`
 }
 
 // ShowID is just a helper wrapper around GetShowIDForEpisodeID.
@@ -77,6 +222,36 @@ func (p *Program) ShowID() string {
 	return GetShowIDForEpisodeID(p.ProgramID)
 }
 
+// IsHD returns true if the program is in high definition, including ultra high definition.
+func (p *Program) IsHD() bool {
+	return p.VideoProperties.Has(HDTVVideo) || p.VideoProperties.Has(UHDTVVideo)
+}
+
+// Is4K returns true if the program is in ultra high definition.
+func (p *Program) Is4K() bool {
+	return p.VideoProperties.Has(UHDTVVideo)
+}
+
+// IsHDR returns true if the program is in high dynamic range.
+func (p *Program) IsHDR() bool {
+	return p.VideoProperties.Has(HDRVideo)
+}
+
+// HasCaptions returns true if the program is closed captioned.
+func (p *Program) HasCaptions() bool {
+	return p.AudioProperties.Has(ClosedCaptionedAudio)
+}
+
+// HasAudioDescription returns true if the program has a descriptive video service track.
+func (p *Program) HasAudioDescription() bool {
+	return p.AudioProperties.Has(DescribedVideoAudio)
+}
+
+// IsLive returns true if the program is being shown in real time.
+func (p *Program) IsLive() bool {
+	return strings.EqualFold(string(p.LiveTapeDelay), string(Live))
+}
+
 // A Schedule stores the program information for a given stationID
 type Schedule struct {
 	StationID string        `json:"stationID,omitempty"`
@@ -86,3 +86,31 @@ func TestGetSchedulesFailsStationNotInLineup(t *testing.T) {
 	})
 	ensureError(t, err, ErrStationIDNotFound)
 }
+
+func TestProgramProperties(t *testing.T) {
+	program := Program{}
+	data := `{"programID":"EP000000060003","liveTapeDelay":"Live","audioProperties":["DD 5.1","CC","dvs","binaural",7],"videoProperties":"UHDTV"}`
+	if err := json.Unmarshal([]byte(data), &program); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, Live, program.LiveTapeDelay)
+	assert.True(t, program.IsLive())
+	assert.Equal(t, AudioProperties{DolbyDigital51Audio, "CC", DescribedVideoAudio, "binaural"}, program.AudioProperties)
+	assert.True(t, program.HasCaptions())
+	assert.True(t, program.HasAudioDescription())
+	assert.True(t, program.IsHD())
+	assert.True(t, program.Is4K())
+	assert.False(t, program.IsHDR())
+
+	assert.True(t, program.AudioProperties[1].Known())
+	assert.False(t, program.AudioProperties[3].Known())
+	assert.True(t, VideoProperty("3D").Known())
+
+	hd := Program{VideoProperties: VideoProperties{HDTVVideo}}
+	assert.True(t, hd.IsHD())
+	assert.False(t, hd.Is4K())
+	assert.False(t, hd.HasCaptions())
+
+	assert.Error(t, json.Unmarshal([]byte(`{"audioProperties":{"cc":true}}`), &program))
+}
@@ -11,6 +11,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"time"
 )
@@ -38,10 +39,21 @@ type Client struct {
 	// The User-Agent to send on every request.
 	UserAgent string
 
+	// Logger receives structured logs about requests, token refreshes, retries, chunking and decompression.
+	// Tokens and password hashes are redacted. Logging is disabled if nil.
+	Logger *slog.Logger
+
+	// Metrics is notified of every request made to Schedules Direct. Disabled if nil.
+	Metrics MetricsHook
+
 	// We store username and password in the client in case we need to attempt a token refresh.
 	username       string
 	password       string
 	failedRequests int
+
+	// The number of lineup changes left for today, as last reported by Schedules Direct.
+	lineupChangesRemaining int
+	lineupChangesKnown     bool
 }
 
 // NewClient returns a new Schedules Direct API client. Uses http.DefaultClient if no http.Client is set.
@@ -92,16 +104,37 @@ func (c *Client) GetToken(username string, password string) (string, error) {
 		return "", httpErr
 	}
 
+	c.log(slog.LevelDebug, "requesting schedules direct token", slog.String("username", username), slog.String("passwordHash", redactSecret(sha1hexPW)))
+
+	metrics := RequestMetrics{Method: req.Method, Endpoint: endpointForURL(req.URL)}
+	token, err := c.getToken(req, &metrics)
+	if metrics.Err == nil {
+		metrics.Err = err
+	}
+	c.observe(metrics)
+
+	return token, err
+}
+
+func (c *Client) getToken(req *http.Request, metrics *RequestMetrics) (string, error) {
+	start := time.Now()
+
 	response, httpErr := c.HTTP.Do(req)
 	if httpErr != nil {
 		return "", fmt.Errorf("cannot reach schedules direct service: %s", httpErr)
 	}
+	metrics.StatusCode = response.StatusCode
 
+	body := &countingReader{Reader: response.Body}
 	buf := &bytes.Buffer{}
-	if _, copyErr := io.Copy(buf, response.Body); copyErr != nil {
+	if _, copyErr := io.Copy(buf, body); copyErr != nil {
 		return "", fmt.Errorf("error when copying bytes of response to buffer: %s", copyErr)
 	}
 
+	metrics.Latency = time.Since(start)
+	metrics.CompressedBytes = body.count
+	metrics.UncompressedBytes = int64(buf.Len())
+
 	if closeErr := response.Body.Close(); closeErr != nil {
 		return "", fmt.Errorf("cannot read response. %v", closeErr)
 	}
@@ -115,11 +148,14 @@ func (c *Client) GetToken(username string, password string) (string, error) {
 	}
 
 	if r.BaseResponse.Code != ErrOK {
+		metrics.ErrorCode = r.BaseResponse.Code
 		return "", r.BaseResponse
 	}
 
 	c.TokenExpiresAt = r.BaseResponse.DateTime.Add(24 * time.Hour)
 
+	c.log(slog.LevelInfo, "received schedules direct token", slog.String("token", redactSecret(r.Token)), slog.Time("expiresAt", c.TokenExpiresAt))
+
 	// return the token string
 	return r.Token, nil
 }
@@ -148,7 +184,7 @@ func (c *Client) GetStatus() (*StatusResponse, error) {
 
 // DeleteSystemMessage deletes a system message from the status response.
 func (c *Client) DeleteSystemMessage(messageID string) error {
-	url := fmt.Sprint(c.BaseURL, "/messages/", messageID)
+	url := fmt.Sprint(c.BaseURL, APIVersion, "/messages/", messageID)
 
 	req, httpErr := http.NewRequest("DELETE", url, nil)
 	if httpErr != nil {
@@ -163,12 +199,24 @@ func (c *Client) DeleteSystemMessage(messageID string) error {
 // SendRequest will send the given http.Request to Schedules Direct.
 // Specify if the request requires a token via the needsToken boolean.
 func (c Client) SendRequest(request *http.Request, needsToken bool) (*http.Response, []byte, error) {
+	metrics := RequestMetrics{Method: request.Method, Endpoint: endpointForURL(request.URL)}
+	response, data, err := c.sendRequest(request, needsToken, &metrics)
+	if metrics.Err == nil {
+		metrics.Err = err
+	}
+	c.observe(metrics)
+
+	return response, data, err
+}
+
+func (c Client) sendRequest(request *http.Request, needsToken bool, metrics *RequestMetrics) (*http.Response, []byte, error) {
 	if needsToken && c.Token == "" {
 		return nil, nil, fmt.Errorf("schedules direct client has not been initialized with a token, stubbornly refusing to make a request")
 	}
 
 	// If we've had the token for more than 24 hours we need to refresh it.
 	if time.Now().After(c.TokenExpiresAt) && c.failedRequests == 0 {
+		c.log(slog.LevelInfo, "refreshing expired schedules direct token", slog.Time("expiredAt", c.TokenExpiresAt))
 		c.failedRequests = c.failedRequests + 1
 		token, tokenErr := c.GetToken(c.username, c.password)
 		if tokenErr != nil {
@@ -186,10 +234,15 @@ func (c Client) SendRequest(request *http.Request, needsToken bool) (*http.Respo
 		request.Header.Set("Content-Type", "application/json")
 	}
 
+	c.log(slog.LevelDebug, "sending schedules direct request", slog.String("method", request.Method), slog.String("url", request.URL.String()), slog.Any("headers", redactedHeaders(request.Header)))
+
+	start := time.Now()
+
 	response, httpErr := c.HTTP.Do(request)
 	if httpErr != nil {
 		return nil, nil, fmt.Errorf("cannot reach schedules direct service: %s", httpErr)
 	}
+	metrics.StatusCode = response.StatusCode
 
 	// This is only for getting programs.
 	//
@@ -198,10 +251,12 @@ func (c Client) SendRequest(request *http.Request, needsToken bool) (*http.Respo
 	// This is due to an implementation bug in 20140530 which will be fixed in 20141201.
 	//
 	// Not actually fixed yet and Go disables automatic decompression if Accept-Encoding is set, so we are stuck doing the decompression ourselves.
-	var reader = response.Body
+	body := &countingReader{Reader: response.Body}
+	var reader io.Reader = body
 	if response.Header.Get("Content-Encoding") == "gzip" && !response.Uncompressed {
 		readerG, errG := gzip.NewReader(reader)
 		if errG == nil {
+			c.log(slog.LevelDebug, "decompressing gzip response", slog.String("endpoint", metrics.Endpoint))
 			reader = readerG
 		} else {
 			return nil, nil, errG
@@ -213,15 +268,22 @@ func (c Client) SendRequest(request *http.Request, needsToken bool) (*http.Respo
 		return nil, nil, fmt.Errorf("error when copying bytes of response to buffer: %s", copyErr)
 	}
 
+	metrics.Latency = time.Since(start)
+	metrics.CompressedBytes = body.count
+	metrics.UncompressedBytes = int64(buf.Len())
+
 	if closeErr := response.Body.Close(); closeErr != nil {
 		return nil, nil, fmt.Errorf("cannot read response. %v", closeErr)
 	}
 
 	baseResp := &BaseResponse{}
 	if unmarshalErr := json.Unmarshal(buf.Bytes(), baseResp); unmarshalErr == nil {
+		metrics.ErrorCode = baseResp.Code
 		if baseResp.Code == ErrInvalidUser && c.failedRequests == 0 {
 			// We know that at some point the credentials were valid, so let's try running the same request again
 			// after we attempt to update the token in case it was expired due to something other than expiration.
+			c.log(slog.LevelWarn, "retrying schedules direct request with a new token", slog.String("endpoint", metrics.Endpoint), slog.String("errorCode", baseResp.Code.InternalCode()))
+			metrics.Err = baseResp
 			c.failedRequests = c.failedRequests + 1
 			token, tokenErr := c.GetToken(c.username, c.password)
 			if tokenErr != nil {
@@ -0,0 +1,415 @@
+package schedulesdirect
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// SearchField is a part of a program which is searchable in a SearchIndex.
+type SearchField string
+
+const (
+	// TitleSearchField is the program title.
+	TitleSearchField SearchField = "title"
+	// EpisodeTitleSearchField is the episode title.
+	EpisodeTitleSearchField SearchField = "episodeTitle"
+	// DescriptionSearchField is any of the program descriptions.
+	DescriptionSearchField SearchField = "description"
+	// KeywordSearchField is any of the program keywords.
+	KeywordSearchField SearchField = "keyword"
+	// GenreSearchField is any of the program genres.
+	GenreSearchField SearchField = "genre"
+	// PersonSearchField is the name of any cast or crew member.
+	PersonSearchField SearchField = "person"
+	// AwardSearchField is the name, category or recipient of any award.
+	AwardSearchField SearchField = "award"
+)
+
+// searchFieldWeight returns how much a match in the field counts towards a hit's score.
+func searchFieldWeight(field SearchField) float64 {
+	switch field {
+	case TitleSearchField:
+		return 5
+	case EpisodeTitleSearchField, PersonSearchField:
+		return 3
+	case GenreSearchField, KeywordSearchField, AwardSearchField:
+		return 2
+	}
+	return 1
+}
+
+// SearchOptions controls how a SearchIndex query is matched.
+type SearchOptions struct {
+	// Prefix allows query terms to match the beginning of indexed terms.
+	Prefix bool
+	// Fuzziness is the maximum edit distance between a query term and an indexed term, 0 for exact matches.
+	Fuzziness int
+	// Fields limits the search to the given fields. All fields are searched if empty.
+	Fields []SearchField
+	// Limit is the maximum number of hits to return, 0 for all.
+	Limit int
+	// UpcomingAfter only attaches airings ending after the given time. All airings are attached if zero.
+	UpcomingAfter time.Time
+}
+
+// A SearchHit is a program matching a search query.
+type SearchHit struct {
+	ProgramID string
+	Program   *ProgramInfo
+	Score     float64
+	// Fields are the fields the query matched in.
+	Fields []SearchField
+	// Airings are the known airings of the program sorted by start time.
+	Airings []Airing
+}
+
+// A SearchIndex is an in memory full text index of programs. It is safe for concurrent use.
+type SearchIndex struct {
+	mu       sync.RWMutex
+	programs map[string]*ProgramInfo
+	postings map[string]map[string]map[SearchField]bool
+	// programTerms are the terms each program is indexed under, so it can be removed from their postings.
+	programTerms map[string][]string
+	terms        []string
+	airings      map[string][]Airing
+}
+
+// NewSearchIndex returns an empty SearchIndex.
+func NewSearchIndex() *SearchIndex {
+	return &SearchIndex{
+		programs:     make(map[string]*ProgramInfo),
+		postings:     make(map[string]map[string]map[SearchField]bool),
+		programTerms: make(map[string][]string),
+		terms:        make([]string, 0),
+		airings:      make(map[string][]Airing),
+	}
+}
+
+// AddPrograms indexes the given programs, replacing any program previously indexed with the same ID.
+func (i *SearchIndex) AddPrograms(programs ...ProgramInfo) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for idx := range programs {
+		program := programs[idx]
+		if program.ProgramID == "" {
+			continue
+		}
+
+		if _, ok := i.programs[program.ProgramID]; ok {
+			i.removeProgram(program.ProgramID)
+		}
+		i.programs[program.ProgramID] = &program
+
+		for field, texts := range searchableText(&program) {
+			for _, text := range texts {
+				for _, term := range tokenize(text) {
+					i.addPosting(term, program.ProgramID, field)
+				}
+			}
+		}
+
+		for idx := range i.airings[program.ProgramID] {
+			i.airings[program.ProgramID][idx].Info = &program
+		}
+	}
+
+	i.terms = i.terms[:0]
+	for term := range i.postings {
+		i.terms = append(i.terms, term)
+	}
+	sort.Strings(i.terms)
+}
+
+// AddAirings makes the airings available to link search hits to. Airings which were already added are ignored.
+func (i *SearchIndex) AddAirings(airings []Airing) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	known := make(map[AiringKey]bool)
+	for _, programAirings := range i.airings {
+		for _, airing := range programAirings {
+			known[airing.Key()] = true
+		}
+	}
+
+	for _, airing := range airings {
+		if known[airing.Key()] {
+			continue
+		}
+		known[airing.Key()] = true
+
+		if airing.Info == nil {
+			airing.Info = i.programs[airing.Program.ProgramID]
+		}
+		i.airings[airing.Program.ProgramID] = append(i.airings[airing.Program.ProgramID], airing)
+	}
+
+	for programID := range i.airings {
+		sortAirings(i.airings[programID])
+	}
+}
+
+// AddSchedules makes the airings in the schedules available to link search hits to.
+func (i *SearchIndex) AddSchedules(schedules []Schedule) {
+	i.AddAirings(AiringsFromSchedules(schedules, nil))
+}
+
+// Program returns the indexed program with the given ID, or nil.
+func (i *SearchIndex) Program(programID string) *ProgramInfo {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.programs[programID]
+}
+
+// Search returns the programs matching every term of the query, best match first.
+func (i *SearchIndex) Search(query string, opts SearchOptions) []SearchHit {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	queryTerms := tokenize(query)
+	if len(queryTerms) == 0 {
+		return make([]SearchHit, 0)
+	}
+
+	var scores map[string]float64
+	matchedFields := make(map[string]map[SearchField]bool)
+
+	for _, queryTerm := range queryTerms {
+		termScores := make(map[string]float64)
+
+		for _, match := range i.matchTerms(queryTerm, opts) {
+			for programID, fields := range i.postings[match.term] {
+				for field := range fields {
+					if len(opts.Fields) > 0 && !containsSearchField(opts.Fields, field) {
+						continue
+					}
+					score := searchFieldWeight(field) * match.quality
+					if score > termScores[programID] {
+						termScores[programID] = score
+					}
+					if matchedFields[programID] == nil {
+						matchedFields[programID] = make(map[SearchField]bool)
+					}
+					matchedFields[programID][field] = true
+				}
+			}
+		}
+
+		// Every query term has to match.
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		for programID := range scores {
+			if termScore, ok := termScores[programID]; ok {
+				scores[programID] += termScore
+			} else {
+				delete(scores, programID)
+			}
+		}
+	}
+
+	hits := make([]SearchHit, 0, len(scores))
+	for programID, score := range scores {
+		hit := SearchHit{
+			ProgramID: programID,
+			Program:   i.programs[programID],
+			Score:     score,
+			Fields:    make([]SearchField, 0),
+			Airings:   make([]Airing, 0),
+		}
+		for field := range matchedFields[programID] {
+			hit.Fields = append(hit.Fields, field)
+		}
+		sort.Slice(hit.Fields, func(a, b int) bool { return hit.Fields[a] < hit.Fields[b] })
+
+		for _, airing := range i.airings[programID] {
+			if opts.UpcomingAfter.IsZero() || airing.End().After(opts.UpcomingAfter) {
+				hit.Airings = append(hit.Airings, airing)
+			}
+		}
+		hits = append(hits, hit)
+	}
+
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].Score != hits[b].Score {
+			return hits[a].Score > hits[b].Score
+		}
+		return hits[a].ProgramID < hits[b].ProgramID
+	})
+
+	if opts.Limit > 0 && len(hits) > opts.Limit {
+		hits = hits[:opts.Limit]
+	}
+
+	return hits
+}
+
+type termMatch struct {
+	term    string
+	quality float64
+}
+
+// matchTerms returns the indexed terms matching queryTerm along with the quality of the match.
+func (i *SearchIndex) matchTerms(queryTerm string, opts SearchOptions) []termMatch {
+	matches := make(map[string]float64)
+
+	if _, ok := i.postings[queryTerm]; ok {
+		matches[queryTerm] = 1
+	}
+
+	if opts.Prefix {
+		for idx := sort.SearchStrings(i.terms, queryTerm); idx < len(i.terms) && strings.HasPrefix(i.terms[idx], queryTerm); idx++ {
+			if _, ok := matches[i.terms[idx]]; !ok {
+				matches[i.terms[idx]] = 0.75
+			}
+		}
+	}
+
+	if opts.Fuzziness > 0 {
+		queryRunes := []rune(queryTerm)
+		for _, term := range i.terms {
+			if _, ok := matches[term]; ok {
+				continue
+			}
+			termRunes := []rune(term)
+			if abs(len(termRunes)-len(queryRunes)) > opts.Fuzziness {
+				continue
+			}
+			if distance := editDistance(queryRunes, termRunes, opts.Fuzziness); distance <= opts.Fuzziness {
+				matches[term] = 0.5 / float64(distance)
+			}
+		}
+	}
+
+	result := make([]termMatch, 0, len(matches))
+	for term, quality := range matches {
+		result = append(result, termMatch{term, quality})
+	}
+	return result
+}
+
+func (i *SearchIndex) addPosting(term, programID string, field SearchField) {
+	if i.postings[term] == nil {
+		i.postings[term] = make(map[string]map[SearchField]bool)
+	}
+	if i.postings[term][programID] == nil {
+		i.postings[term][programID] = make(map[SearchField]bool)
+		i.programTerms[programID] = append(i.programTerms[programID], term)
+	}
+	i.postings[term][programID][field] = true
+}
+
+func (i *SearchIndex) removeProgram(programID string) {
+	for _, term := range i.programTerms[programID] {
+		programs := i.postings[term]
+		delete(programs, programID)
+		if len(programs) == 0 {
+			delete(i.postings, term)
+		}
+	}
+	delete(i.programTerms, programID)
+	delete(i.programs, programID)
+}
+
+// searchableText returns the text of each searchable field of the program.
+func searchableText(p *ProgramInfo) map[SearchField][]string {
+	text := make(map[SearchField][]string)
+
+	for _, title := range p.Titles {
+		text[TitleSearchField] = append(text[TitleSearchField], title.Title120)
+	}
+
+	if p.EpisodeTitle150 != "" {
+		text[EpisodeTitleSearchField] = append(text[EpisodeTitleSearchField], p.EpisodeTitle150)
+	}
+
+	for _, descriptions := range p.Descriptions {
+		for _, description := range descriptions {
+			text[DescriptionSearchField] = append(text[DescriptionSearchField], description.Description)
+		}
+	}
+
+	for _, keywords := range p.Keywords {
+		text[KeywordSearchField] = append(text[KeywordSearchField], keywords...)
+	}
+
+	text[GenreSearchField] = append(text[GenreSearchField], p.Genres...)
+
+	for _, person := range append(append([]Person{}, p.Cast...), p.Crew...) {
+		text[PersonSearchField] = append(text[PersonSearchField], person.Name)
+	}
+
+	for _, award := range p.Awards {
+		text[AwardSearchField] = append(text[AwardSearchField], award.AwardName, award.Category, award.Name, award.Recipient)
+	}
+
+	return text
+}
+
+// tokenize splits text into lower cased terms, dropping punctuation.
+func tokenize(text string) []string {
+	text = strings.NewReplacer("'", "", "’", "").Replace(text)
+
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// editDistance returns the Levenshtein distance between a and b, or max+1 once it is known to exceed max.
+func editDistance(a, b []rune, max int) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(minInt(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+			if current[j] < rowMin {
+				rowMin = current[j]
+			}
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+func containsSearchField(fields []SearchField, field SearchField) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func abs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
@@ -0,0 +1,95 @@
+package schedulesdirect
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testSearchIndex() *SearchIndex {
+	index := NewSearchIndex()
+	index.AddPrograms(
+		ProgramInfo{
+			ProgramID:       "EP000000060003",
+			Titles:          []Title{{Title120: "'Allo 'Allo!"}},
+			EpisodeTitle150: "The Poloceman Cometh",
+			Descriptions:    map[string][]Description{"description1000": {{Description: "A disguised British Intelligence officer is sent to help the airmen."}}},
+			Genres:          []string{"Sitcom"},
+			Cast:            []Person{{Name: "Gorden Kaye", Role: "Actor"}},
+			Crew:            []Person{{Name: "David Croft", Role: "Director"}},
+		},
+		ProgramInfo{
+			ProgramID: "MV000000010000",
+			Titles:    []Title{{Title120: "The Intelligence Men"}},
+			Keywords:  map[string][]string{"Setting": {"London"}},
+			Genres:    []string{"Comedy", "Spy"},
+			Awards:    []Award{{AwardName: "BAFTA", Category: "Best Comedy"}},
+		},
+	)
+	index.AddSchedules([]Schedule{
+		{StationID: "10001", Programs: []Program{
+			testProgram("EP000000060003", "2015-03-03T20:00:00Z", 1800),
+			testProgram("EP000000060003", "2015-03-05T20:00:00Z", 1800),
+		}},
+	})
+	return index
+}
+
+func TestSearchIndexExact(t *testing.T) {
+	index := testSearchIndex()
+
+	hits := index.Search("intelligence", SearchOptions{})
+	if assert.Len(t, hits, 2) {
+		assert.Equal(t, "MV000000010000", hits[0].ProgramID)
+		assert.Equal(t, []SearchField{TitleSearchField}, hits[0].Fields)
+		assert.Equal(t, []SearchField{DescriptionSearchField}, hits[1].Fields)
+		assert.Len(t, hits[1].Airings, 2)
+		assert.Equal(t, "'Allo 'Allo!", hits[1].Airings[0].Title())
+	}
+
+	assert.Len(t, index.Search("intelligence sitcom", SearchOptions{}), 1)
+	assert.Len(t, index.Search("allo", SearchOptions{Fields: []SearchField{TitleSearchField}}), 1)
+	assert.Len(t, index.Search("bafta london", SearchOptions{}), 1)
+	assert.Empty(t, index.Search("croft london", SearchOptions{}))
+	assert.Empty(t, index.Search("  !! ", SearchOptions{}))
+}
+
+func TestSearchIndexPrefixAndFuzzy(t *testing.T) {
+	index := testSearchIndex()
+
+	assert.Empty(t, index.Search("polo", SearchOptions{}))
+	hits := index.Search("polo", SearchOptions{Prefix: true})
+	if assert.Len(t, hits, 1) {
+		assert.Equal(t, []SearchField{EpisodeTitleSearchField}, hits[0].Fields)
+	}
+
+	hits = index.Search("gordon kay", SearchOptions{Fuzziness: 1})
+	if assert.Len(t, hits, 1) {
+		assert.Equal(t, "EP000000060003", hits[0].ProgramID)
+	}
+	assert.Empty(t, index.Search("gordan kai", SearchOptions{Fuzziness: 1}))
+}
+
+func TestSearchIndexUpcomingAndReplace(t *testing.T) {
+	index := testSearchIndex()
+
+	hits := index.Search("allo", SearchOptions{UpcomingAfter: time.Date(2015, 3, 4, 0, 0, 0, 0, time.UTC), Limit: 1})
+	if assert.Len(t, hits, 1) && assert.Len(t, hits[0].Airings, 1) {
+		assert.Equal(t, time.Date(2015, 3, 5, 20, 0, 0, 0, time.UTC), hits[0].Airings[0].Start())
+	}
+
+	index.AddPrograms(ProgramInfo{ProgramID: "EP000000060003", Titles: []Title{{Title120: "Hello Hello"}}})
+	assert.Empty(t, index.Search("allo", SearchOptions{}))
+	assert.Len(t, index.Search("hello", SearchOptions{}), 1)
+	assert.Equal(t, "Hello Hello", index.Program("EP000000060003").Titles[0].Title120)
+	assert.Equal(t, []string{"hello"}, index.programTerms["EP000000060003"])
+	assert.NotContains(t, index.terms, "allo")
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance([]rune("kaye"), []rune("kaye"), 2))
+	assert.Equal(t, 1, editDistance([]rune("kay"), []rune("kaye"), 2))
+	assert.Equal(t, 1, editDistance([]rune("gordan"), []rune("gorden"), 2))
+	assert.Equal(t, 3, editDistance([]rune("abc"), []rune("xyz"), 2))
+}
@@ -0,0 +1,201 @@
+package schedulesdirect
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// SportsUpdateType is the kind of change a SportsWatcher observed.
+type SportsUpdateType string
+
+const (
+	// ScoreChangedSportsUpdate means the score of either team changed since the previous poll.
+	ScoreChangedSportsUpdate SportsUpdateType = "scoreChanged"
+	// OverrunSportsUpdate means the program is still running past its scheduled end.
+	// It is emitted on every poll for as long as the overrun lasts.
+	OverrunSportsUpdate SportsUpdateType = "overrun"
+	// CompletedSportsUpdate means the program is complete. It is the last event for an airing.
+	CompletedSportsUpdate SportsUpdateType = "completed"
+	// ErrorSportsUpdate means polling the program failed. The airing keeps being watched.
+	ErrorSportsUpdate SportsUpdateType = "error"
+)
+
+// DefaultSportsWatcherInterval is used when SportsWatcher.Interval is not set.
+const DefaultSportsWatcherInterval = time.Minute
+
+// A SportsUpdate is a change in the real time state of a sports airing.
+type SportsUpdate struct {
+	Type   SportsUpdateType
+	Airing Airing
+	// Time is when the change was observed.
+	Time time.Time
+	// Status is the response of the poll, nil for ErrorSportsUpdate.
+	Status   *StillRunningResponse
+	HomeTeam *Team
+	AwayTeam *Team
+	// Overrun is how long the airing ran past its scheduled end as of Time.
+	Overrun time.Duration
+	Err     error
+}
+
+// A SportsWatcher polls GetProgramStillRunning for currently airing sports programs.
+type SportsWatcher struct {
+	Client *Client
+	// Interval is the time between polls, DefaultSportsWatcherInterval if zero.
+	Interval time.Duration
+	// MaxOverrun stops watching an airing which is not complete this long past its scheduled end, 0 to watch until complete.
+	MaxOverrun time.Duration
+
+	now func() time.Time
+}
+
+// IsSportsAiring returns true if the airing is a sports program, which either has event details or a SP program ID.
+func IsSportsAiring(airing Airing) bool {
+	if airing.Info != nil && airing.Info.EventDetails != nil {
+		return true
+	}
+	return strings.HasPrefix(airing.Program.ProgramID, "SP")
+}
+
+type watchedAiring struct {
+	airing   Airing
+	homeTeam *Team
+	awayTeam *Team
+	polled   bool
+}
+
+// Watch polls the sports airings among the given airings while they are airing, until they are complete.
+// Airings which have not started yet are picked up once they start.
+// The returned channel is closed once every airing is complete or ctx is done.
+func (w *SportsWatcher) Watch(ctx context.Context, airings []Airing) <-chan SportsUpdate {
+	events := make(chan SportsUpdate)
+
+	watched := make([]*watchedAiring, 0)
+	for _, airing := range airings {
+		if IsSportsAiring(airing) {
+			watched = append(watched, &watchedAiring{airing: airing})
+		}
+	}
+
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultSportsWatcherInterval
+	}
+
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			var ok bool
+			if watched, ok = w.poll(ctx, watched, events); !ok || len(watched) == 0 {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return events
+}
+
+// poll checks every started airing once and returns the airings still to watch.
+// It returns false if ctx was done while sending an event, in which case watching stops.
+func (w *SportsWatcher) poll(ctx context.Context, watched []*watchedAiring, events chan<- SportsUpdate) ([]*watchedAiring, bool) {
+	now := w.currentTime()
+	remaining := watched[:0]
+	responses := make(map[string]*StillRunningResponse)
+
+	send := func(event SportsUpdate) bool {
+		select {
+		case events <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for _, current := range watched {
+		airing := current.airing
+		overrun := now.Sub(airing.End())
+
+		if now.Before(airing.Start()) {
+			remaining = append(remaining, current)
+			continue
+		}
+
+		if w.MaxOverrun > 0 && overrun > w.MaxOverrun {
+			w.Client.log(slog.LevelDebug, "giving up on overrunning sports program", slog.String("programID", airing.Program.ProgramID), slog.Duration("overrun", overrun))
+			continue
+		}
+
+		status, ok := responses[airing.Program.ProgramID]
+		if !ok {
+			var err error
+			if status, err = w.Client.GetProgramStillRunning(airing.Program.ProgramID); err != nil {
+				remaining = append(remaining, current)
+				if !send(SportsUpdate{Type: ErrorSportsUpdate, Airing: airing, Time: now, Err: err}) {
+					return nil, false
+				}
+				continue
+			}
+			responses[airing.Program.ProgramID] = status
+		}
+
+		event := SportsUpdate{Airing: airing, Time: now, Status: status, HomeTeam: status.Result.HomeTeam, AwayTeam: status.Result.AwayTeam}
+		if overrun > 0 {
+			event.Overrun = overrun
+		}
+
+		scoreChanged := current.polled && (teamScore(current.homeTeam) != teamScore(event.HomeTeam) || teamScore(current.awayTeam) != teamScore(event.AwayTeam))
+		current.homeTeam, current.awayTeam, current.polled = event.HomeTeam, event.AwayTeam, true
+
+		if scoreChanged {
+			event.Type = ScoreChangedSportsUpdate
+			if !send(event) {
+				return nil, false
+			}
+		}
+
+		if status.IsComplete {
+			event.Type = CompletedSportsUpdate
+			if !send(event) {
+				return nil, false
+			}
+			continue
+		}
+
+		remaining = append(remaining, current)
+
+		if overrun > 0 {
+			event.Type = OverrunSportsUpdate
+			if !send(event) {
+				return nil, false
+			}
+		}
+	}
+
+	return remaining, true
+}
+
+func (w *SportsWatcher) currentTime() time.Time {
+	if w.now != nil {
+		return w.now()
+	}
+	return time.Now()
+}
+
+func teamScore(team *Team) string {
+	if team == nil {
+		return ""
+	}
+	return team.Score
+}
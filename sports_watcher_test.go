@@ -0,0 +1,94 @@
+package schedulesdirect
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSportsAiring(t *testing.T) {
+	assert.True(t, IsSportsAiring(Airing{Program: testProgram("SP000000000001", "2015-03-03T20:00:00Z", 3600)}))
+	assert.False(t, IsSportsAiring(Airing{Program: testProgram("EP000000060003", "2015-03-03T20:00:00Z", 3600)}))
+	assert.True(t, IsSportsAiring(Airing{
+		Program: testProgram("EP000000060003", "2015-03-03T20:00:00Z", 3600),
+		Info:    &ProgramInfo{EventDetails: &EventDetails{Venue: "Wembley"}},
+	}))
+}
+
+func TestSportsWatcherWatch(t *testing.T) {
+	mux, client := setup()
+
+	responses := []string{
+		`{"programID":"SP000000000001","isComplete":false,"result":{"homeTeam":{"name":"Dons","score":"0"},"awayTeam":{"name":"Rovers","score":"0"}}}`,
+		`{"programID":"SP000000000001","isComplete":false,"result":{"homeTeam":{"name":"Dons","score":"1"},"awayTeam":{"name":"Rovers","score":"0"}}}`,
+		`{"programID":"SP000000000001","isComplete":true,"result":{"homeTeam":{"name":"Dons","score":"1"},"awayTeam":{"name":"Rovers","score":"0"}}}`,
+	}
+	polls := 0
+	mux.HandleFunc(fmt.Sprint("/", APIVersion, "/metadata/stillRunning/SP000000000001"),
+		func(w http.ResponseWriter, r *http.Request) {
+			ensureMethod(t, r, "GET")
+			ensureHeader(t, r, "token", "d97c908ed44c25fdca302612c70584c8d5acd47a")
+
+			fmt.Fprint(w, responses[polls])
+			polls++
+		},
+	)
+
+	watcher := &SportsWatcher{
+		Client:   client,
+		Interval: 10 * time.Millisecond,
+		now:      func() time.Time { return time.Date(2015, 3, 3, 21, 30, 0, 0, time.UTC) },
+	}
+
+	airings := []Airing{
+		{StationID: "10001", Program: testProgram("SP000000000001", "2015-03-03T20:00:00Z", 3600)},
+		{StationID: "10001", Program: testProgram("EP000000060003", "2015-03-03T21:00:00Z", 3600)},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events := make([]SportsUpdate, 0)
+	for event := range watcher.Watch(ctx, airings) {
+		events = append(events, event)
+	}
+
+	assert.Equal(t, 3, polls)
+	if assert.Len(t, events, 4) {
+		assert.Equal(t, OverrunSportsUpdate, events[0].Type)
+		assert.Equal(t, 30*time.Minute, events[0].Overrun)
+
+		assert.Equal(t, ScoreChangedSportsUpdate, events[1].Type)
+		assert.Equal(t, "1", events[1].HomeTeam.Score)
+		assert.Equal(t, "0", events[1].AwayTeam.Score)
+		assert.Equal(t, OverrunSportsUpdate, events[2].Type)
+
+		assert.Equal(t, CompletedSportsUpdate, events[3].Type)
+		assert.True(t, events[3].Status.IsComplete)
+		assert.Equal(t, "SP000000000001", events[3].Airing.Program.ProgramID)
+	}
+}
+
+func TestSportsWatcherMaxOverrunAndCancel(t *testing.T) {
+	_, client := setup()
+
+	overrunning := []Airing{{StationID: "10001", Program: testProgram("SP000000000001", "2015-03-03T20:00:00Z", 3600)}}
+	watcher := &SportsWatcher{
+		Client:     client,
+		MaxOverrun: time.Hour,
+		now:        func() time.Time { return time.Date(2015, 3, 3, 23, 0, 0, 0, time.UTC) },
+	}
+	_, open := <-watcher.Watch(context.Background(), overrunning)
+	assert.False(t, open)
+
+	upcoming := []Airing{{StationID: "10001", Program: testProgram("SP000000000002", "2015-03-04T20:00:00Z", 3600)}}
+	ctx, cancel := context.WithCancel(context.Background())
+	events := watcher.Watch(ctx, upcoming)
+	cancel()
+	_, open = <-events
+	assert.False(t, open)
+}
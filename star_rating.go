@@ -0,0 +1,193 @@
+package schedulesdirect
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// FiveStarScale normalizes ratings to 0 to 5 stars.
+	FiveStarScale = 5.0
+	// TenStarScale normalizes ratings to 0 to 10 stars.
+	TenStarScale = 10.0
+)
+
+// A StarRating is a MovieQualityRating parsed and converted to a common star scale.
+type StarRating struct {
+	MovieQualityRating
+
+	// Value, Min, Max and Increment are the parsed values of the original rating.
+	Value     float64
+	Min       float64
+	Max       float64
+	Increment float64
+
+	// Stars is the rating converted to Scale, rounded to a multiple of half a star.
+	Stars float64
+	Scale float64
+}
+
+// Fraction returns the rating as a fraction of the maximum, between 0 and 1.
+func (r StarRating) Fraction() float64 {
+	if r.Scale == 0 {
+		return 0
+	}
+	return r.Stars / r.Scale
+}
+
+// String returns the stars and scale, e.g. 3.5/5.
+func (r StarRating) String() string {
+	return fmt.Sprint(strconv.FormatFloat(r.Stars, 'f', -1, 64), "/", strconv.FormatFloat(r.Scale, 'f', -1, 64))
+}
+
+// An XMLTVStarRating is a star-rating element of an XMLTV programme.
+type XMLTVStarRating struct {
+	XMLName xml.Name `xml:"star-rating"`
+	System  string   `xml:"system,attr,omitempty"`
+	Value   string   `xml:"value"`
+}
+
+// XMLTV returns the rating as an XMLTV star-rating element.
+func (r StarRating) XMLTV() XMLTVStarRating {
+	return XMLTVStarRating{System: r.RatingsBody, Value: r.String()}
+}
+
+// ParseStarRating parses the strings of a MovieQualityRating and converts it to the given scale.
+//
+// Ratings may be numbers ("3.5"), use "½" for halves ("3½"), be written as stars ("***½")
+// or as a fraction of the maximum ("7/10"). A missing minimum rating is 0.
+func ParseStarRating(rating MovieQualityRating, scale float64) (StarRating, error) {
+	parsed := StarRating{MovieQualityRating: rating, Scale: scale}
+
+	if scale <= 0 {
+		return parsed, fmt.Errorf("invalid star scale %v", scale)
+	}
+
+	value, maxValue, err := parseStarValue(rating.Rating)
+	if err != nil {
+		return parsed, err
+	}
+
+	if rating.MaxRating != "" {
+		if maxValue, _, err = parseStarValue(rating.MaxRating); err != nil {
+			return parsed, fmt.Errorf("invalid max rating %q", rating.MaxRating)
+		}
+	}
+	if maxValue == 0 {
+		return parsed, fmt.Errorf("rating %q has no max rating", rating.Rating)
+	}
+
+	minValue := 0.0
+	if rating.MinRating != "" {
+		if minValue, _, err = parseStarValue(rating.MinRating); err != nil {
+			return parsed, fmt.Errorf("invalid min rating %q", rating.MinRating)
+		}
+	}
+
+	increment := 0.0
+	if rating.Increment != "" {
+		if increment, _, err = parseStarValue(rating.Increment); err != nil {
+			return parsed, fmt.Errorf("invalid rating increment %q", rating.Increment)
+		}
+	}
+
+	if maxValue <= minValue || value < minValue || value > maxValue {
+		return parsed, fmt.Errorf("rating %q is not between %v and %v", rating.Rating, minValue, maxValue)
+	}
+
+	// Snap values such as 3.4 to the rating's own increments before converting.
+	if increment > 0 {
+		value = minValue + math.Round((value-minValue)/increment)*increment
+	}
+
+	parsed.Value, parsed.Min, parsed.Max, parsed.Increment = value, minValue, maxValue, increment
+	parsed.Stars = math.Round((value-minValue)/(maxValue-minValue)*scale*2) / 2
+	return parsed, nil
+}
+
+// parseStarValue parses a number, a number with a ½, a run of stars or a fraction.
+// For a fraction the denominator is returned as maxValue, otherwise maxValue is 0.
+func parseStarValue(text string) (value, maxValue float64, err error) {
+	text = strings.TrimSpace(text)
+
+	if slash := strings.Index(text, "/"); slash >= 0 {
+		if value, _, err = parseStarValue(text[:slash]); err != nil {
+			return 0, 0, err
+		}
+		if maxValue, _, err = parseStarValue(text[slash+1:]); err != nil {
+			return 0, 0, err
+		}
+		return value, maxValue, nil
+	}
+
+	half := 0.0
+	if strings.HasSuffix(text, "½") {
+		text, half = strings.TrimSpace(strings.TrimSuffix(text, "½")), 0.5
+	}
+
+	if text != "" && strings.Trim(text, "*★") == "" {
+		return float64(len([]rune(text))) + half, 0, nil
+	}
+	if text == "" && half > 0 {
+		return half, 0, nil
+	}
+
+	value, err = strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid star rating %q", text)
+	}
+	return value + half, 0, nil
+}
+
+// A StarRatingNormalizer picks and converts the star ratings of movies.
+type StarRatingNormalizer struct {
+	// Scale is the scale ratings are converted to, FiveStarScale if zero.
+	Scale float64
+	// PreferredBodies are the ratings bodies to prefer, most preferred first, ignoring case.
+	// Ratings of other bodies are used in their original order if none of them are available.
+	PreferredBodies []string
+}
+
+// All returns every parsable rating of the movie, in order of preference.
+func (n StarRatingNormalizer) All(movie *Movie) []StarRating {
+	ratings := make([]StarRating, 0)
+	if movie == nil {
+		return ratings
+	}
+
+	scale := n.Scale
+	if scale == 0 {
+		scale = FiveStarScale
+	}
+
+	for _, rating := range movie.QualityRating {
+		if parsed, err := ParseStarRating(rating, scale); err == nil {
+			ratings = append(ratings, parsed)
+		}
+	}
+
+	preference := func(r StarRating) int {
+		for idx, body := range n.PreferredBodies {
+			if strings.EqualFold(body, r.RatingsBody) {
+				return idx
+			}
+		}
+		return len(n.PreferredBodies)
+	}
+	sort.SliceStable(ratings, func(a, b int) bool { return preference(ratings[a]) < preference(ratings[b]) })
+
+	return ratings
+}
+
+// Normalize returns the most preferred parsable rating of the movie.
+func (n StarRatingNormalizer) Normalize(movie *Movie) (StarRating, bool) {
+	ratings := n.All(movie)
+	if len(ratings) == 0 {
+		return StarRating{}, false
+	}
+	return ratings[0], true
+}
@@ -0,0 +1,90 @@
+package schedulesdirect
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStarRating(t *testing.T) {
+	rating, err := ParseStarRating(MovieQualityRating{RatingsBody: "Gracenote", Rating: "3.5", MinRating: "1", MaxRating: "4", Increment: ".5"}, FiveStarScale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3.5, rating.Value)
+	assert.Equal(t, 1.0, rating.Min)
+	assert.Equal(t, 4.0, rating.Max)
+	assert.Equal(t, 0.5, rating.Increment)
+	assert.Equal(t, 4.0, rating.Stars)
+	assert.Equal(t, "4/5", rating.String())
+	assert.Equal(t, 0.8, rating.Fraction())
+
+	for text, stars := range map[string]float64{
+		"2":    5,
+		"3½":   9,
+		"***½": 9,
+		"★":    2.5,
+		"3.4":  9,
+	} {
+		rating, err := ParseStarRating(MovieQualityRating{Rating: text, MaxRating: "4", Increment: "0.5"}, TenStarScale)
+		if assert.NoError(t, err, text) {
+			assert.Equal(t, stars, rating.Stars, text)
+		}
+	}
+
+	rating, err = ParseStarRating(MovieQualityRating{RatingsBody: "IMDb", Rating: "7.3/10"}, FiveStarScale)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3.5, rating.Stars)
+	}
+
+	for _, invalid := range []MovieQualityRating{
+		{Rating: "3"},
+		{Rating: "good", MaxRating: "4"},
+		{Rating: "5", MaxRating: "4"},
+		{Rating: "3", MaxRating: "four"},
+		{Rating: "3", MinRating: "4", MaxRating: "4"},
+		{Rating: "3", MaxRating: "4", Increment: "half"},
+	} {
+		_, err := ParseStarRating(invalid, FiveStarScale)
+		assert.Error(t, err, invalid)
+	}
+	_, err = ParseStarRating(MovieQualityRating{Rating: "3", MaxRating: "4"}, 0)
+	assert.Error(t, err)
+}
+
+func TestStarRatingNormalizer(t *testing.T) {
+	movie := &Movie{QualityRating: []MovieQualityRating{
+		{RatingsBody: "Broken", Rating: "n/a"},
+		{RatingsBody: "Gracenote", Rating: "3", MaxRating: "4"},
+		{RatingsBody: "IMDb", Rating: "8", MaxRating: "10"},
+		{RatingsBody: "Rotten Tomatoes", Rating: "91", MaxRating: "100"},
+	}}
+
+	rating, ok := StarRatingNormalizer{}.Normalize(movie)
+	if assert.True(t, ok) {
+		assert.Equal(t, "Gracenote", rating.RatingsBody)
+		assert.Equal(t, 4.0, rating.Stars)
+	}
+
+	normalizer := StarRatingNormalizer{Scale: TenStarScale, PreferredBodies: []string{"rotten tomatoes", "imdb"}}
+	ratings := normalizer.All(movie)
+	if assert.Len(t, ratings, 3) {
+		assert.Equal(t, "Rotten Tomatoes", ratings[0].RatingsBody)
+		assert.Equal(t, 9.0, ratings[0].Stars)
+		assert.Equal(t, "IMDb", ratings[1].RatingsBody)
+		assert.Equal(t, "Gracenote", ratings[2].RatingsBody)
+		assert.Equal(t, 7.5, ratings[2].Stars)
+	}
+
+	_, ok = normalizer.Normalize(nil)
+	assert.False(t, ok)
+	_, ok = normalizer.Normalize(&Movie{QualityRating: []MovieQualityRating{{Rating: "n/a"}}})
+	assert.False(t, ok)
+
+	data, err := xml.Marshal(ratings[0].XMLTV())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<star-rating system="Rotten Tomatoes"><value>9/10</value></star-rating>`, string(data))
+}
@@ -153,7 +153,30 @@ type Status struct {
 // An AccountInfo stores the message account information
 // usually as part of a StatusResponse.
 type AccountInfo struct {
-	Expires    string   `json:"expires,omitempty"`
-	Messages   []string `json:"messages,omitempty"`
-	MaxLineups int      `json:"maxLineups,omitempty"`
+	Expires    string          `json:"expires,omitempty"`
+	Messages   []SystemMessage `json:"messages,omitempty"`
+	MaxLineups int             `json:"maxLineups,omitempty"`
+}
+
+// A SystemMessage is a message for the account which can be deleted once acknowledged.
+type SystemMessage struct {
+	MessageID string     `json:"msgID,omitempty"`
+	Date      *time.Time `json:"date,omitempty"`
+	Message   string     `json:"message,omitempty"`
+}
+
+// UnmarshalJSON decodes a SystemMessage from either an object or a bare string.
+func (m *SystemMessage) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		*m = SystemMessage{}
+		return json.Unmarshal(data, &m.Message)
+	}
+
+	type systemMessage SystemMessage
+	msg := systemMessage{}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	*m = SystemMessage(msg)
+	return nil
 }
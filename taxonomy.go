@@ -0,0 +1,278 @@
+package schedulesdirect
+
+import (
+	"sort"
+	"strings"
+)
+
+// PlexBucket is one of the broad categories Plex sorts guide entries into.
+type PlexBucket string
+
+const (
+	// PlexMovies is the bucket of movies.
+	PlexMovies PlexBucket = "Movies"
+	// PlexSports is the bucket of sports events and sports programming.
+	PlexSports PlexBucket = "Sports"
+	// PlexNews is the bucket of news programming.
+	PlexNews PlexBucket = "News"
+	// PlexKids is the bucket of children's programming.
+	PlexKids PlexBucket = "Kids"
+	// PlexShows is the bucket of everything else.
+	PlexShows PlexBucket = "TV Shows"
+)
+
+// A GenreCategory is the standardized categories of a Schedules Direct genre or keyword.
+// Zero values mean the genre has no category in that taxonomy.
+type GenreCategory struct {
+	// DVBContent is the content_nibble_level_1 and content_nibble_level_2 of EN 300 468 table 28, e.g. 0x14 for comedy.
+	DVBContent byte
+	// ATSCGenre is the genre_descriptor category of ATSC A/65 table 6.20, e.g. 0x34 for comedy.
+	ATSCGenre byte
+	// TVAnytime is the term ID of the TV-Anytime ContentCS classification scheme, e.g. "3.4.9" for comedy.
+	TVAnytime string
+	// XMLTV is the XMLTV category name, using the EN 300 468 names Kodi recognizes, e.g. "Movie / Drama".
+	XMLTV string
+	// Plex is the Plex bucket of the genre.
+	Plex PlexBucket
+}
+
+// dvbContentNames are the names of the EN 300 468 content_nibble_level_1 values, as used by Kodi.
+func dvbContentNames() map[byte]string {
+	return map[byte]string{
+		0x1: "Movie / Drama",
+		0x2: "News / Current affairs",
+		0x3: "Show / Game show",
+		0x4: "Sports",
+		0x5: "Children's / Youth programmes",
+		0x6: "Music / Ballet / Dance",
+		0x7: "Arts / Culture (without music)",
+		0x8: "Social / Political issues / Economics",
+		0x9: "Education / Science / Factual topics",
+		0xA: "Leisure hobbies",
+		0xB: "Special characteristics",
+	}
+}
+
+// DefaultGenreCategories returns the built-in categories of Schedules Direct genres, keyed by lower case genre.
+// XMLTV categories and Plex buckets are derived from the DVB content nibbles.
+//
+// TV-Anytime terms are those of the ContentCS classification scheme of ETSI TS 102 822-3-1
+// (urn:tva:metadata:cs:ContentCS:2011), e.g. 3.1.3.4 "Legal" under 3.1.3 "General non-fiction" and
+// 3.4.6 "Action", which holds crime, mystery, thriller, war and western fiction. Genres without a matching
+// term use the nearest broader term, e.g. 3.2 "Sports" for sports without a sport group of their own.
+func DefaultGenreCategories() map[string]GenreCategory {
+	rows := []struct {
+		genre     string
+		dvb, atsc byte
+		tva       string
+	}{
+		{"movie", 0x10, 0x22, ""}, {"drama", 0x10, 0x3C, "3.4"}, {"crime", 0x11, 0x39, "3.4.6"}, {"crime drama", 0x11, 0x39, "3.4.6"},
+		{"mystery", 0x11, 0x61, "3.4.6"}, {"suspense", 0x11, 0x77, "3.4.6"}, {"thriller", 0x11, 0x77, "3.4.6"},
+		{"action", 0x12, 0x27, "3.4.6"}, {"adventure", 0x12, 0x27, "3.4.6"}, {"western", 0x12, 0x7F, "3.4.6"}, {"war", 0x12, 0x5E, "3.4.6"},
+		{"science fiction", 0x13, 0x40, "3.4.7"}, {"fantasy", 0x13, 0x40, "3.4.7"}, {"horror", 0x13, 0x52, "3.4.8"},
+		{"comedy", 0x14, 0x34, "3.4.9"}, {"sitcom", 0x14, 0x34, "3.4.9"}, {"comedy drama", 0x14, 0x34, "3.4.9"},
+		{"soap", 0x15, 0x75, "3.4.2"}, {"romance", 0x16, 0x70, "3.4.3"}, {"romantic comedy", 0x16, 0x70, "3.4.9"},
+		{"historical drama", 0x17, 0x3C, "3.4"}, {"adults only", 0x18, 0x3E, ""}, {"anthology", 0x10, 0x2A, ""},
+		{"miniseries", 0x10, 0x5F, ""}, {"military", 0x23, 0x5E, "3.1.3.8"}, {"law", 0x80, 0x58, "3.1.3.4"},
+		{"news", 0x20, 0x23, "3.1.1"}, {"weather", 0x21, 0x7E, "3.1.1"}, {"newsmagazine", 0x22, 0x23, "3.1.1"},
+		{"documentary", 0x23, 0x3B, "3.1"}, {"docudrama", 0x23, 0x3B, "3.1"}, {"interview", 0x24, 0x56, "3.1.1"},
+		{"public affairs", 0x24, 0x6A, "3.1.3"}, {"talk", 0x33, 0x78, "3.5"},
+		{"reality", 0x30, 0x21, "3.5"}, {"entertainment", 0x30, 0x21, "3.5"}, {"awards", 0x30, 0x2C, "3.5"},
+		{"game show", 0x31, 0x48, "3.5.2"}, {"variety", 0x32, 0x7C, "3.5.3"},
+		{"sports event", 0x40, 0x25, "3.2"}, {"sports non-event", 0x40, 0x25, "3.2"}, {"sports talk", 0x42, 0x25, "3.2"},
+		{"soccer", 0x43, 0x25, "3.2.3"}, {"tennis", 0x44, 0x7A, "3.2.4"}, {"football", 0x45, 0x45, "3.2.3"},
+		{"basketball", 0x45, 0x2E, "3.2.3"}, {"baseball", 0x45, 0x2D, "3.2.3"}, {"hockey", 0x45, 0x50, "3.2.3"},
+		{"golf", 0x40, 0x4A, "3.2"}, {"athletics", 0x46, 0x25, "3.2.1"}, {"cycling", 0x40, 0x25, "3.2.2"},
+		{"auto racing", 0x47, 0x6B, "3.2"}, {"motorsports", 0x47, 0x6B, "3.2"}, {"swimming", 0x48, 0x25, "3.2.6"},
+		{"sailing", 0x48, 0x25, "3.2.6"}, {"skiing", 0x49, 0x25, "3.2.7"}, {"figure skating", 0x49, 0x25, "3.2.7"},
+		{"equestrian", 0x4A, 0x25, "3.2"}, {"boxing", 0x4B, 0x33, "3.2.5"}, {"martial arts", 0x4B, 0x33, "3.2.5"},
+		{"wrestling", 0x4B, 0x33, "3.2.5"},
+		{"children", 0x50, 0, ""}, {"educational", 0x54, 0x20, "3.1.3.6"}, {"animated", 0x55, 0x29, ""}, {"anime", 0x55, 0x29, ""},
+		{"music", 0x60, 0x60, "3.6"}, {"musical", 0x65, 0x60, "3.6"}, {"opera", 0x65, 0x60, "3.6"},
+		{"ballet", 0x66, 0x3A, "3.6"}, {"dance", 0x66, 0x3A, "3.6"},
+		{"arts/crafts", 0x70, 0x4F, "3.3"}, {"performing arts", 0x71, 0, "3.1.4"}, {"art", 0x72, 0, "3.1.4"},
+		{"religious", 0x73, 0x24, "3.1.2"}, {"fashion", 0x7B, 0x42, "3.3.7"},
+		{"politics", 0x80, 0x65, "3.1.3.1"}, {"bus./financial", 0x82, 0x30, "3.1.3.5"}, {"biography", 0x83, 0, "3.1.5"},
+		{"science", 0x90, 0x71, "3.1.6"}, {"nature", 0x91, 0x63, "3.1.6"}, {"animals", 0x91, 0x63, "3.1.6"},
+		{"technology", 0x92, 0x79, "3.1.6"}, {"medical", 0x93, 0x5C, "3.1.6"}, {"history", 0x95, 0x4E, "3.1.5"},
+		{"outdoors", 0xA0, 0x4F, "3.3"}, {"travel", 0xA1, 0x7B, "3.3.8"}, {"home improvement", 0xA2, 0x51, "3.3.4"},
+		{"howto", 0xA2, 0x54, "3.3"}, {"auto", 0xA3, 0x2B, "3.3.6"}, {"health", 0xA4, 0x4C, "3.3"},
+		{"exercise", 0xA4, 0x3F, "3.3"}, {"cooking", 0xA5, 0x44, "3.3.3"}, {"consumer", 0xA6, 0x37, "3.3.1"},
+		{"shopping", 0xA6, 0x74, "3.3.1"}, {"gardening", 0xA7, 0x49, "3.3.4"}, {"special", 0xB0, 0x76, ""},
+	}
+
+	names := dvbContentNames()
+	buckets := map[byte]PlexBucket{0x2: PlexNews, 0x4: PlexSports, 0x5: PlexKids}
+
+	categories := make(map[string]GenreCategory, len(rows))
+	for _, row := range rows {
+		category := GenreCategory{
+			DVBContent: row.dvb,
+			ATSCGenre:  row.atsc,
+			TVAnytime:  row.tva,
+			XMLTV:      names[row.dvb>>4],
+			Plex:       buckets[row.dvb>>4],
+		}
+		if row.genre == "movie" {
+			category.Plex = PlexMovies
+		}
+		categories[row.genre] = category
+	}
+	return categories
+}
+
+// A GenreMapper maps Schedules Direct genres and keywords onto standardized taxonomies.
+type GenreMapper struct {
+	categories map[string]GenreCategory
+}
+
+// NewGenreMapper returns a mapper using the default categories, replaced or extended by the overrides.
+// Overrides are matched ignoring case. An override with no categories makes a genre known without mapping it.
+func NewGenreMapper(overrides map[string]GenreCategory) *GenreMapper {
+	categories := DefaultGenreCategories()
+	for genre, category := range overrides {
+		categories[genreKey(genre)] = category
+	}
+	return &GenreMapper{categories: categories}
+}
+
+// genreKey normalizes a genre for lookups, ignoring case and surrounding or repeated white space.
+func genreKey(genre string) string {
+	return strings.ToLower(strings.Join(strings.Fields(genre), " "))
+}
+
+// Lookup returns the categories of a genre or keyword and whether it is known.
+func (m *GenreMapper) Lookup(genre string) (GenreCategory, bool) {
+	category, ok := m.categories[genreKey(genre)]
+	return category, ok
+}
+
+// A GenreMapping is the result of mapping the genres and keywords of a program.
+type GenreMapping struct {
+	// Categories of the known genres, followed by those of known keywords, in order.
+	Categories []GenreCategory
+	// Unknown are the genres which are not recognized. Unknown keywords are not reported.
+	Unknown []string
+	// Movie is true if the program is a movie or one of its genres is in the PlexMovies bucket.
+	Movie bool
+}
+
+// Genres maps genres, reporting those which are not recognized.
+func (m *GenreMapper) Genres(genres []string) GenreMapping {
+	mapping := GenreMapping{Categories: make([]GenreCategory, 0, len(genres)), Unknown: make([]string, 0)}
+	for _, genre := range genres {
+		if category, ok := m.Lookup(genre); ok {
+			mapping.Categories = append(mapping.Categories, category)
+			mapping.Movie = mapping.Movie || category.Plex == PlexMovies
+		} else if strings.TrimSpace(genre) != "" && !containsStringFold(mapping.Unknown, genre) {
+			mapping.Unknown = append(mapping.Unknown, genre)
+		}
+	}
+	return mapping
+}
+
+// Program maps the genres of the program followed by its keywords, in sorted keyword category order.
+func (m *GenreMapper) Program(info *ProgramInfo) GenreMapping {
+	if info == nil {
+		return m.Genres(nil)
+	}
+
+	mapping := m.Genres(info.Genres)
+	mapping.Movie = mapping.Movie || info.EntityType == MovieEntityType || strings.HasPrefix(info.ProgramID, "MV")
+
+	keywordCategories := make([]string, 0, len(info.Keywords))
+	for keywordCategory := range info.Keywords {
+		keywordCategories = append(keywordCategories, keywordCategory)
+	}
+	sort.Strings(keywordCategories)
+	for _, keywordCategory := range keywordCategories {
+		for _, keyword := range info.Keywords[keywordCategory] {
+			if category, ok := m.Lookup(keyword); ok {
+				mapping.Categories = append(mapping.Categories, category)
+			}
+		}
+	}
+	return mapping
+}
+
+// UnknownGenres returns how many of the programs have each genre that is not recognized.
+func (m *GenreMapper) UnknownGenres(programs map[string]*ProgramInfo) map[string]int {
+	unknown := make(map[string]int)
+	for _, info := range programs {
+		if info == nil {
+			continue
+		}
+		for _, genre := range m.Genres(info.Genres).Unknown {
+			unknown[genre]++
+		}
+	}
+	return unknown
+}
+
+// DVBContent returns the distinct DVB content nibbles of the mapping.
+func (m GenreMapping) DVBContent() []byte {
+	values := make([]byte, 0, len(m.Categories))
+	for _, category := range m.Categories {
+		if category.DVBContent != 0 && !containsByte(values, category.DVBContent) {
+			values = append(values, category.DVBContent)
+		}
+	}
+	return values
+}
+
+// ATSCGenres returns the distinct ATSC genre categories of the mapping.
+func (m GenreMapping) ATSCGenres() []byte {
+	values := make([]byte, 0, len(m.Categories))
+	for _, category := range m.Categories {
+		if category.ATSCGenre != 0 && !containsByte(values, category.ATSCGenre) {
+			values = append(values, category.ATSCGenre)
+		}
+	}
+	return values
+}
+
+// TVAnytime returns the distinct TV-Anytime ContentCS terms of the mapping.
+func (m GenreMapping) TVAnytime() []string {
+	return m.distinct(func(category GenreCategory) string { return category.TVAnytime })
+}
+
+// XMLTV returns the distinct XMLTV category names of the mapping.
+func (m GenreMapping) XMLTV() []string {
+	return m.distinct(func(category GenreCategory) string { return category.XMLTV })
+}
+
+// Plex returns the Plex bucket of the program. Movies take precedence, then sports, news and children's programming.
+func (m GenreMapping) Plex() PlexBucket {
+	if m.Movie {
+		return PlexMovies
+	}
+	for _, bucket := range []PlexBucket{PlexSports, PlexNews, PlexKids} {
+		for _, category := range m.Categories {
+			if category.Plex == bucket {
+				return bucket
+			}
+		}
+	}
+	return PlexShows
+}
+
+func (m GenreMapping) distinct(value func(GenreCategory) string) []string {
+	values := make([]string, 0, len(m.Categories))
+	seen := make(map[string]bool)
+	for _, category := range m.Categories {
+		if v := value(category); v != "" && !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func containsByte(values []byte, value byte) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
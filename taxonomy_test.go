@@ -0,0 +1,65 @@
+package schedulesdirect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenreMapper(t *testing.T) {
+	mapper := NewGenreMapper(nil)
+
+	comedy, ok := mapper.Lookup("  SITCOM ")
+	if assert.True(t, ok) {
+		assert.Equal(t, GenreCategory{DVBContent: 0x14, ATSCGenre: 0x34, TVAnytime: "3.4.9", XMLTV: "Movie / Drama"}, comedy)
+	}
+
+	for genre, term := range map[string]string{"crime": "3.4.6", "crime drama": "3.4.6", "law": "3.1.3.4", "military": "3.1.3.8", "talk": "3.5"} {
+		category, _ := mapper.Lookup(genre)
+		assert.Equal(t, term, category.TVAnytime, genre)
+	}
+
+	mapping := mapper.Genres([]string{"News", "Weather", "Soccer", "Cricket", "cricket", ""})
+	assert.Equal(t, []byte{0x20, 0x21, 0x43}, mapping.DVBContent())
+	assert.Equal(t, []byte{0x23, 0x7E, 0x25}, mapping.ATSCGenres())
+	assert.Equal(t, []string{"3.1.1", "3.2.3"}, mapping.TVAnytime())
+	assert.Equal(t, []string{"News / Current affairs", "Sports"}, mapping.XMLTV())
+	assert.Equal(t, PlexSports, mapping.Plex())
+	assert.Equal(t, []string{"Cricket"}, mapping.Unknown)
+
+	movie := mapper.Program(&ProgramInfo{
+		ProgramID: "MV000000010000",
+		Genres:    []string{"Children", "Animated"},
+		Keywords:  map[string][]string{"Subject": {"Crime"}, "Mood": {"Upbeat"}},
+	})
+	assert.Equal(t, []byte{0x50, 0x55, 0x11}, movie.DVBContent())
+	assert.Equal(t, PlexMovies, movie.Plex())
+	assert.Empty(t, movie.Unknown)
+
+	assert.Equal(t, PlexKids, mapper.Program(&ProgramInfo{Genres: []string{"Children"}}).Plex())
+	assert.Equal(t, PlexMovies, mapper.Program(&ProgramInfo{Genres: []string{"Soccer", "Movie"}}).Plex())
+	assert.Equal(t, PlexShows, mapper.Program(nil).Plex())
+}
+
+func TestGenreMapperOverrides(t *testing.T) {
+	mapper := NewGenreMapper(map[string]GenreCategory{
+		"Cricket": {DVBContent: 0x40, XMLTV: "Sports", Plex: PlexSports},
+		"news":    {},
+	})
+
+	mapping := mapper.Genres([]string{"cricket", "News"})
+	assert.Equal(t, []byte{0x40}, mapping.DVBContent())
+	assert.Equal(t, PlexSports, mapping.Plex())
+	assert.Empty(t, mapping.ATSCGenres())
+	assert.Empty(t, mapping.Unknown)
+
+	_, ok := NewGenreMapper(nil).Lookup("cricket")
+	assert.False(t, ok)
+
+	unknown := NewGenreMapper(nil).UnknownGenres(map[string]*ProgramInfo{
+		"EP1": {Genres: []string{"Cricket", "News"}},
+		"EP2": {Genres: []string{"Cricket", "Curling"}},
+		"EP3": nil,
+	})
+	assert.Equal(t, map[string]int{"Cricket": 2, "Curling": 1}, unknown)
+}
@@ -0,0 +1,278 @@
+package schedulesdirect
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A TunerConflict is a period of time in which more recordings are planned than there are tuners.
+type TunerConflict struct {
+	Start time.Time
+	End   time.Time
+	// Recordings are all of the recordings which overlap the period.
+	Recordings []PlannedRecording
+}
+
+// DropReason describes why a TunerScheduler did not schedule a recording.
+type DropReason string
+
+const (
+	// DuplicateDropReason means another airing of the same program is already scheduled.
+	DuplicateDropReason DropReason = "Duplicate"
+	// ConflictDropReason means all tuners are busy with higher priority recordings and no alternate airing fits.
+	ConflictDropReason DropReason = "Conflict"
+)
+
+// A DroppedRecording is a recording which a TunerScheduler could not fit.
+type DroppedRecording struct {
+	Recording PlannedRecording
+	Reason    DropReason
+	// ConflictsWith are the scheduled recordings which occupy the tuners at the time of the dropped recording.
+	ConflictsWith []ScheduledRecording
+	// Explanation is a human readable explanation of why the recording was dropped.
+	Explanation string
+}
+
+// A ScheduledRecording is a recording assigned to a tuner.
+type ScheduledRecording struct {
+	PlannedRecording
+	// Tuner is the zero based index of the tuner the recording is assigned to.
+	Tuner int
+	// Replaces is set if the recording is an alternate airing of a recording that could not fit.
+	Replaces *PlannedRecording
+}
+
+// A TunerSchedule is the result of resolving recordings against the available tuners.
+type TunerSchedule struct {
+	Scheduled []ScheduledRecording
+	Dropped   []DroppedRecording
+}
+
+// A TunerScheduler fits planned recordings onto a limited number of tuners.
+type TunerScheduler struct {
+	// Tuners is the number of recordings which can happen at the same time.
+	Tuners int
+	// Alternates are airings which may be recorded instead of a recording that does not fit,
+	// typically every airing in the guide. Only airings of the same program ID on another station
+	// at the same time, or starting later, are considered.
+	Alternates []Airing
+}
+
+// Conflicts returns the periods of time in which more than Tuners recordings overlap.
+func (s TunerScheduler) Conflicts(recordings []PlannedRecording) []TunerConflict {
+	type edge struct {
+		at    time.Time
+		delta int
+	}
+
+	edges := make([]edge, 0, len(recordings)*2)
+	for _, recording := range recordings {
+		edges = append(edges, edge{recording.RecordStart, 1}, edge{recording.RecordEnd, -1})
+	}
+
+	// Recordings ending at the same time another starts do not overlap, so process ends first.
+	sort.Slice(edges, func(i, j int) bool {
+		if !edges[i].at.Equal(edges[j].at) {
+			return edges[i].at.Before(edges[j].at)
+		}
+		return edges[i].delta < edges[j].delta
+	})
+
+	conflicts := make([]TunerConflict, 0)
+	active := 0
+	var current *TunerConflict
+
+	for _, e := range edges {
+		active += e.delta
+		if active > s.Tuners && current == nil {
+			current = &TunerConflict{Start: e.at}
+		} else if active <= s.Tuners && current != nil {
+			current.End = e.at
+			current.Recordings = overlappingRecordings(recordings, current.Start, current.End)
+			conflicts = append(conflicts, *current)
+			current = nil
+		}
+	}
+
+	return conflicts
+}
+
+// Resolve fits the recordings onto the tuners.
+//
+// Recordings are considered by descending priority, then by start time. Only one airing of each program
+// is scheduled, later airings are dropped as duplicates even if tuners are free. A recording which does not fit
+// is replaced by the earliest alternate airing of the same program that does, or else dropped.
+func (s TunerScheduler) Resolve(recordings []PlannedRecording) *TunerSchedule {
+	ordered := make([]PlannedRecording, len(recordings))
+	copy(ordered, recordings)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Priority != ordered[j].Priority {
+			return ordered[i].Priority > ordered[j].Priority
+		}
+		return airingLess(ordered[i].Airing, ordered[j].Airing)
+	})
+
+	accepted := make([]ScheduledRecording, 0)
+	dropped := make([]DroppedRecording, 0)
+	acceptedKeys := make(map[AiringKey]bool)
+
+	for _, recording := range ordered {
+		// The program may already be scheduled, e.g. as a simulcast or the alternate of a higher priority recording.
+		if existing := scheduledProgram(accepted, recording.Program.ProgramID); existing != nil {
+			dropped = append(dropped, DroppedRecording{
+				Recording: recording,
+				Reason:    DuplicateDropReason,
+				Explanation: fmt.Sprintf("%s is already scheduled on station %s at %s",
+					recording.Program.ProgramID, existing.StationID, existing.Start().Format(time.RFC3339)),
+			})
+			continue
+		}
+
+		if s.fits(accepted, recording) {
+			accepted = append(accepted, ScheduledRecording{PlannedRecording: recording})
+			acceptedKeys[recording.Key()] = true
+			continue
+		}
+
+		conflicting := overlappingScheduled(accepted, recording.RecordStart, recording.RecordEnd)
+
+		if alternate := s.findAlternate(accepted, acceptedKeys, recording); alternate != nil {
+			original := recording
+			alternate.Replaces = &original
+			accepted = append(accepted, *alternate)
+			acceptedKeys[alternate.Key()] = true
+			continue
+		}
+
+		dropped = append(dropped, DroppedRecording{
+			Recording:     recording,
+			Reason:        ConflictDropReason,
+			ConflictsWith: conflicting,
+			Explanation:   conflictExplanation(s.Tuners, recording, conflicting),
+		})
+	}
+
+	assignTuners(accepted)
+
+	return &TunerSchedule{Scheduled: accepted, Dropped: dropped}
+}
+
+// fits returns true if recording can be added to scheduled without exceeding the tuner count.
+func (s TunerScheduler) fits(scheduled []ScheduledRecording, recording PlannedRecording) bool {
+	overlapping := overlappingScheduled(scheduled, recording.RecordStart, recording.RecordEnd)
+	if len(overlapping) < s.Tuners {
+		return true
+	}
+
+	planned := make([]PlannedRecording, 0, len(overlapping)+1)
+	for _, o := range overlapping {
+		planned = append(planned, o.PlannedRecording)
+	}
+	planned = append(planned, recording)
+
+	return len(s.Conflicts(planned)) == 0
+}
+
+// findAlternate returns the earliest alternate airing of the recording's program which fits, if any.
+func (s TunerScheduler) findAlternate(scheduled []ScheduledRecording, scheduledKeys map[AiringKey]bool, recording PlannedRecording) *ScheduledRecording {
+	candidates := make([]Airing, 0)
+	for _, airing := range s.Alternates {
+		if airing.Program.ProgramID != recording.Program.ProgramID || airing.Program.AirDateTime == nil {
+			continue
+		}
+		if airing.Start().Before(recording.Airing.Start()) {
+			continue
+		}
+		key := airing.Key()
+		if key == recording.Key() || scheduledKeys[key] {
+			continue
+		}
+		candidates = append(candidates, airing)
+	}
+	sortAirings(candidates)
+
+	startPadding := recording.Airing.Start().Sub(recording.RecordStart)
+	endPadding := recording.RecordEnd.Sub(recording.Airing.End())
+
+	for _, airing := range candidates {
+		alternate := recording
+		alternate.Airing = airing
+		alternate.RecordStart = airing.Start().Add(-startPadding)
+		alternate.RecordEnd = airing.End().Add(endPadding)
+
+		if s.fits(scheduled, alternate) {
+			return &ScheduledRecording{PlannedRecording: alternate}
+		}
+	}
+
+	return nil
+}
+
+// assignTuners gives each scheduled recording a tuner, reusing the lowest numbered free tuner.
+func assignTuners(scheduled []ScheduledRecording) {
+	order := make([]int, len(scheduled))
+	for idx := range order {
+		order[idx] = idx
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scheduled[order[i]].RecordStart.Before(scheduled[order[j]].RecordStart)
+	})
+
+	busyUntil := make([]time.Time, 0)
+	for _, idx := range order {
+		recording := &scheduled[idx]
+		tuner := -1
+		for t, until := range busyUntil {
+			if !until.After(recording.RecordStart) {
+				tuner = t
+				break
+			}
+		}
+		if tuner == -1 {
+			tuner = len(busyUntil)
+			busyUntil = append(busyUntil, time.Time{})
+		}
+		busyUntil[tuner] = recording.RecordEnd
+		recording.Tuner = tuner
+	}
+}
+
+func conflictExplanation(tuners int, recording PlannedRecording, conflicting []ScheduledRecording) string {
+	names := make([]string, 0, len(conflicting))
+	for _, c := range conflicting {
+		names = append(names, fmt.Sprintf("%s on %s (priority %d)", c.Program.ProgramID, c.StationID, c.Priority))
+	}
+	return fmt.Sprintf("all %d tuners are busy with %s and no alternate airing of %s fits",
+		tuners, strings.Join(names, ", "), recording.Program.ProgramID)
+}
+
+func scheduledProgram(scheduled []ScheduledRecording, programID string) *ScheduledRecording {
+	for idx := range scheduled {
+		if scheduled[idx].Program.ProgramID == programID {
+			return &scheduled[idx]
+		}
+	}
+	return nil
+}
+
+func overlappingScheduled(scheduled []ScheduledRecording, start, end time.Time) []ScheduledRecording {
+	overlapping := make([]ScheduledRecording, 0)
+	for _, recording := range scheduled {
+		if recording.RecordStart.Before(end) && recording.RecordEnd.After(start) {
+			overlapping = append(overlapping, recording)
+		}
+	}
+	return overlapping
+}
+
+func overlappingRecordings(recordings []PlannedRecording, start, end time.Time) []PlannedRecording {
+	overlapping := make([]PlannedRecording, 0)
+	for _, recording := range recordings {
+		if recording.RecordStart.Before(end) && recording.RecordEnd.After(start) {
+			overlapping = append(overlapping, recording)
+		}
+	}
+	return overlapping
+}
@@ -0,0 +1,137 @@
+package schedulesdirect
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testPlannedRecording(stationID, programID, airDateTime string, duration, priority int) PlannedRecording {
+	airing := Airing{StationID: stationID, Program: testProgram(programID, airDateTime, duration)}
+	return PlannedRecording{Airing: airing, RecordStart: airing.Start(), RecordEnd: airing.End(), Priority: priority}
+}
+
+func TestTunerSchedulerConflicts(t *testing.T) {
+	scheduler := TunerScheduler{Tuners: 2}
+
+	recordings := []PlannedRecording{
+		testPlannedRecording("10001", "EP000000000001", "2015-03-03T20:00:00Z", 3600, 0),
+		testPlannedRecording("10002", "EP000000000002", "2015-03-03T20:30:00Z", 3600, 0),
+		testPlannedRecording("10003", "EP000000000003", "2015-03-03T20:45:00Z", 1800, 0),
+		testPlannedRecording("10004", "EP000000000004", "2015-03-03T21:30:00Z", 1800, 0),
+	}
+
+	conflicts := scheduler.Conflicts(recordings)
+
+	if assert.Len(t, conflicts, 1) {
+		assert.Equal(t, time.Date(2015, 3, 3, 20, 45, 0, 0, time.UTC), conflicts[0].Start)
+		assert.Equal(t, time.Date(2015, 3, 3, 21, 0, 0, 0, time.UTC), conflicts[0].End)
+		assert.Len(t, conflicts[0].Recordings, 3)
+	}
+
+	assert.Empty(t, TunerScheduler{Tuners: 3}.Conflicts(recordings))
+}
+
+func TestTunerSchedulerResolve(t *testing.T) {
+	scheduler := TunerScheduler{
+		Tuners: 1,
+		Alternates: []Airing{
+			{StationID: "10002", Program: testProgram("EP000000000002", "2015-03-03T19:00:00Z", 1800)},
+			{StationID: "10003", Program: testProgram("EP000000000002", "2015-03-03T20:30:00Z", 1800)},
+			{StationID: "10002", Program: testProgram("EP000000000002", "2015-03-04T01:00:00Z", 1800)},
+		},
+	}
+
+	recordings := []PlannedRecording{
+		testPlannedRecording("10001", "EP000000000001", "2015-03-03T20:00:00Z", 3600, 10),
+		testPlannedRecording("10002", "EP000000000002", "2015-03-03T20:30:00Z", 1800, 5),
+		testPlannedRecording("10004", "EP000000000003", "2015-03-03T20:30:00Z", 1800, 1),
+		testPlannedRecording("10005", "EP000000000001", "2015-03-03T20:00:00Z", 3600, 0),
+	}
+	recordings[1].RecordStart = recordings[1].RecordStart.Add(-time.Minute)
+
+	schedule := scheduler.Resolve(recordings)
+
+	if assert.Len(t, schedule.Scheduled, 2) {
+		assert.Equal(t, "10001", schedule.Scheduled[0].StationID)
+		assert.Nil(t, schedule.Scheduled[0].Replaces)
+
+		replacement := schedule.Scheduled[1]
+		assert.Equal(t, "10002", replacement.StationID)
+		assert.Equal(t, time.Date(2015, 3, 4, 1, 0, 0, 0, time.UTC), replacement.Airing.Start())
+		assert.Equal(t, time.Date(2015, 3, 4, 0, 59, 0, 0, time.UTC), replacement.RecordStart)
+		if assert.NotNil(t, replacement.Replaces) {
+			assert.Equal(t, "10002", replacement.Replaces.StationID)
+		}
+		assert.Equal(t, 0, replacement.Tuner)
+	}
+
+	if assert.Len(t, schedule.Dropped, 2) {
+		assert.Equal(t, "EP000000000003", schedule.Dropped[0].Recording.Program.ProgramID)
+		assert.Equal(t, ConflictDropReason, schedule.Dropped[0].Reason)
+		assert.Len(t, schedule.Dropped[0].ConflictsWith, 1)
+		assert.Contains(t, schedule.Dropped[0].Explanation, "EP000000000001 on 10001 (priority 10)")
+
+		assert.Equal(t, "10005", schedule.Dropped[1].Recording.StationID)
+		assert.Equal(t, DuplicateDropReason, schedule.Dropped[1].Reason)
+	}
+}
+
+func TestTunerSchedulerResolveAcceptedAlternate(t *testing.T) {
+	scheduler := TunerScheduler{
+		Tuners:     2,
+		Alternates: []Airing{{StationID: "10002", Program: testProgram("EP000000000002", "2015-03-03T21:00:00Z", 3600)}},
+	}
+
+	recordings := []PlannedRecording{
+		testPlannedRecording("10001", "EP000000000001", "2015-03-03T20:00:00Z", 3600, 10),
+		testPlannedRecording("10003", "EP000000000003", "2015-03-03T20:00:00Z", 3600, 10),
+		testPlannedRecording("10002", "EP000000000002", "2015-03-03T20:00:00Z", 3600, 5),
+		testPlannedRecording("10002", "EP000000000002", "2015-03-03T21:00:00Z", 3600, 0),
+	}
+
+	schedule := scheduler.Resolve(recordings)
+
+	if assert.Len(t, schedule.Scheduled, 3) {
+		alternate := schedule.Scheduled[2]
+		assert.Equal(t, time.Date(2015, 3, 3, 21, 0, 0, 0, time.UTC), alternate.Airing.Start())
+		assert.NotNil(t, alternate.Replaces)
+	}
+	if assert.Len(t, schedule.Dropped, 1) {
+		assert.Equal(t, time.Date(2015, 3, 3, 21, 0, 0, 0, time.UTC), schedule.Dropped[0].Recording.Airing.Start())
+		assert.Equal(t, DuplicateDropReason, schedule.Dropped[0].Reason)
+	}
+}
+
+func TestTunerSchedulerResolveSimulcast(t *testing.T) {
+	scheduler := TunerScheduler{Tuners: 4}
+
+	schedule := scheduler.Resolve([]PlannedRecording{
+		testPlannedRecording("10001", "EP000000000001", "2015-03-03T20:00:00Z", 3600, 0),
+		testPlannedRecording("10011", "EP000000000001", "2015-03-03T20:00:00Z", 3600, 0),
+	})
+
+	if assert.Len(t, schedule.Scheduled, 1) {
+		assert.Equal(t, "10001", schedule.Scheduled[0].StationID)
+	}
+	if assert.Len(t, schedule.Dropped, 1) {
+		assert.Equal(t, "10011", schedule.Dropped[0].Recording.StationID)
+		assert.Equal(t, DuplicateDropReason, schedule.Dropped[0].Reason)
+		assert.Empty(t, schedule.Dropped[0].ConflictsWith)
+	}
+}
+
+func TestAssignTuners(t *testing.T) {
+	scheduled := []ScheduledRecording{
+		{PlannedRecording: testPlannedRecording("10001", "EP000000000001", "2015-03-03T20:00:00Z", 3600, 0)},
+		{PlannedRecording: testPlannedRecording("10002", "EP000000000002", "2015-03-03T20:30:00Z", 3600, 0)},
+		{PlannedRecording: testPlannedRecording("10003", "EP000000000003", "2015-03-03T21:00:00Z", 1800, 0)},
+	}
+
+	assignTuners(scheduled)
+
+	assert.Equal(t, 0, scheduled[0].Tuner)
+	assert.Equal(t, 1, scheduled[1].Tuner)
+	assert.Equal(t, 0, scheduled[2].Tuner)
+}
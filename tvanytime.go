@@ -0,0 +1,376 @@
+package schedulesdirect
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strings"
+	"time"
+)
+
+// tvaCRIDPrefix is prepended to program IDs to form content reference identifiers.
+const tvaCRIDPrefix = "crid://schedulesdirect.org/"
+
+// A TVAnytimeExporter writes schedules as a TV-Anytime (ETSI TS 102 822-3-1) document with a ProgramInformationTable,
+// a ProgramLocationTable and a ServiceInformationTable, as used by DVB-I content guides.
+//
+// Programs are identified by CRIDs of the form crid://schedulesdirect.org/<programID>.
+type TVAnytimeExporter struct {
+	// Programs are used for titles, synopses, genres and parental guidance.
+	// Airings of programs which are missing are still scheduled but have no program information.
+	Programs map[string]*ProgramInfo
+	// Artwork is the artwork of programs keyed by the IDs returned by GetArtworkForProgramIDs, see IndexArtwork.
+	Artwork map[string][]Artwork
+	// ImageURL returns the URL of an artwork URI, e.g. Client.GetImageURL. URIs are used as they are if nil.
+	ImageURL func(uri string) string
+	// ServiceID returns the serviceId of a station. A tag URI of the station ID is used if nil.
+	ServiceID func(stationID string) string
+	// Language is the xml:lang of the document, "en" if empty.
+	Language string
+	// Genres maps genres and keywords to ContentCS terms, NewGenreMapper(nil) if nil.
+	Genres *GenreMapper
+}
+
+// IndexArtwork returns the artwork of the responses keyed by program ID, skipping responses with errors.
+func IndexArtwork(responses []ArtworkResponse) map[string][]Artwork {
+	index := make(map[string][]Artwork, len(responses))
+	for _, response := range responses {
+		if response.Artwork == nil || response.ProgramID == "" {
+			continue
+		}
+		index[response.ProgramID] = append(index[response.ProgramID], *response.Artwork...)
+	}
+	return index
+}
+
+type tvaMain struct {
+	XMLName            xml.Name      `xml:"TVAMain"`
+	Namespace          string        `xml:"xmlns,attr"`
+	MPEG7Namespace     string        `xml:"xmlns:mpeg7,attr"`
+	Language           string        `xml:"xml:lang,attr"`
+	ProgramInformation []tvaProgram  `xml:"ProgramDescription>ProgramInformationTable>ProgramInformation"`
+	Schedules          []tvaSchedule `xml:"ProgramDescription>ProgramLocationTable>Schedule"`
+	Services           []tvaService  `xml:"ProgramDescription>ServiceInformationTable>ServiceInformation"`
+}
+
+type tvaProgram struct {
+	ProgramID        string               `xml:"programId,attr"`
+	Titles           []tvaText            `xml:"BasicDescription>Title"`
+	Synopses         []tvaText            `xml:"BasicDescription>Synopsis"`
+	Genres           []tvaGenre           `xml:"BasicDescription>Genre"`
+	ParentalGuidance *tvaParentalGuidance `xml:"BasicDescription>ParentalGuidance"`
+	RelatedMaterial  []tvaRelatedMaterial `xml:"BasicDescription>RelatedMaterial"`
+	EpisodeOf        *tvaEpisodeOf        `xml:"EpisodeOf"`
+}
+
+type tvaText struct {
+	Type     string `xml:"type,attr,omitempty"`
+	Length   string `xml:"length,attr,omitempty"`
+	Language string `xml:"xml:lang,attr,omitempty"`
+	Value    string `xml:",chardata"`
+}
+
+type tvaGenre struct {
+	Type string `xml:"type,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type tvaParentalGuidance struct {
+	MinimumAge      int      `xml:"mpeg7:MinimumAge"`
+	ExplanatoryText []string `xml:"mpeg7:ExplanatoryText,omitempty"`
+}
+
+type tvaRelatedMaterial struct {
+	HowRelated tvaHref     `xml:"HowRelated"`
+	MediaURI   tvaMediaURI `xml:"MediaLocator>mpeg7:MediaUri"`
+}
+
+type tvaHref struct {
+	Href string `xml:"href,attr"`
+}
+
+type tvaMediaURI struct {
+	ContentType string `xml:"contentType,attr,omitempty"`
+	URI         string `xml:",chardata"`
+}
+
+type tvaEpisodeOf struct {
+	CRID  string `xml:"crid,attr"`
+	Index int    `xml:"index,attr,omitempty"`
+}
+
+type tvaSchedule struct {
+	ServiceIDRef string             `xml:"serviceIDRef,attr"`
+	Start        string             `xml:"start,attr"`
+	End          string             `xml:"end,attr"`
+	Events       []tvaScheduleEvent `xml:"ScheduleEvent"`
+}
+
+type tvaScheduleEvent struct {
+	Program            tvaCRID  `xml:"Program"`
+	PublishedStartTime string   `xml:"PublishedStartTime"`
+	PublishedDuration  string   `xml:"PublishedDuration"`
+	Live               *tvaFlag `xml:"Live"`
+	Repeat             *tvaFlag `xml:"Repeat"`
+	FirstShowing       *tvaFlag `xml:"FirstShowing"`
+}
+
+type tvaCRID struct {
+	CRID string `xml:"crid,attr"`
+}
+
+type tvaFlag struct {
+	Value bool `xml:"value,attr"`
+}
+
+type tvaService struct {
+	ServiceID       string               `xml:"serviceId,attr"`
+	Names           []string             `xml:"Name"`
+	Owner           string               `xml:"Owner,omitempty"`
+	RelatedMaterial []tvaRelatedMaterial `xml:"RelatedMaterial"`
+}
+
+// Write writes the services of the lineups and the schedules of those services as a TV-Anytime document.
+// Services and their schedules are written in lineup order, once per station.
+// Schedules of stations missing from the lineups are skipped.
+func (e TVAnytimeExporter) Write(w io.Writer, lineups []*ChannelResponse, schedules []Schedule) error {
+	language := e.Language
+	if language == "" {
+		language = "en"
+	}
+
+	doc := tvaMain{
+		Namespace:          "urn:tva:metadata:2019",
+		MPEG7Namespace:     "urn:tva:mpeg7:2008",
+		Language:           language,
+		ProgramInformation: make([]tvaProgram, 0),
+		Schedules:          make([]tvaSchedule, 0),
+		Services:           make([]tvaService, 0),
+	}
+
+	genres := e.Genres
+	if genres == nil {
+		genres = NewGenreMapper(nil)
+	}
+
+	services := make(map[string]bool)
+	stationOrder := make([]string, 0)
+	for _, lineup := range lineups {
+		if lineup == nil {
+			continue
+		}
+		stations := make(map[string]Station, len(lineup.Stations))
+		for _, station := range lineup.Stations {
+			stations[station.StationID] = station
+		}
+		for _, entry := range lineup.Map {
+			if entry.StationID == "" || services[entry.StationID] {
+				continue
+			}
+			services[entry.StationID] = true
+			stationOrder = append(stationOrder, entry.StationID)
+			doc.Services = append(doc.Services, e.service(entry, stations[entry.StationID]))
+		}
+	}
+
+	airingsByStation := make(map[string][]Airing)
+	for _, airing := range AiringsFromSchedules(schedules, e.Programs) {
+		if airing.Program.AirDateTime != nil {
+			airingsByStation[airing.StationID] = append(airingsByStation[airing.StationID], airing)
+		}
+	}
+
+	programs := make(map[string]bool)
+	for _, stationID := range stationOrder {
+		airings := airingsByStation[stationID]
+		if len(airings) == 0 {
+			continue
+		}
+		schedule := tvaSchedule{
+			ServiceIDRef: e.serviceID(stationID),
+			Start:        airings[0].Start().UTC().Format(time.RFC3339),
+			Events:       make([]tvaScheduleEvent, 0, len(airings)),
+		}
+
+		end := airings[0].End()
+		for _, airing := range airings {
+			if airing.End().After(end) {
+				end = airing.End()
+			}
+
+			event := tvaScheduleEvent{
+				Program:            tvaCRID{CRID: tvaCRIDPrefix + airing.Program.ProgramID},
+				PublishedStartTime: airing.Start().UTC().Format(time.RFC3339),
+				PublishedDuration:  xsdDuration(airing.End().Sub(airing.Start())),
+			}
+			if airing.Program.IsLive() {
+				event.Live = &tvaFlag{Value: true}
+			}
+			if airing.IsRepeat() {
+				event.Repeat = &tvaFlag{Value: true}
+			}
+			if airing.Program.New || airing.Program.Premiere {
+				event.FirstShowing = &tvaFlag{Value: true}
+			}
+			schedule.Events = append(schedule.Events, event)
+
+			if airing.Info != nil && !programs[airing.Program.ProgramID] {
+				programs[airing.Program.ProgramID] = true
+				doc.ProgramInformation = append(doc.ProgramInformation, e.program(airing, genres))
+			}
+		}
+		schedule.End = end.UTC().Format(time.RFC3339)
+
+		doc.Schedules = append(doc.Schedules, schedule)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (e TVAnytimeExporter) serviceID(stationID string) string {
+	if e.ServiceID != nil {
+		return e.ServiceID(stationID)
+	}
+	return "tag:schedulesdirect.org,2014:station:" + stationID
+}
+
+func (e TVAnytimeExporter) imageURL(uri string) string {
+	if e.ImageURL != nil {
+		return e.ImageURL(uri)
+	}
+	return uri
+}
+
+func (e TVAnytimeExporter) service(entry ChannelMap, station Station) tvaService {
+	service := tvaService{
+		ServiceID:       e.serviceID(entry.StationID),
+		Names:           make([]string, 0, 2),
+		Owner:           station.Affiliate,
+		RelatedMaterial: make([]tvaRelatedMaterial, 0),
+	}
+	if station.Name != "" {
+		service.Names = append(service.Names, station.Name)
+	}
+	if station.CallSign != "" && station.CallSign != station.Name {
+		service.Names = append(service.Names, station.CallSign)
+	}
+	if len(service.Names) == 0 {
+		service.Names = append(service.Names, entry.Number())
+	}
+	if logo := station.PrimaryLogo(); logo != nil && logo.URL != "" {
+		service.RelatedMaterial = append(service.RelatedMaterial, tvaRelatedMaterial{
+			HowRelated: tvaHref{Href: "urn:dvb:metadata:cs:HowRelatedCS:2020:1001.2"},
+			MediaURI:   tvaMediaURI{ContentType: imageContentType(logo.URL), URI: logo.URL},
+		})
+	}
+	return service
+}
+
+func (e TVAnytimeExporter) program(airing Airing, genres *GenreMapper) tvaProgram {
+	info := airing.Info
+	program := tvaProgram{
+		ProgramID:       tvaCRIDPrefix + airing.Program.ProgramID,
+		Titles:          make([]tvaText, 0, 2),
+		Synopses:        make([]tvaText, 0),
+		Genres:          make([]tvaGenre, 0),
+		RelatedMaterial: make([]tvaRelatedMaterial, 0),
+	}
+
+	if title := info.Title(); title != "" {
+		program.Titles = append(program.Titles, tvaText{Type: "main", Value: title})
+	}
+	if info.EpisodeTitle150 != "" {
+		program.Titles = append(program.Titles, tvaText{Type: "secondary", Value: info.EpisodeTitle150})
+	}
+
+	for _, synopsis := range [][2]string{{"description100", "short"}, {"description1000", "long"}} {
+		for _, description := range info.Descriptions[synopsis[0]] {
+			if description.Description != "" {
+				program.Synopses = append(program.Synopses, tvaText{Length: synopsis[1], Language: description.Language, Value: description.Description})
+			}
+		}
+	}
+
+	for _, term := range genres.Program(info).TVAnytime() {
+		program.Genres = append(program.Genres, tvaGenre{Type: "main", Href: "urn:tva:metadata:cs:ContentCS:2011:" + term})
+	}
+
+	minimumAge := -1
+	for _, rating := range AiringRatings(airing) {
+		if rating.Known && rating.MinimumAge > minimumAge {
+			minimumAge = rating.MinimumAge
+		}
+	}
+	if minimumAge >= 0 {
+		program.ParentalGuidance = &tvaParentalGuidance{MinimumAge: minimumAge, ExplanatoryText: info.ContentAdvisory}
+	}
+
+	for _, artwork := range e.pictures(info) {
+		program.RelatedMaterial = append(program.RelatedMaterial, tvaRelatedMaterial{
+			HowRelated: tvaHref{Href: "urn:tva:metadata:cs:HowRelatedCS:2012:19"},
+			MediaURI:   tvaMediaURI{ContentType: imageContentType(artwork.URI), URI: e.imageURL(artwork.URI)},
+		})
+	}
+
+	if showID := airing.ShowID(); showID != airing.Program.ProgramID {
+		_, episode := info.SeasonEpisode()
+		program.EpisodeOf = &tvaEpisodeOf{CRID: tvaCRIDPrefix + showID, Index: episode}
+	}
+
+	return program
+}
+
+// pictures returns the primary, then largest, artwork of the program for each aspect ratio.
+func (e TVAnytimeExporter) pictures(info *ProgramInfo) []Artwork {
+	candidates := programArtwork(info, e.Artwork)
+
+	pictures := make([]Artwork, 0)
+	for _, aspect := range []ArtworkAspectRatio{SixteenByNineAspectRatio, FourByThreeAspectRatio, TwoByThreeAspectRatio, ThreeByFourAspectRatio, OneByOneAspectRatio} {
+		if artwork := selectArtwork(candidates, func(a Artwork) bool { return a.Aspect == aspect }); artwork != nil {
+			pictures = append(pictures, *artwork)
+		}
+	}
+	return pictures
+}
+
+// imageContentType returns the MIME type of an image from the extension of its URL, or an empty string if it is unknown.
+func imageContentType(uri string) string {
+	if idx := strings.IndexAny(uri, "?#"); idx >= 0 {
+		uri = uri[:idx]
+	}
+	contentType := mime.TypeByExtension(strings.ToLower(path.Ext(uri)))
+	if !strings.HasPrefix(contentType, "image/") {
+		return ""
+	}
+	return contentType
+}
+
+// xsdDuration formats d as an XML Schema duration, e.g. PT1H30M.
+func xsdDuration(d time.Duration) string {
+	seconds := int(d / time.Second)
+	if seconds <= 0 {
+		return "PT0S"
+	}
+	duration := "PT"
+	if hours := seconds / 3600; hours > 0 {
+		duration += fmt.Sprintf("%dH", hours)
+	}
+	if minutes := seconds / 60 % 60; minutes > 0 {
+		duration += fmt.Sprintf("%dM", minutes)
+	}
+	if seconds%60 > 0 {
+		duration += fmt.Sprintf("%dS", seconds%60)
+	}
+	return duration
+}
@@ -0,0 +1,170 @@
+package schedulesdirect
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTVAnytimeExporter(t *testing.T) {
+	lineup := &ChannelResponse{}
+	if err := json.Unmarshal([]byte(`{
+		"map": [
+			{"stationID": "10001", "channel": "2"},
+			{"stationID": "10002", "channel": "4"},
+			{"stationID": "10001", "channel": "102"}
+		],
+		"stations": [
+			{"stationID": "10001", "callsign": "BBC1", "name": "BBC One", "affiliate": "BBC", "logo": {"URL": "https://example.com/bbc1.png"}},
+			{"stationID": "10002", "callsign": "ITV"}
+		],
+		"metadata": {"lineup": "GBR-0001458-DEFAULT"}
+	}`), lineup); err != nil {
+		t.Fatal(err)
+	}
+
+	episode := testProgram("EP000000060003", "2015-03-03T20:00:00Z", 1800)
+	episode.New = true
+	episode.Ratings = []ContentRating{{Body: "British Board of Film Classification", Code: "12", Country: "GBR"}}
+	repeat := testProgram("EP000000060003", "2015-03-04T02:00:00Z", 1800)
+	repeat.Repeat = true
+	news := testProgram("SH000000070000", "2015-03-03T20:00:00Z", 5430)
+	news.LiveTapeDelay = "live"
+
+	schedules := []Schedule{
+		{StationID: "10001", Programs: []Program{repeat, episode}},
+		{StationID: "10002", Programs: []Program{news}},
+		{StationID: "99999", Programs: []Program{testProgram("SH000000080000", "2015-03-03T20:00:00Z", 1800)}},
+	}
+
+	programs := IndexProgramInfo([]ProgramInfo{
+		{
+			ProgramID:       "EP000000060003",
+			Titles:          []Title{{Title120: "'Allo 'Allo!"}},
+			EpisodeTitle150: "The Nicked Airmen",
+			Genres:          []string{"Sitcom", "Comedy", "Children"},
+			ContentAdvisory: []string{"Mild innuendo"},
+			Descriptions: map[string][]Description{
+				"description100":  {{Language: "en", Description: "René hides two airmen."}},
+				"description1000": {{Language: "en-GB", Description: "René hides two British airmen in the café."}},
+			},
+			Metadata:          []map[string]Metadata{{"Gracenote": {Season: 2, Episode: 4}}},
+			HasEpisodeArtwork: true,
+		},
+	})
+
+	artwork := map[string][]Artwork{
+		"SH000000060000": {
+			{Aspect: SixteenByNineAspectRatio, URI: "assets/p1_h5_aa.jpg", Width: 1280, Height: 720},
+			{Aspect: SixteenByNineAspectRatio, URI: "assets/p1_h10_aa.jpg", Width: 1920, Height: 1080},
+			{Aspect: TwoByThreeAspectRatio, URI: "assets/p1_v5_aa.png", Width: 240, Height: 360},
+		},
+	}
+
+	exporter := TVAnytimeExporter{
+		Programs: programs,
+		Artwork:  artwork,
+		ImageURL: func(uri string) string { return "https://images.example.com/" + uri },
+	}
+
+	buf := &bytes.Buffer{}
+	if err := exporter.Write(buf, []*ChannelResponse{lineup, nil}, schedules); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<TVAMain xmlns="urn:tva:metadata:2019" xmlns:mpeg7="urn:tva:mpeg7:2008" xml:lang="en">
+  <ProgramDescription>
+    <ProgramInformationTable>
+      <ProgramInformation programId="crid://schedulesdirect.org/EP000000060003">
+        <BasicDescription>
+          <Title type="main">&#39;Allo &#39;Allo!</Title>
+          <Title type="secondary">The Nicked Airmen</Title>
+          <Synopsis length="short" xml:lang="en">René hides two airmen.</Synopsis>
+          <Synopsis length="long" xml:lang="en-GB">René hides two British airmen in the café.</Synopsis>
+          <Genre type="main" href="urn:tva:metadata:cs:ContentCS:2011:3.4.9"></Genre>
+          <ParentalGuidance>
+            <mpeg7:MinimumAge>12</mpeg7:MinimumAge>
+            <mpeg7:ExplanatoryText>Mild innuendo</mpeg7:ExplanatoryText>
+          </ParentalGuidance>
+          <RelatedMaterial>
+            <HowRelated href="urn:tva:metadata:cs:HowRelatedCS:2012:19"></HowRelated>
+            <MediaLocator>
+              <mpeg7:MediaUri contentType="image/jpeg">https://images.example.com/assets/p1_h10_aa.jpg</mpeg7:MediaUri>
+            </MediaLocator>
+          </RelatedMaterial>
+          <RelatedMaterial>
+            <HowRelated href="urn:tva:metadata:cs:HowRelatedCS:2012:19"></HowRelated>
+            <MediaLocator>
+              <mpeg7:MediaUri contentType="image/png">https://images.example.com/assets/p1_v5_aa.png</mpeg7:MediaUri>
+            </MediaLocator>
+          </RelatedMaterial>
+        </BasicDescription>
+        <EpisodeOf crid="crid://schedulesdirect.org/SH000000060000" index="4"></EpisodeOf>
+      </ProgramInformation>
+    </ProgramInformationTable>
+    <ProgramLocationTable>
+      <Schedule serviceIDRef="tag:schedulesdirect.org,2014:station:10001" start="2015-03-03T20:00:00Z" end="2015-03-04T02:30:00Z">
+        <ScheduleEvent>
+          <Program crid="crid://schedulesdirect.org/EP000000060003"></Program>
+          <PublishedStartTime>2015-03-03T20:00:00Z</PublishedStartTime>
+          <PublishedDuration>PT30M</PublishedDuration>
+          <FirstShowing value="true"></FirstShowing>
+        </ScheduleEvent>
+        <ScheduleEvent>
+          <Program crid="crid://schedulesdirect.org/EP000000060003"></Program>
+          <PublishedStartTime>2015-03-04T02:00:00Z</PublishedStartTime>
+          <PublishedDuration>PT30M</PublishedDuration>
+          <Repeat value="true"></Repeat>
+        </ScheduleEvent>
+      </Schedule>
+      <Schedule serviceIDRef="tag:schedulesdirect.org,2014:station:10002" start="2015-03-03T20:00:00Z" end="2015-03-03T21:30:30Z">
+        <ScheduleEvent>
+          <Program crid="crid://schedulesdirect.org/SH000000070000"></Program>
+          <PublishedStartTime>2015-03-03T20:00:00Z</PublishedStartTime>
+          <PublishedDuration>PT1H30M30S</PublishedDuration>
+          <Live value="true"></Live>
+        </ScheduleEvent>
+      </Schedule>
+    </ProgramLocationTable>
+    <ServiceInformationTable>
+      <ServiceInformation serviceId="tag:schedulesdirect.org,2014:station:10001">
+        <Name>BBC One</Name>
+        <Name>BBC1</Name>
+        <Owner>BBC</Owner>
+        <RelatedMaterial>
+          <HowRelated href="urn:dvb:metadata:cs:HowRelatedCS:2020:1001.2"></HowRelated>
+          <MediaLocator>
+            <mpeg7:MediaUri contentType="image/png">https://example.com/bbc1.png</mpeg7:MediaUri>
+          </MediaLocator>
+        </RelatedMaterial>
+      </ServiceInformation>
+      <ServiceInformation serviceId="tag:schedulesdirect.org,2014:station:10002">
+        <Name>ITV</Name>
+      </ServiceInformation>
+    </ServiceInformationTable>
+  </ProgramDescription>
+</TVAMain>
+`, buf.String())
+
+	assert.NoError(t, xml.Unmarshal(buf.Bytes(), new(struct{})))
+}
+
+func TestTVAnytimeHelpers(t *testing.T) {
+	assert.Equal(t, "PT0S", xsdDuration(0))
+	assert.Equal(t, "PT2H", xsdDuration(2*time.Hour))
+	assert.Equal(t, "PT45S", xsdDuration(45*time.Second))
+	assert.Equal(t, "image/jpeg", imageContentType("https://example.com/a.JPG?w=10"))
+	assert.Equal(t, "", imageContentType("https://example.com/a"))
+
+	// Episodes without episode artwork still find artwork stored under the root of their program ID.
+	exporter := TVAnytimeExporter{Artwork: map[string][]Artwork{"EP00000006": {{Aspect: SixteenByNineAspectRatio, URI: "assets/ep.jpg"}}}}
+	assert.Equal(t, []Artwork{{Aspect: SixteenByNineAspectRatio, URI: "assets/ep.jpg"}}, exporter.pictures(&ProgramInfo{ProgramID: "EP000000060003"}))
+
+	responses := []ArtworkResponse{{ProgramID: "SH00000006", Artwork: &[]Artwork{{URI: "a.jpg"}}}, {ProgramID: "SH00000007"}}
+	assert.Equal(t, map[string][]Artwork{"SH00000006": {{URI: "a.jpg"}}}, IndexArtwork(responses))
+}